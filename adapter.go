@@ -3,11 +3,18 @@ package casbinkube
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/casbin/casbin/v3/model"
 	"github.com/casbin/casbin/v3/persist"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
 	"github.com/grepplabs/loggo/zlog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // CasbinRule is used to determine which policy line to load.
@@ -24,12 +31,45 @@ type CasbinRule struct {
 type AdapterConfig struct {
 	// Kubernetes client configuration
 	KubeConfig KubeConfig
+	// Snapshot written after each successful load and used when the API server cannot be reached.
+	Snapshot SnapshotConfig
+	// Tolerant skips the rules rejected by the model instead of failing LoadPolicy. See Adapter.QuarantinedRules.
+	Tolerant bool
+	// EventRecorder reports the skipped Rules as Kubernetes Events if set.
+	EventRecorder events.EventRecorder
+	// EventReference is the object regarding the Events of the bulk deletions, e.g. the Deployment of the application.
+	// RemoveFilteredPolicy and SavePolicy report the number of deleted Rules if it is set together with EventRecorder.
+	EventReference *corev1.ObjectReference
+	// EventComponent identifies the reporter in the Events, defaults to DefaultAdapterEventComponent.
+	EventComponent string
+	// Revisions records the Rules after each write as a PolicyRevision, see Adapter.RollbackToRevision.
+	Revisions RevisionConfig
+	// BreakGlass limits the emergency Rules granted with Adapter.GrantBreakGlass.
+	BreakGlass BreakGlassConfig
 }
 
 type Adapter struct {
-	store *k8sAdapter
+	store    *k8sAdapter
+	snapshot SnapshotConfig
+	stale    staleState
+
+	tolerant   bool
+	quarantine quarantine
+	events     eventReporter
+	revisions  RevisionConfig
+	breakGlass BreakGlassConfig
+
+	clusterRulePrecedence ClusterRulePrecedence
+
+	// namespaceAsDomain injects the namespace as the domain, the domain fields are found in the model of the last LoadPolicy.
+	namespaceAsDomain bool
+	modelMu           sync.RWMutex
+	model             model.Model
 }
 
+// errNamespaceAsDomain is returned for the policy revisions, their Rules are recorded in a single namespace.
+var errNamespaceAsDomain = errors.New("not supported in the namespace as domain mode")
+
 var _ persist.BatchAdapter = (*Adapter)(nil)
 var _ persist.ContextAdapter = (*Adapter)(nil)
 
@@ -37,12 +77,23 @@ func NewAdapter(config *AdapterConfig) (*Adapter, error) {
 	if config == nil {
 		return nil, errors.New("config cannot be nil")
 	}
+	if config.Revisions.Enabled && config.KubeConfig.NamespaceAsDomain {
+		return nil, fmt.Errorf("policy revisions are %w", errNamespaceAsDomain)
+	}
 	s, err := newK8sAdapter(config)
 	if err != nil {
 		return nil, err
 	}
 	a := &Adapter{
-		store: s,
+		store:      s,
+		snapshot:   config.Snapshot,
+		tolerant:   config.Tolerant,
+		events:     newEventReporter(config.EventRecorder, config.EventReference, config.EventComponent, DefaultAdapterEventComponent),
+		revisions:  config.Revisions,
+		breakGlass: config.BreakGlass,
+
+		clusterRulePrecedence: config.KubeConfig.ClusterRulePrecedence,
+		namespaceAsDomain:     config.KubeConfig.NamespaceAsDomain,
 	}
 	return a, nil
 }
@@ -61,7 +112,48 @@ func loadPolicyLine(line CasbinRule, model model.Model) error {
 	return persist.LoadPolicyArray(p, model)
 }
 
-func (a *Adapter) savePolicyLine(ptype string, rule []string) CasbinRule {
+// modelLine returns the line of the Rule as loaded into the model, with its namespace as the domain in the NamespaceAsDomain mode.
+func (a *Adapter) modelLine(m model.Model, rule *v1alpha1.Rule) CasbinRule {
+	line := fromRule(rule)
+	if a.namespaceAsDomain {
+		return withDomain(m, line, rule.Namespace)
+	}
+	return line
+}
+
+// savePolicyLine returns the namespace and the line of the Rule storing the policy rule.
+// In the NamespaceAsDomain mode the domain is stripped from the line and used as the namespace.
+func (a *Adapter) savePolicyLine(ptype string, rule []string) (string, CasbinRule, error) {
+	if !a.namespaceAsDomain {
+		return "", toCasbinRule(ptype, rule), nil
+	}
+	m := a.getModel()
+	if m == nil {
+		return "", CasbinRule{}, errors.New("the policy must be loaded before it is written")
+	}
+	if domainIndex(m, ptype) < 0 {
+		return "", toCasbinRule(ptype, rule), nil
+	}
+	domain, stripped := withoutDomain(m, ptype, rule)
+	if domain == "" {
+		return "", CasbinRule{}, fmt.Errorf("policy line %q has no domain", lineString(toCasbinRule(ptype, rule)))
+	}
+	return domain, toCasbinRule(ptype, stripped), nil
+}
+
+func (a *Adapter) setModel(m model.Model) {
+	a.modelMu.Lock()
+	defer a.modelMu.Unlock()
+	a.model = m
+}
+
+func (a *Adapter) getModel() model.Model {
+	a.modelMu.RLock()
+	defer a.modelMu.RUnlock()
+	return a.model
+}
+
+func toCasbinRule(ptype string, rule []string) CasbinRule {
 	line := CasbinRule{}
 	line.PType = ptype
 	if len(rule) > 0 {
@@ -93,53 +185,234 @@ func (a *Adapter) LoadPolicy(model model.Model) error {
 func (a *Adapter) LoadPolicyCtx(ctx context.Context, model model.Model) error {
 	defer logDuration("loading policies", time.Now())
 	zlog.Debugw("loading policies")
-	lines, err := a.store.GetAllPolicies(ctx)
+	a.setModel(model)
+	sources, err := a.getAll(ctx, model)
 	if err != nil {
-		return err
-	}
-	zlog.Infow("loading policies count", "count", len(lines))
-	for _, line := range lines {
-		err := loadPolicyLine(line, model)
-		if err != nil {
+		if !a.snapshot.enabled() {
 			return err
 		}
+		s, serr := readSnapshot(a.snapshot.Path)
+		if serr != nil {
+			return errors.Join(err, serr)
+		}
+		zlog.Warnf("loading policies failed, using stale snapshot %s from %s: %v", a.snapshot.Path, s.Time.Format(time.RFC3339), err)
+		a.stale.set(s.Time)
+		sources = make([]policySource, 0, len(s.Rules))
+		for _, line := range s.Rules {
+			rule := toRule(a.store.k8sClient.Namespace, line)
+			sources = append(sources, policySource{obj: &rule, lines: []CasbinRule{line}})
+		}
+	} else if a.snapshot.enabled() {
+		a.stale.clear()
+		var lines []CasbinRule
+		for _, source := range sources {
+			lines = append(lines, source.lines...)
+		}
+		if err = writeSnapshot(a.snapshot.Path, &snapshot{Time: a.store.clock.Now(), Rules: lines}); err != nil {
+			zlog.Errorf("write snapshot err: %v", err)
+		}
+	}
+	zlog.Infow("loading policies count", "count", len(sources))
+	a.quarantine.reset()
+	for _, source := range sources {
+		for _, line := range source.lines {
+			if err = a.loadLine(model, source.obj, line); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+// policySource is a Rule, RuleSet, Role, RBAC binding or ClusterRule with its policy lines.
+type policySource struct {
+	obj   client.Object
+	lines []CasbinRule
+}
+
+// getAll returns the policy sources in the load order.
+func (a *Adapter) getAll(ctx context.Context, m model.Model) ([]policySource, error) {
+	rules, err := a.store.GetAllRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ruleSets, err := a.store.GetAllRuleSets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := a.store.GetAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clusterRules, err := a.store.GetAllClusterRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bindings, err := a.getAllBindings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]policySource, 0, len(rules)+len(ruleSets)+len(roles))
+	for i := range rules {
+		sources = append(sources, policySource{obj: &rules[i], lines: []CasbinRule{fromRule(&rules[i])}})
+	}
+	for i := range ruleSets {
+		sources = append(sources, policySource{obj: &ruleSets[i], lines: ruleSetLines(&ruleSets[i])})
+	}
+	for i := range roles {
+		sources = append(sources, policySource{obj: &roles[i], lines: roleLines(&roles[i])})
+	}
+	cluster := make([]policySource, 0, len(clusterRules))
+	for i := range clusterRules {
+		cluster = append(cluster, policySource{obj: &clusterRules[i], lines: []CasbinRule{fromClusterRule(&clusterRules[i])}})
+	}
+	if a.namespaceAsDomain {
+		// the ClusterRules have no namespace and keep their domain
+		for i := range sources {
+			for j, line := range sources[i].lines {
+				sources[i].lines[j] = withDomain(m, line, sources[i].obj.GetNamespace())
+			}
+		}
+	}
+	// the RBAC bindings get their domain from RBACConfig
+	sources = append(sources, bindings...)
+	if a.clusterRulePrecedence == ClusterRulesLast {
+		return append(sources, cluster...), nil
+	}
+	return append(cluster, sources...), nil
+}
+
+// getAllBindings returns the imported RoleBindings and ClusterRoleBindings with their grouping lines.
+func (a *Adapter) getAllBindings(ctx context.Context) ([]policySource, error) {
+	roleBindings, err := a.store.GetAllRoleBindings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clusterRoleBindings, err := a.store.GetAllClusterRoleBindings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]policySource, 0, len(roleBindings)+len(clusterRoleBindings))
+	for i := range roleBindings {
+		sources = append(sources, policySource{obj: &roleBindings[i], lines: roleBindingLines(&roleBindings[i], a.store.rbac)})
+	}
+	for i := range clusterRoleBindings {
+		sources = append(sources, policySource{obj: &clusterRoleBindings[i], lines: clusterRoleBindingLines(&clusterRoleBindings[i], a.store.rbac)})
+	}
+	return sources, nil
+}
+
+// loadLine loads the line into the model. In the tolerant mode a rejected line is quarantined instead of failing.
+func (a *Adapter) loadLine(model model.Model, obj client.Object, line CasbinRule) error {
+	err := validateRoleDomain(obj, a.namespaceAsDomain)
+	if err == nil {
+		err = loadPolicyLine(line, model)
+	}
+	if err == nil {
+		return nil
+	}
+	if !a.tolerant {
+		return err
+	}
+	zlog.Warnf("%s %s/%s line %q skipped: %v", strings.ToLower(objectKind(obj)), obj.GetNamespace(), obj.GetName(), lineString(line), err)
+	a.quarantine.add(obj, line, err)
+	a.events.invalidRule(obj, line, err, a.quarantine.count(obj))
+	return nil
+}
+
+// QuarantinedRules returns the Rules and the RuleSet, Role and RBAC binding lines skipped by the last LoadPolicy in the tolerant mode.
+func (a *Adapter) QuarantinedRules() []QuarantinedRule {
+	return a.quarantine.list()
+}
+
+// StaleSince returns the time of the snapshot and true if the last LoadPolicy used the snapshot instead of the API server.
+func (a *Adapter) StaleSince() (time.Time, bool) {
+	return a.stale.get()
+}
+
 // SavePolicy saves all policy rules to the storage.
 func (a *Adapter) SavePolicy(model model.Model) error {
 	return a.SavePolicyCtx(context.Background(), model)
 }
 
-// SavePolicyCtx saves policy to the storage.
+// SavePolicyCtx saves policy to the storage. The Rules are diffed against the lines of the model: the missing lines are created
+// and the Rules of the removed lines are deleted. The existing Rules are kept unchanged, e.g. with their expiry and priority,
+// and the inactive and the emergency Rules are not deleted as they are not loaded into the model or expire on their own.
+// In the NamespaceAsDomain mode the Rules of all domain namespaces are diffed and a missing line is created in the namespace of its domain.
 func (a *Adapter) SavePolicyCtx(ctx context.Context, model model.Model) error {
 	defer logDuration("saving policies", time.Now())
 	zlog.Debugw("saving policies")
-
-	err := a.store.DeleteAllPolicies(ctx)
+	rules, err := a.store.GetFilteredRules(ctx, a.store.namespaces, CasbinRule{})
 	if err != nil {
 		return err
 	}
-
-	for ptype, ast := range model["p"] {
-		for _, rule := range ast.Policy {
-			line := a.savePolicyLine(ptype, rule)
-			err = a.store.CreatePolicy(ctx, line)
-			if err != nil {
-				return err
+	var lines []CasbinRule
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range model[sec] {
+			for _, rule := range ast.Policy {
+				lines = append(lines, toCasbinRule(ptype, rule))
 			}
 		}
 	}
-	for ptype, ast := range model["g"] {
-		for _, rule := range ast.Policy {
-			line := a.savePolicyLine(ptype, rule)
-			err = a.store.CreatePolicy(ctx, line)
-			if err != nil {
-				return err
-			}
+	desired := make(map[CasbinRule]bool, len(lines))
+	for _, line := range lines {
+		desired[line] = true
+	}
+	now := a.store.clock.Now()
+	existing := make(map[CasbinRule]bool, len(rules))
+	var stale []v1alpha1.Rule
+	for _, rule := range rules {
+		line := a.modelLine(model, &rule)
+		// the emergency Rules are revoked by the BreakGlassReconciler, their lines are neither recreated as permanent Rules nor deleted
+		if isBreakGlassRule(&rule) {
+			existing[line] = true
+			continue
+		}
+		if !ruleActive(&rule, now) {
+			continue
+		}
+		// the quarantined Rules were rejected by the model, they are kept until the Rule or the model is fixed
+		if a.quarantine.contains(&rule, line) {
+			existing[line] = true
+			continue
 		}
+		if desired[line] {
+			existing[line] = true
+			continue
+		}
+		stale = append(stale, rule)
 	}
+	// the lines of the RuleSets, Roles, RBAC bindings and ClusterRules are not written back as Rules,
+	// a copied Rule would keep the line after its source is deleted
+	sources, err := a.getAll(ctx, model)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if _, ok := source.obj.(*v1alpha1.Rule); ok {
+			continue
+		}
+		for _, line := range source.lines {
+			existing[line] = true
+		}
+	}
+	for _, line := range lines {
+		if existing[line] {
+			continue
+		}
+		_, ptype, rule := lineToPolicyParams(line)
+		namespace, stored, err := a.savePolicyLine(ptype, rule)
+		if err != nil {
+			return err
+		}
+		if err = a.store.CreatePolicy(ctx, namespace, stored); err != nil {
+			return err
+		}
+	}
+	if err = a.deleteRules(ctx, ActionSavePolicy, stale); err != nil {
+		return err
+	}
+	a.recordRevision(ctx, OperationSavePolicy)
 	return nil
 }
 
@@ -152,18 +425,31 @@ func (a *Adapter) AddPolicy(sec string, ptype string, rule []string) error {
 func (a *Adapter) AddPolicies(sec string, ptype string, rules [][]string) error {
 	ctx := context.Background()
 	for _, rule := range rules {
-		err := a.AddPolicyCtx(ctx, sec, ptype, rule)
+		err := a.addPolicy(ctx, ptype, rule)
 		if err != nil {
 			return err
 		}
 	}
+	a.recordRevision(ctx, OperationAddPolicy)
 	return nil
 }
 
 // AddPolicyCtx adds a policy rule to the storage.
 func (a *Adapter) AddPolicyCtx(ctx context.Context, sec string, ptype string, rule []string) error {
-	line := a.savePolicyLine(ptype, rule)
-	return a.store.CreatePolicy(ctx, line)
+	err := a.addPolicy(ctx, ptype, rule)
+	if err != nil {
+		return err
+	}
+	a.recordRevision(ctx, OperationAddPolicy)
+	return nil
+}
+
+func (a *Adapter) addPolicy(ctx context.Context, ptype string, rule []string) error {
+	namespace, line, err := a.savePolicyLine(ptype, rule)
+	if err != nil {
+		return err
+	}
+	return a.store.CreatePolicy(ctx, namespace, line)
 }
 
 // RemovePolicy removes a policy rule from the storage.
@@ -175,18 +461,31 @@ func (a *Adapter) RemovePolicy(sec string, ptype string, rule []string) error {
 func (a *Adapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
 	ctx := context.Background()
 	for _, rule := range rules {
-		err := a.RemovePolicyCtx(ctx, sec, ptype, rule)
+		err := a.removePolicy(ctx, ptype, rule)
 		if err != nil {
 			return err
 		}
 	}
+	a.recordRevision(ctx, OperationRemovePolicy)
 	return nil
 }
 
 // RemovePolicyCtx removes a policy rule from the storage.
 func (a *Adapter) RemovePolicyCtx(ctx context.Context, sec string, ptype string, rule []string) error {
-	line := a.savePolicyLine(ptype, rule)
-	return a.store.DeletePolicy(ctx, line)
+	err := a.removePolicy(ctx, ptype, rule)
+	if err != nil {
+		return err
+	}
+	a.recordRevision(ctx, OperationRemovePolicy)
+	return nil
+}
+
+func (a *Adapter) removePolicy(ctx context.Context, ptype string, rule []string) error {
+	namespace, line, err := a.savePolicyLine(ptype, rule)
+	if err != nil {
+		return err
+	}
+	return a.store.DeletePolicy(ctx, namespace, line)
 }
 
 // RemoveFilteredPolicy removes policy rules that match the filter from the storage.
@@ -196,10 +495,13 @@ func (a *Adapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int,
 
 // RemoveFilteredPolicyCtx removes policy rules that match the filter from the storage.
 func (a *Adapter) RemoveFilteredPolicyCtx(ctx context.Context, sec string, ptype string, fieldIndex int, fieldValues ...string) error { //nolint:cyclop
+	if a.namespaceAsDomain {
+		return a.removeDomainFilteredPolicies(ctx, ptype, fieldIndex, fieldValues)
+	}
 	line := CasbinRule{}
 	line.PType = ptype
 	if fieldIndex == -1 {
-		return a.store.DeleteFilteredPolicies(ctx, line)
+		return a.removeFilteredPolicies(ctx, line)
 	}
 	err := a.checkQueryField(fieldValues)
 	if err != nil {
@@ -223,7 +525,91 @@ func (a *Adapter) RemoveFilteredPolicyCtx(ctx context.Context, sec string, ptype
 	if fieldIndex <= 5 && 5 < fieldIndex+len(fieldValues) {
 		line.V5 = fieldValues[5-fieldIndex]
 	}
-	return a.store.DeleteFilteredPolicies(ctx, line)
+	return a.removeFilteredPolicies(ctx, line)
+}
+
+// removeDomainFilteredPolicies removes the Rules matching the filter in the NamespaceAsDomain mode.
+// The domain value of the filter selects the namespace and is stripped like from a written line,
+// a filter without a domain value matches the Rules of all domain namespaces, e.g. for DeleteUser.
+func (a *Adapter) removeDomainFilteredPolicies(ctx context.Context, ptype string, fieldIndex int, fieldValues []string) error {
+	m := a.getModel()
+	if m == nil {
+		return errors.New("the policy must be loaded before it is written")
+	}
+	var rule []string
+	if fieldIndex >= 0 {
+		if err := a.checkQueryField(fieldValues); err != nil {
+			return err
+		}
+		rule = append(make([]string, fieldIndex), fieldValues...)
+	}
+	namespaces := a.store.namespaces
+	if domainIndex(m, ptype) < 0 {
+		// the lines without a domain field are written to the configured namespace
+		namespaces = []string{a.store.k8sClient.Namespace}
+	}
+	domain, stripped := withoutDomain(m, ptype, rule)
+	if domain != "" {
+		namespaces = []string{domain}
+	}
+	rules, err := a.store.GetFilteredRules(ctx, namespaces, toCasbinRule(ptype, stripped))
+	if err != nil {
+		return err
+	}
+	if err = a.deleteRules(ctx, ActionDeletePolicies, rules); err != nil {
+		return err
+	}
+	a.recordRevision(ctx, OperationRemoveFilteredPolicy)
+	return nil
+}
+
+func (a *Adapter) removeFilteredPolicies(ctx context.Context, filter CasbinRule) error {
+	err := a.deletePolicies(ctx, ActionDeletePolicies, filter)
+	if err != nil {
+		return err
+	}
+	a.recordRevision(ctx, OperationRemoveFilteredPolicy)
+	return nil
+}
+
+// deletePolicies deletes the Rules matching the filter in bulk, all Rules if the filter is empty.
+// With an EventReference the number of deleted Rules is reported, the Rules are counted before the deletion.
+func (a *Adapter) deletePolicies(ctx context.Context, action string, filter CasbinRule) error {
+	count := 0
+	if a.events.bulkEnabled() {
+		n, err := a.store.CountPolicies(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("count rules err: %w", err)
+		}
+		count = n
+	}
+	var err error
+	if filter == (CasbinRule{}) {
+		err = a.store.DeleteAllPolicies(ctx)
+	} else {
+		err = a.store.DeleteFilteredPolicies(ctx, filter)
+	}
+	if err != nil {
+		a.events.deletePoliciesFailed(action, filter, count, err)
+		return err
+	}
+	a.events.policiesDeleted(action, filter, count)
+	return nil
+}
+
+// deleteRules deletes the Rules and reports their number like a bulk deletion.
+func (a *Adapter) deleteRules(ctx context.Context, action string, rules []v1alpha1.Rule) error {
+	for i := range rules {
+		// the preconditions protect a Rule which was recreated meanwhile
+		uid := rules[i].UID
+		err := a.store.k8sClient.Delete(ctx, &rules[i], client.Preconditions{UID: &uid})
+		if err = client.IgnoreNotFound(err); err != nil {
+			a.events.deletePoliciesFailed(action, CasbinRule{}, len(rules), err)
+			return fmt.Errorf("delete rule %s err: %w", rules[i].Name, err)
+		}
+	}
+	a.events.policiesDeleted(action, CasbinRule{}, len(rules))
+	return nil
 }
 
 func (a *Adapter) checkQueryField(fieldValues []string) error {
@@ -0,0 +1,50 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="PType",type="string",JSONPath=`.spec.ptype`
+// +kubebuilder:printcolumn:name="V0",type="string",JSONPath=`.spec.v0`
+// +kubebuilder:printcolumn:name="V1",type="string",JSONPath=`.spec.v1`
+// +kubebuilder:printcolumn:name="Disabled",type="boolean",JSONPath=`.spec.disabled`
+// +kubebuilder:printcolumn:name="Priority",type="integer",JSONPath=`.spec.priority`,priority=1
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:selectablefield:JSONPath=.spec.ptype
+// +kubebuilder:selectablefield:JSONPath=.spec.v0
+// +kubebuilder:selectablefield:JSONPath=.spec.v1
+// +kubebuilder:selectablefield:JSONPath=.spec.v2
+// +kubebuilder:selectablefield:JSONPath=.spec.v3
+// +kubebuilder:selectablefield:JSONPath=.spec.v4
+// +kubebuilder:selectablefield:JSONPath=.spec.v5
+
+// ClusterRule is the Schema for the cluster-wide policies API.
+type ClusterRule struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+	// spec defines the desired state of ClusterRule
+	// +required
+	Spec RuleSpec `json:"spec"`
+	// status defines the observed state of ClusterRule
+	// +optional
+	Status RuleStatus `json:"status,omitempty,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterRuleList contains a list of ClusterRule.
+type ClusterRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterRule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterRule{}, &ClusterRuleList{})
+}
@@ -0,0 +1,133 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyChangeRequestLabel links a Rule to the PolicyChangeRequest which created it with the label value as name.
+const PolicyChangeRequestLabel = "casbin.grepplabs.com/change-request"
+
+// PolicyChangeRequestSpec defines the requested change of the Rules.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+// +kubebuilder:validation:XValidation:rule="(has(self.add) ? size(self.add) : 0) + (has(self.remove) ? size(self.remove) : 0) > 0",message="add or remove must not be empty"
+type PolicyChangeRequestSpec struct {
+	// Policy lines of the Rules to create in the namespace of the request
+	// +kubebuilder:validation:MaxItems=1000
+	// +listType=atomic
+	// +optional
+	Add []PolicyLine `json:"add,omitempty"`
+
+	// Policy lines of the Rules to delete from the namespace of the request
+	// +kubebuilder:validation:MaxItems=1000
+	// +listType=atomic
+	// +optional
+	Remove []PolicyLine `json:"remove,omitempty"`
+
+	// Reason of the change shown to the approvers
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Requester is set to the creating user by the admission webhook, a requester cannot approve the request
+	// +optional
+	Requester string `json:"requester,omitempty"`
+}
+
+// PolicyApproval is the sign-off of an approver.
+type PolicyApproval struct {
+	// Name of the approving user, must match the user adding the approval if the admission webhook is installed
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Approver string `json:"approver"`
+
+	// Comment of the approver
+	// +optional
+	Comment string `json:"comment,omitempty"`
+}
+
+const (
+	// PolicyChangeRequestPending waits for the approvals.
+	PolicyChangeRequestPending = "Pending"
+	// PolicyChangeRequestApplied is set after the Rules were created and deleted.
+	PolicyChangeRequestApplied = "Applied"
+	// PolicyChangeRequestFailed is set if the change cannot be applied, e.g. a policy line is invalid for the model.
+	PolicyChangeRequestFailed = "Failed"
+
+	// ConditionTypeApproved reports whether the request has the required number of approvals.
+	ConditionTypeApproved = "Approved"
+
+	// ReasonWaitingForApproval is set while approvals are missing.
+	ReasonWaitingForApproval = "WaitingForApproval"
+	// ReasonApproved is set when the required number of approvals is reached.
+	ReasonApproved = "Approved"
+)
+
+// PolicyChangeRequestStatus defines the observed state of PolicyChangeRequest.
+type PolicyChangeRequestStatus struct {
+	// Phase of the request: Pending, Applied or Failed
+	// +kubebuilder:validation:Enum=Pending;Applied;Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Approvals added by the approvers, e.g. with a JSON patch of the status subresource
+	// +listType=atomic
+	// +optional
+	Approvals []PolicyApproval `json:"approvals"`
+
+	// Distinct approvers counted for the request, the requester is not counted
+	// +listType=atomic
+	// +optional
+	ApprovedBy []string `json:"approvedBy,omitempty"`
+
+	// Time at which the change was applied
+	// +optional
+	AppliedAt *metav1.Time `json:"appliedAt,omitempty"`
+
+	// Message describing the outcome
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// observedGeneration is the most recent generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// conditions represent the current state of the PolicyChangeRequest.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=pcr
+// +kubebuilder:printcolumn:name="Requester",type="string",JSONPath=`.spec.requester`
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Approved",type="string",JSONPath=`.status.conditions[?(@.type=="Approved")].status`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// PolicyChangeRequest is the Schema for the policy change requests API.
+type PolicyChangeRequest struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+	// spec defines the requested change of the Rules
+	// +required
+	Spec PolicyChangeRequestSpec `json:"spec"`
+	// status defines the observed state of PolicyChangeRequest
+	// +optional
+	Status PolicyChangeRequestStatus `json:"status,omitempty,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// PolicyChangeRequestList contains a list of PolicyChangeRequest.
+type PolicyChangeRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PolicyChangeRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PolicyChangeRequest{}, &PolicyChangeRequestList{})
+}
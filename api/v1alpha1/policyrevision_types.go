@@ -0,0 +1,102 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyRevisionLabel groups the revisions of a policy history with the label value as history name.
+const PolicyRevisionLabel = "casbin.grepplabs.com/policy-revision"
+
+// PolicyRevisionSpec defines the recorded state of the policy.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="revisions are immutable"
+type PolicyRevisionSpec struct {
+	// Sequence number of the revision within its history, starting at 1
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	Revision int64 `json:"revision"`
+
+	// Operation which produced the revision, e.g. SavePolicy, AddPolicy or Rollback
+	// +kubebuilder:validation:Required
+	Operation string `json:"operation"`
+
+	// Author of the change
+	// +optional
+	Author string `json:"author,omitempty"`
+
+	// Time of the change
+	// +kubebuilder:validation:Required
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// Message describing the change, e.g. the rolled back revision
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Number of policy lines of the revision
+	// +optional
+	RuleCount int32 `json:"ruleCount,omitempty"`
+
+	// Rules after the change, empty if compressedRules is set.
+	// +listType=atomic
+	// +optional
+	Rules []RevisionRule `json:"rules,omitempty"`
+
+	// Gzip compressed JSON array of the rules, used for large policies instead of rules.
+	// +optional
+	CompressedRules []byte `json:"compressedRules,omitempty"`
+}
+
+// RevisionRule is the policy line of a recorded Rule together with the fields of its spec restored by a rollback.
+type RevisionRule struct {
+	PolicyLine `json:",inline"`
+
+	// Time before which the rule is not active
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+
+	// Time at which the rule expires
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// Schedule activates the rule only in recurring time windows
+	// +optional
+	Schedule *RuleSchedule `json:"schedule,omitempty"`
+
+	// Disabled suspends the rule without deleting it
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Priority orders the rules, a rule with a lower value is loaded first
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Revision",type="integer",JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Operation",type="string",JSONPath=`.spec.operation`
+// +kubebuilder:printcolumn:name="Author",type="string",JSONPath=`.spec.author`
+// +kubebuilder:printcolumn:name="Rules",type="integer",JSONPath=`.spec.ruleCount`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// PolicyRevision is the Schema for the policy revisions API.
+type PolicyRevision struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+	// spec defines the recorded state of the policy
+	// +required
+	Spec PolicyRevisionSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// PolicyRevisionList contains a list of PolicyRevision.
+type PolicyRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PolicyRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PolicyRevision{}, &PolicyRevisionList{})
+}
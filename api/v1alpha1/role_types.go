@@ -0,0 +1,65 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RoleSpec defines the desired state of Role.
+type RoleSpec struct {
+	// Grouping policy type: g, g2, ...
+	// +kubebuilder:validation:Pattern=`^g\d*$`
+	// +kubebuilder:default=g
+	// +optional
+	PType string `json:"ptype,omitempty"`
+
+	// Role name, defaults to the name of the object.
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// Domain of the role for models with domains, e.g. g = _, _, _
+	// +optional
+	Domain string `json:"domain,omitempty"`
+
+	// Members assigned the role, each member is expanded into a grouping line: ptype, member, role[, domain]
+	// +kubebuilder:validation:MaxItems=10000
+	// +listType=set
+	// +optional
+	Members []string `json:"members,omitempty"`
+
+	// Nested roles whose permissions the role inherits, each one is expanded into a grouping line: ptype, role, nested role[, domain]
+	// +kubebuilder:validation:MaxItems=100
+	// +listType=set
+	// +optional
+	Roles []string `json:"roles,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="PType",type="string",JSONPath=`.spec.ptype`
+// +kubebuilder:printcolumn:name="Role",type="string",JSONPath=`.spec.role`
+// +kubebuilder:printcolumn:name="Domain",type="string",JSONPath=`.spec.domain`,priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// Role is the Schema for the roles API, it assigns a role to a list of members.
+type Role struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+	// spec defines the desired state of Role
+	// +required
+	Spec RoleSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// RoleList contains a list of Role.
+type RoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Role `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Role{}, &RoleList{})
+}
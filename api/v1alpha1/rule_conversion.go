@@ -0,0 +1,4 @@
+package v1alpha1
+
+// Hub marks v1alpha1 as the conversion hub of Rule, it is the storage version.
+func (*Rule) Hub() {}
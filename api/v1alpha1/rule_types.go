@@ -4,7 +4,17 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// BreakGlassLabel marks the emergency Rules granted with the break-glass API, the value is "true".
+	BreakGlassLabel = "casbin.grepplabs.com/break-glass"
+	// BreakGlassReasonAnnotation is the mandatory reason of an emergency Rule.
+	BreakGlassReasonAnnotation = "casbin.grepplabs.com/break-glass-reason"
+	// BreakGlassRequesterAnnotation is the user who granted an emergency Rule.
+	BreakGlassRequesterAnnotation = "casbin.grepplabs.com/break-glass-requester"
+)
+
 // RuleSpec defines the desired state of Rule.
+// +kubebuilder:validation:XValidation:rule="!has(self.notBefore) || !has(self.expiresAt) || self.notBefore < self.expiresAt",message="notBefore must be before expiresAt"
 type RuleSpec struct {
 	// Rule type: p, p2, g, g2, ...
 	// +kubebuilder:validation:Required
@@ -42,17 +52,106 @@ type RuleSpec struct {
 	// +kubebuilder:selectablefield:JSONPath=.spec.v5
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="v5 is immutable"
 	V5 string `json:"v5,omitempty"`
+
+	// Time before which the rule is not active
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+
+	// Time at which the rule expires, an expired rule is removed from the enforcer
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// Schedule activates the rule only in recurring time windows, e.g. the business hours
+	// +optional
+	Schedule *RuleSchedule `json:"schedule,omitempty"`
+
+	// Disabled suspends the rule without deleting it
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Priority orders the rules for models depending on the policy order, e.g. priority(p.eft) without a priority field.
+	// A rule with a lower value is loaded first.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+}
+
+// RuleSchedule defines the recurring time windows in which a rule is active, either started by a cron expression
+// and lasting the duration or weekly windows. The windows apply within notBefore and expiresAt.
+// +kubebuilder:validation:XValidation:rule="has(self.cron) ? has(self.duration) && !has(self.windows) : has(self.windows) && !has(self.duration)",message="either cron with duration or windows must be set"
+type RuleSchedule struct {
+	// Time zone of the schedule as IANA name, e.g. Europe/Berlin, defaults to UTC
+	// +kubebuilder:validation:MaxLength=64
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Cron expression starting the windows: minute hour day-of-month month day-of-week, e.g. "0 22 * * Sat"
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	Cron string `json:"cron,omitempty"`
+
+	// Duration of the windows started by the cron expression, e.g. 4h
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// Weekly windows, e.g. Mon-Fri 09:00-17:00
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=32
+	// +listType=atomic
+	// +optional
+	Windows []TimeWindow `json:"windows,omitempty"`
 }
 
+// TimeWindow is a daily time range starting on the selected weekdays.
+type TimeWindow struct {
+	// Weekdays on which the window starts, every day if empty
+	// +kubebuilder:validation:items:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+	// +listType=set
+	// +optional
+	Days []string `json:"days,omitempty"`
+
+	// Start of the window as HH:MM
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End of the window as HH:MM, an end not after the start is on the next day
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+}
+
+const (
+	// ConditionTypeReady reports whether the rule is valid for the model.
+	ConditionTypeReady = "Ready"
+
+	// ReasonAccepted is set when the rule was validated against the model.
+	ReasonAccepted = "Accepted"
+	// ReasonInvalid is set when the model rejects the rule.
+	ReasonInvalid = "Invalid"
+)
+
 // RuleStatus defines the observed state of Rule.
 type RuleStatus struct {
+	// observedGeneration is the most recent generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// conditions represent the current state of the Rule.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:storageversion
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="PType",type="string",JSONPath=`.spec.ptype`
 // +kubebuilder:printcolumn:name="V0",type="string",JSONPath=`.spec.v0`
 // +kubebuilder:printcolumn:name="V1",type="string",JSONPath=`.spec.v1`
+// +kubebuilder:printcolumn:name="Disabled",type="boolean",JSONPath=`.spec.disabled`
+// +kubebuilder:printcolumn:name="Priority",type="integer",JSONPath=`.spec.priority`,priority=1
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 // +kubebuilder:selectablefield:JSONPath=.spec.ptype
 // +kubebuilder:selectablefield:JSONPath=.spec.v0
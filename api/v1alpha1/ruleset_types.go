@@ -0,0 +1,70 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RuleSetLabel groups the shards of a RuleSet with the label value as name.
+const RuleSetLabel = "casbin.grepplabs.com/ruleset"
+
+// PolicyLine is a single policy line of a RuleSet.
+type PolicyLine struct {
+	// Rule type: p, p2, g, g2, ...
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^(p|g)\d*$`
+	PType string `json:"ptype"`
+
+	// Positional parameters v0
+	V0 string `json:"v0,omitempty"`
+
+	// Positional parameters v1
+	V1 string `json:"v1,omitempty"`
+
+	// Positional parameters v2
+	V2 string `json:"v2,omitempty"`
+
+	// Positional parameters v3
+	V3 string `json:"v3,omitempty"`
+
+	// Positional parameters v4
+	V4 string `json:"v4,omitempty"`
+
+	// Positional parameters v5
+	V5 string `json:"v5,omitempty"`
+}
+
+// RuleSetSpec defines the desired state of RuleSet.
+type RuleSetSpec struct {
+	// Policy lines, large policies should be sharded into multiple RuleSets.
+	// +kubebuilder:validation:MaxItems=10000
+	// +listType=atomic
+	// +optional
+	Rules []PolicyLine `json:"rules,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// RuleSet is the Schema for the policy sets API.
+type RuleSet struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+	// spec defines the desired state of RuleSet
+	// +required
+	Spec RuleSetSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// RuleSetList contains a list of RuleSet.
+type RuleSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RuleSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RuleSet{}, &RuleSetList{})
+}
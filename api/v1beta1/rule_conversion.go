@@ -0,0 +1,153 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+const (
+	// DescriptionAnnotation keeps the description of a v1beta1 Rule stored as v1alpha1.
+	DescriptionAnnotation = "casbin.grepplabs.com/description"
+	// FieldsAnnotation keeps the named fields of a v1beta1 Rule stored as v1alpha1 as a JSON object.
+	FieldsAnnotation = "casbin.grepplabs.com/fields"
+
+	maxValues = 6
+)
+
+var _ conversion.Convertible = (*Rule)(nil)
+
+// ConvertTo converts the Rule to the v1alpha1 hub. The named fields are not resolved to values, it requires the model.
+func (src *Rule) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1alpha1.Rule)
+	if !ok {
+		return fmt.Errorf("unsupported hub type %T", dstRaw)
+	}
+	if len(src.Spec.Values) > maxValues {
+		return fmt.Errorf("rule %s has %d values, at most %d are supported", src.Name, len(src.Spec.Values), maxValues)
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	setAnnotation(&dst.ObjectMeta, DescriptionAnnotation, src.Spec.Description)
+	var fields string
+	if len(src.Spec.Fields) != 0 {
+		data, err := json.Marshal(src.Spec.Fields)
+		if err != nil {
+			return fmt.Errorf("marshal fields of rule %s: %w", src.Name, err)
+		}
+		fields = string(data)
+	}
+	setAnnotation(&dst.ObjectMeta, FieldsAnnotation, fields)
+
+	values := make([]string, maxValues)
+	copy(values, src.Spec.Values)
+	dst.Spec = v1alpha1.RuleSpec{
+		PType:     src.Spec.PType,
+		V0:        values[0],
+		V1:        values[1],
+		V2:        values[2],
+		V3:        values[3],
+		V4:        values[4],
+		V5:        values[5],
+		NotBefore: src.Spec.NotBefore.DeepCopy(),
+		ExpiresAt: src.Spec.ExpiresAt.DeepCopy(),
+		Schedule:  scheduleToHub(src.Spec.Schedule),
+		Disabled:  src.Spec.Disabled,
+		Priority:  src.Spec.Priority,
+	}
+	dst.Status = v1alpha1.RuleStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         slices.Clone(src.Status.Conditions),
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub to the Rule. The trailing empty values are dropped.
+func (dst *Rule) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1alpha1.Rule)
+	if !ok {
+		return fmt.Errorf("unsupported hub type %T", srcRaw)
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	description := dst.Annotations[DescriptionAnnotation]
+	var fields map[string]string
+	if data, ok := dst.Annotations[FieldsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), &fields); err != nil {
+			return fmt.Errorf("unmarshal fields of rule %s: %w", src.Name, err)
+		}
+	}
+	setAnnotation(&dst.ObjectMeta, DescriptionAnnotation, "")
+	setAnnotation(&dst.ObjectMeta, FieldsAnnotation, "")
+
+	values := []string{src.Spec.V0, src.Spec.V1, src.Spec.V2, src.Spec.V3, src.Spec.V4, src.Spec.V5}
+	for len(values) > 0 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	if len(values) == 0 {
+		values = nil
+	}
+	dst.Spec = RuleSpec{
+		PType:       src.Spec.PType,
+		Values:      values,
+		Fields:      fields,
+		Description: description,
+		NotBefore:   src.Spec.NotBefore.DeepCopy(),
+		ExpiresAt:   src.Spec.ExpiresAt.DeepCopy(),
+		Schedule:    scheduleFromHub(src.Spec.Schedule),
+		Disabled:    src.Spec.Disabled,
+		Priority:    src.Spec.Priority,
+	}
+	dst.Status = RuleStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         slices.Clone(src.Status.Conditions),
+	}
+	return nil
+}
+
+func scheduleToHub(src *RuleSchedule) *v1alpha1.RuleSchedule {
+	if src == nil {
+		return nil
+	}
+	dst := &v1alpha1.RuleSchedule{
+		TimeZone: src.TimeZone,
+		Cron:     src.Cron,
+		Duration: src.Duration.DeepCopy(),
+	}
+	for _, w := range src.Windows {
+		dst.Windows = append(dst.Windows, v1alpha1.TimeWindow{Days: slices.Clone(w.Days), Start: w.Start, End: w.End})
+	}
+	return dst
+}
+
+func scheduleFromHub(src *v1alpha1.RuleSchedule) *RuleSchedule {
+	if src == nil {
+		return nil
+	}
+	dst := &RuleSchedule{
+		TimeZone: src.TimeZone,
+		Cron:     src.Cron,
+		Duration: src.Duration.DeepCopy(),
+	}
+	for _, w := range src.Windows {
+		dst.Windows = append(dst.Windows, TimeWindow{Days: slices.Clone(w.Days), Start: w.Start, End: w.End})
+	}
+	return dst
+}
+
+// setAnnotation sets the annotation or removes it if the value is empty.
+func setAnnotation(meta *metav1.ObjectMeta, key string, value string) {
+	if value != "" {
+		if meta.Annotations == nil {
+			meta.Annotations = make(map[string]string)
+		}
+		meta.Annotations[key] = value
+		return
+	}
+	delete(meta.Annotations, key)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+}
@@ -0,0 +1,164 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RuleSpec defines the desired state of Rule.
+// +kubebuilder:validation:XValidation:rule="!has(self.notBefore) || !has(self.expiresAt) || self.notBefore < self.expiresAt",message="notBefore must be before expiresAt"
+type RuleSpec struct {
+	// Rule type: p, p2, g, g2, ...
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^(p|g)\d*$`
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="ptype is immutable"
+	PType string `json:"ptype"`
+
+	// Ordered policy values, e.g. [alice, data1, read] for p = sub, obj, act
+	// +kubebuilder:validation:MaxItems=6
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="values are immutable"
+	// +listType=atomic
+	// +optional
+	Values []string `json:"values,omitempty"`
+
+	// Named aliases of the values by the field names of the ptype definition, e.g. {sub: alice, obj: data1, act: read}.
+	// They are resolved to values by the conversion webhook, which knows the model.
+	// +kubebuilder:validation:MaxProperties=6
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="fields are immutable"
+	// +optional
+	Fields map[string]string `json:"fields,omitempty"`
+
+	// Free-form description of the rule
+	// +kubebuilder:validation:MaxLength=1024
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Time before which the rule is not active
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+
+	// Time at which the rule expires, an expired rule is removed from the enforcer
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// Schedule activates the rule only in recurring time windows, e.g. the business hours
+	// +optional
+	Schedule *RuleSchedule `json:"schedule,omitempty"`
+
+	// Disabled suspends the rule without deleting it
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Priority orders the rules for models depending on the policy order, e.g. priority(p.eft) without a priority field.
+	// A rule with a lower value is loaded first.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+}
+
+// RuleSchedule defines the recurring time windows in which a rule is active, either started by a cron expression
+// and lasting the duration or weekly windows. The windows apply within notBefore and expiresAt.
+// +kubebuilder:validation:XValidation:rule="has(self.cron) ? has(self.duration) && !has(self.windows) : has(self.windows) && !has(self.duration)",message="either cron with duration or windows must be set"
+type RuleSchedule struct {
+	// Time zone of the schedule as IANA name, e.g. Europe/Berlin, defaults to UTC
+	// +kubebuilder:validation:MaxLength=64
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Cron expression starting the windows: minute hour day-of-month month day-of-week, e.g. "0 22 * * Sat"
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	Cron string `json:"cron,omitempty"`
+
+	// Duration of the windows started by the cron expression, e.g. 4h
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// Weekly windows, e.g. Mon-Fri 09:00-17:00
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=32
+	// +listType=atomic
+	// +optional
+	Windows []TimeWindow `json:"windows,omitempty"`
+}
+
+// TimeWindow is a daily time range starting on the selected weekdays.
+type TimeWindow struct {
+	// Weekdays on which the window starts, every day if empty
+	// +kubebuilder:validation:items:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+	// +listType=set
+	// +optional
+	Days []string `json:"days,omitempty"`
+
+	// Start of the window as HH:MM
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End of the window as HH:MM, an end not after the start is on the next day
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+}
+
+const (
+	// ConditionTypeReady reports whether the rule is valid for the model.
+	ConditionTypeReady = "Ready"
+
+	// ReasonAccepted is set when the rule was validated against the model.
+	ReasonAccepted = "Accepted"
+	// ReasonInvalid is set when the model rejects the rule.
+	ReasonInvalid = "Invalid"
+)
+
+// RuleStatus defines the observed state of Rule.
+type RuleStatus struct {
+	// observedGeneration is the most recent generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// conditions represent the current state of the Rule.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="PType",type="string",JSONPath=`.spec.ptype`
+// +kubebuilder:printcolumn:name="Sub",type="string",JSONPath=`.spec.fields.sub`
+// +kubebuilder:printcolumn:name="Obj",type="string",JSONPath=`.spec.fields.obj`
+// +kubebuilder:printcolumn:name="Act",type="string",JSONPath=`.spec.fields.act`
+// +kubebuilder:printcolumn:name="Values",type="string",JSONPath=`.spec.values`,priority=1
+// +kubebuilder:printcolumn:name="Description",type="string",JSONPath=`.spec.description`,priority=1
+// +kubebuilder:printcolumn:name="Disabled",type="boolean",JSONPath=`.spec.disabled`
+// +kubebuilder:printcolumn:name="Priority",type="integer",JSONPath=`.spec.priority`,priority=1
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:selectablefield:JSONPath=.spec.ptype
+
+// Rule is the Schema for the policies API.
+type Rule struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+	// spec defines the desired state of Rule
+	// +required
+	Spec RuleSpec `json:"spec"`
+	// status defines the observed state of Rule
+	// +optional
+	Status RuleStatus `json:"status,omitempty,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// RuleList contains a list of Rule.
+type RuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Rule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Rule{}, &RuleList{})
+}
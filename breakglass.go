@@ -0,0 +1,169 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultBreakGlassMaxTTL is the default upper limit of the TTL of an emergency Rule.
+const DefaultBreakGlassMaxTTL = 24 * time.Hour
+
+var (
+	ErrBreakGlassNotFound = errors.New("break-glass rule not found")
+	ErrBreakGlassConflict = errors.New("policy line already exists")
+)
+
+type BreakGlassConfig struct {
+	// MaxTTL is the upper limit of the TTL of the emergency Rules, defaults to DefaultBreakGlassMaxTTL.
+	MaxTTL time.Duration
+}
+
+func (c BreakGlassConfig) maxTTL() time.Duration {
+	if c.MaxTTL > 0 {
+		return c.MaxTTL
+	}
+	return DefaultBreakGlassMaxTTL
+}
+
+// BreakGlassRequest is the emergency access to grant.
+type BreakGlassRequest struct {
+	// PType of the policy line, a p or g type.
+	PType string
+	// Rule is the policy line without the ptype, e.g. [alice, admin] for g.
+	Rule []string
+	// Reason of the emergency access, mandatory.
+	Reason string
+	// TTL after which the Rule expires, mandatory and at most BreakGlassConfig.MaxTTL.
+	TTL time.Duration
+	// Requester recorded in the Rule, defaults to the revision author, see WithRevisionAuthor.
+	Requester string
+}
+
+// BreakGlassGrant is an emergency Rule.
+type BreakGlassGrant struct {
+	Namespace string
+	Name      string
+	Line      CasbinRule
+	Reason    string
+	Requester string
+	GrantedAt time.Time
+	ExpiresAt time.Time
+}
+
+// GrantBreakGlass creates an emergency Rule labelled with v1alpha1.BreakGlassLabel which expires after the TTL.
+// The informer removes the Rule from the enforcer at its expiry, the BreakGlassReconciler deletes it and reports it with Events and metrics.
+// A policy line which already exists is not granted again, it has to be revoked first.
+func (a *Adapter) GrantBreakGlass(ctx context.Context, req BreakGlassRequest) (*BreakGlassGrant, error) {
+	if !strings.HasPrefix(req.PType, "p") && !strings.HasPrefix(req.PType, "g") {
+		return nil, fmt.Errorf("ptype %q is not a policy or grouping type", req.PType)
+	}
+	if len(req.Rule) == 0 {
+		return nil, errors.New("policy line cannot be empty")
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	if req.TTL <= 0 {
+		return nil, errors.New("ttl must be positive")
+	}
+	if maxTTL := a.breakGlass.maxTTL(); req.TTL > maxTTL {
+		return nil, fmt.Errorf("ttl %s exceeds the maximum of %s", req.TTL, maxTTL)
+	}
+	requester := req.Requester
+	if requester == "" {
+		requester = a.revisionAuthor(ctx)
+	}
+	namespace, line, err := a.savePolicyLine(req.PType, req.Rule)
+	if err != nil {
+		return nil, err
+	}
+	now := a.store.clock.Now()
+	rule := toRule(namespace, line)
+	rule.Labels = map[string]string{v1alpha1.BreakGlassLabel: "true"}
+	rule.Annotations = map[string]string{
+		v1alpha1.BreakGlassReasonAnnotation:    reason,
+		v1alpha1.BreakGlassRequesterAnnotation: requester,
+	}
+	rule.Spec.ExpiresAt = &metav1.Time{Time: now.Add(req.TTL).Truncate(time.Second)}
+	if err = a.store.k8sClient.Create(ctx, &rule); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("%w: %q is granted by rule %s/%s", ErrBreakGlassConflict, lineString(line), rule.Namespace, rule.Name)
+		}
+		return nil, fmt.Errorf("create break-glass rule err: %w", err)
+	}
+	zlog.Warnf("break-glass rule %s/%s %q granted by %s until %s: %s", rule.Namespace, rule.Name, lineString(line), requester,
+		rule.Spec.ExpiresAt.Format(time.RFC3339), reason)
+	grant := toBreakGlassGrant(&rule)
+	return &grant, nil
+}
+
+// RevokeBreakGlass deletes the emergency Rule before its expiry, the namespace defaults to the configured one.
+func (a *Adapter) RevokeBreakGlass(ctx context.Context, namespace string, name string) error {
+	if namespace == "" {
+		namespace = a.store.k8sClient.Namespace
+	}
+	rule := &v1alpha1.Rule{}
+	if err := a.store.k8sClient.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, rule); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("%w: %s/%s", ErrBreakGlassNotFound, namespace, name)
+		}
+		return err
+	}
+	if !isBreakGlassRule(rule) {
+		return fmt.Errorf("%w: rule %s/%s is not labelled with %s", ErrBreakGlassNotFound, namespace, name, v1alpha1.BreakGlassLabel)
+	}
+	uid := rule.UID
+	if err := a.store.k8sClient.Delete(ctx, rule, client.Preconditions{UID: &uid}); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	zlog.Warnf("break-glass rule %s/%s %q revoked by %s", namespace, name, lineString(fromRule(rule)), a.revisionAuthor(ctx))
+	return nil
+}
+
+// ListBreakGlass returns the active emergency Rules sorted by their expiry.
+func (a *Adapter) ListBreakGlass(ctx context.Context) ([]BreakGlassGrant, error) {
+	rules, err := a.store.GetAllRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var grants []BreakGlassGrant
+	for i := range rules {
+		if isBreakGlassRule(&rules[i]) && rules[i].DeletionTimestamp.IsZero() {
+			grants = append(grants, toBreakGlassGrant(&rules[i]))
+		}
+	}
+	sort.SliceStable(grants, func(i, j int) bool {
+		return grants[i].ExpiresAt.Before(grants[j].ExpiresAt)
+	})
+	return grants, nil
+}
+
+func isBreakGlassRule(rule *v1alpha1.Rule) bool {
+	return rule.Labels[v1alpha1.BreakGlassLabel] == "true"
+}
+
+func toBreakGlassGrant(rule *v1alpha1.Rule) BreakGlassGrant {
+	grant := BreakGlassGrant{
+		Namespace: rule.Namespace,
+		Name:      rule.Name,
+		Line:      fromRule(rule),
+		Reason:    rule.Annotations[v1alpha1.BreakGlassReasonAnnotation],
+		Requester: rule.Annotations[v1alpha1.BreakGlassRequesterAnnotation],
+		GrantedAt: rule.CreationTimestamp.Time,
+	}
+	if rule.Spec.ExpiresAt != nil {
+		grant.ExpiresAt = rule.Spec.ExpiresAt.Time
+	}
+	return grant
+}
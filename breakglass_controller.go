@@ -0,0 +1,232 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	DefaultBreakGlassControllerName = "casbin-break-glass"
+
+	// ReasonBreakGlassGranted is reported on an emergency Rule when it is created.
+	ReasonBreakGlassGranted = "BreakGlassGranted"
+	// ReasonBreakGlassExpired is reported on an emergency Rule deleted at its expiry.
+	ReasonBreakGlassExpired = "BreakGlassExpired"
+	// ReasonBreakGlassRevoked is reported on an emergency Rule deleted before its expiry.
+	ReasonBreakGlassRevoked = "BreakGlassRevoked"
+
+	ActionGrant  = "Grant"
+	ActionRevoke = "Revoke"
+)
+
+var (
+	breakGlassGrants = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "casbin_kube_break_glass_grants_total",
+		Help: "Number of the granted break-glass Rules.",
+	}, []string{"namespace"})
+	breakGlassRevocations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "casbin_kube_break_glass_revocations_total",
+		Help: "Number of the revoked break-glass Rules by reason, expired or revoked.",
+	}, []string{"namespace", "reason"})
+	breakGlassActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "casbin_kube_break_glass_active_rules",
+		Help: "Number of the break-glass Rules which are not revoked yet.",
+	}, []string{"namespace"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(breakGlassGrants, breakGlassRevocations, breakGlassActive)
+}
+
+type BreakGlassReconcilerConfig struct {
+	// Namespace of the reconciled Rules, all namespaces if empty.
+	Namespace string
+	// Labels selecting the reconciled Rules.
+	Labels map[string]string
+	// MaxTTL revokes an emergency Rule at the latest after the duration since its creation, defaults to DefaultBreakGlassMaxTTL.
+	// It also revokes the Rules whose expiry was removed or extended beyond it.
+	MaxTTL time.Duration
+	// EventRecorder reports the grants and the revocations as Kubernetes Events if set.
+	EventRecorder events.EventRecorder
+	// EventComponent identifies the reporter in the Events, defaults to DefaultBreakGlassControllerName.
+	EventComponent string
+}
+
+// BreakGlassReconciler audits and revokes the emergency Rules labelled with v1alpha1.BreakGlassLabel.
+// It reports each grant and revocation as a Warning Event and in the metrics, and deletes the Rules at their expiry.
+// The Rules created before the start of the reconciler are tracked without reporting them as granted again.
+type BreakGlassReconciler struct {
+	client    client.Client
+	namespace string
+	labels    map[string]string
+	maxTTL    time.Duration
+	events    eventReporter
+	clock     clock.PassiveClock
+	started   time.Time
+
+	mu      sync.Mutex
+	tracked map[types.NamespacedName]*v1alpha1.Rule
+}
+
+func NewBreakGlassReconciler(c client.Client, config *BreakGlassReconcilerConfig) (*BreakGlassReconciler, error) {
+	if c == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.MaxTTL < 0 {
+		return nil, errors.New("max ttl cannot be negative")
+	}
+	r := &BreakGlassReconciler{
+		client:    c,
+		namespace: config.Namespace,
+		labels:    config.Labels,
+		maxTTL:    BreakGlassConfig{MaxTTL: config.MaxTTL}.maxTTL(),
+		events:    newEventReporter(config.EventRecorder, nil, config.EventComponent, DefaultBreakGlassControllerName),
+		clock:     clock.RealClock{},
+		tracked:   make(map[types.NamespacedName]*v1alpha1.Rule),
+	}
+	// the creation timestamps have a second precision
+	r.started = r.clock.Now().Truncate(time.Second)
+	return r, nil
+}
+
+// SetupWithManager watches the emergency Rules, a Rule whose label was removed is reconciled once more to stop tracking it.
+func (r *BreakGlassReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Rule{}, builder.WithPredicates(
+			predicate.Funcs{
+				CreateFunc:  func(e event.CreateEvent) bool { return r.matches(e.Object) },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return r.matches(e.Object) },
+				GenericFunc: func(e event.GenericEvent) bool { return r.matches(e.Object) },
+				UpdateFunc:  func(e event.UpdateEvent) bool { return r.matches(e.ObjectOld) || r.matches(e.ObjectNew) },
+			},
+		)).
+		Named(DefaultBreakGlassControllerName).
+		Complete(r)
+}
+
+func (r *BreakGlassReconciler) matches(obj client.Object) bool {
+	rule, ok := obj.(*v1alpha1.Rule)
+	return ok && isBreakGlassRule(rule) && matchesRule(obj, r.namespace, r.labels)
+}
+
+func (r *BreakGlassReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	rule := &v1alpha1.Rule{}
+	if err := r.client.Get(ctx, req.NamespacedName, rule); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		r.deleted(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+	if !isBreakGlassRule(rule) {
+		if r.untrack(req.NamespacedName) != nil {
+			zlog.Warnf("rule %s is no longer labelled with %s, it is not revoked by the break-glass controller", req.String(), v1alpha1.BreakGlassLabel)
+		}
+		return ctrl.Result{}, nil
+	}
+	if !rule.DeletionTimestamp.IsZero() {
+		r.deleted(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+	r.track(rule)
+
+	if wait := r.deadline(rule).Sub(r.clock.Now()); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+	// the preconditions protect a rule whose expiry was extended meanwhile
+	uid, resourceVersion := rule.UID, rule.ResourceVersion
+	err := r.client.Delete(ctx, rule, client.GracePeriodSeconds(DefaultGracePeriodSeconds), client.Preconditions{UID: &uid, ResourceVersion: &resourceVersion})
+	if err = client.IgnoreNotFound(err); err != nil {
+		return ctrl.Result{}, fmt.Errorf("delete break-glass rule err: %w", err)
+	}
+	r.deleted(req.NamespacedName)
+	return ctrl.Result{}, nil
+}
+
+// deadline returns the expiry of the Rule limited by the max TTL.
+func (r *BreakGlassReconciler) deadline(rule *v1alpha1.Rule) time.Time {
+	deadline := rule.CreationTimestamp.Add(r.maxTTL)
+	if rule.Spec.ExpiresAt != nil && rule.Spec.ExpiresAt.Time.Before(deadline) {
+		deadline = rule.Spec.ExpiresAt.Time
+	}
+	return deadline
+}
+
+// track records the Rule and reports it as granted when it is seen for the first time.
+func (r *BreakGlassReconciler) track(rule *v1alpha1.Rule) {
+	key := client.ObjectKeyFromObject(rule)
+	r.mu.Lock()
+	_, known := r.tracked[key]
+	r.tracked[key] = rule.DeepCopy()
+	r.updateActive(rule.Namespace)
+	r.mu.Unlock()
+	if known || rule.CreationTimestamp.Time.Before(r.started) {
+		return
+	}
+	grant := toBreakGlassGrant(rule)
+	breakGlassGrants.WithLabelValues(rule.Namespace).Inc()
+	zlog.Warnf("break-glass rule %s %q granted by %s until %s: %s", key.String(), lineString(grant.Line), grant.Requester,
+		r.deadline(rule).Format(time.RFC3339), grant.Reason)
+	r.events.eventf(rule, corev1.EventTypeWarning, ReasonBreakGlassGranted, ActionGrant, "Emergency access %q granted to %s by %s until %s: %s",
+		lineString(grant.Line), grant.Line.V0, grant.Requester, r.deadline(rule).Format(time.RFC3339), grant.Reason)
+}
+
+// deleted reports the revocation of a tracked Rule, as expired if it was deleted after its deadline.
+func (r *BreakGlassReconciler) deleted(key types.NamespacedName) {
+	rule := r.untrack(key)
+	if rule == nil {
+		return
+	}
+	grant := toBreakGlassGrant(rule)
+	reason, eventReason := "revoked", ReasonBreakGlassRevoked
+	if !r.clock.Now().Before(r.deadline(rule)) {
+		reason, eventReason = "expired", ReasonBreakGlassExpired
+	}
+	breakGlassRevocations.WithLabelValues(rule.Namespace, reason).Inc()
+	zlog.Warnf("break-glass rule %s %q granted by %s %s", key.String(), lineString(grant.Line), grant.Requester, reason)
+	r.events.eventf(rule, corev1.EventTypeWarning, eventReason, ActionRevoke, "Emergency access %q granted by %s %s",
+		lineString(grant.Line), grant.Requester, reason)
+}
+
+func (r *BreakGlassReconciler) untrack(key types.NamespacedName) *v1alpha1.Rule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rule, ok := r.tracked[key]
+	if !ok {
+		return nil
+	}
+	delete(r.tracked, key)
+	r.updateActive(key.Namespace)
+	return rule
+}
+
+// updateActive sets the gauge of the namespace, the lock must be held.
+func (r *BreakGlassReconciler) updateActive(namespace string) {
+	var active int
+	for key := range r.tracked {
+		if key.Namespace == namespace {
+			active++
+		}
+	}
+	breakGlassActive.WithLabelValues(namespace).Set(float64(active))
+}
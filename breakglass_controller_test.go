@@ -0,0 +1,96 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func breakGlassRule(name string, created time.Time, ttl time.Duration, ptype string, vals ...string) *v1alpha1.Rule {
+	r := namedRule(name, ptype, vals...)
+	r.CreationTimestamp = metav1.NewTime(created)
+	r.Labels = map[string]string{v1alpha1.BreakGlassLabel: "true"}
+	r.Annotations = map[string]string{
+		v1alpha1.BreakGlassReasonAnnotation:    "INC-1",
+		v1alpha1.BreakGlassRequesterAnnotation: "jane",
+	}
+	if ttl != 0 {
+		r.Spec.ExpiresAt = &metav1.Time{Time: created.Add(ttl)}
+	}
+	return r
+}
+
+func Test_BreakGlassReconciler(t *testing.T) {
+	started := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	now := started.Add(time.Hour)
+
+	granted := breakGlassRule("granted", started.Add(30*time.Minute), time.Hour, "g", "alice", "admin")
+	revoked := breakGlassRule("revoked", started.Add(30*time.Minute), time.Hour, "g", "bob", "admin")
+	expired := breakGlassRule("expired", started.Add(time.Minute), 30*time.Minute, "p", "carol", "data1", "write")
+	extended := breakGlassRule("extended", started.Add(-time.Hour), 48*time.Hour, "g", "dave", "admin")
+	permanent := namedRule("permanent", "g", "erin", "admin")
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(granted, revoked, expired, extended, permanent).Build()
+	recorder := events.NewFakeRecorder(10)
+	r, err := NewBreakGlassReconciler(c, &BreakGlassReconcilerConfig{MaxTTL: 2 * time.Hour, EventRecorder: recorder})
+	require.NoError(t, err)
+	fakeClock := clocktesting.NewFakePassiveClock(now)
+	r.clock = fakeClock
+	r.started = started
+
+	grants := testutil.ToFloat64(breakGlassGrants.WithLabelValues(DefaultNamespace))
+	expiries := testutil.ToFloat64(breakGlassRevocations.WithLabelValues(DefaultNamespace, "expired"))
+	revocations := testutil.ToFloat64(breakGlassRevocations.WithLabelValues(DefaultNamespace, "revoked"))
+
+	ctx := context.Background()
+	reconcile := func(obj client.Object) time.Duration {
+		t.Helper()
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+		require.NoError(t, err)
+		return res.RequeueAfter
+	}
+	require.Equal(t, 30*time.Minute, reconcile(granted))
+	require.Contains(t, <-recorder.Events, `Warning BreakGlassGranted Emergency access "g, alice, admin" granted to alice by jane until 2026-10-18T10:30:00Z: INC-1`)
+	require.Equal(t, 30*time.Minute, reconcile(granted))
+	require.Empty(t, recorder.Events, "reported once")
+	require.Equal(t, 30*time.Minute, reconcile(revoked))
+	<-recorder.Events
+
+	// created before the start, the max TTL cuts the extended expiry
+	require.Zero(t, reconcile(extended))
+	require.True(t, apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(extended), &v1alpha1.Rule{})))
+	require.Contains(t, <-recorder.Events, `Warning BreakGlassExpired Emergency access "g, dave, admin" granted by jane expired`)
+
+	require.Zero(t, reconcile(expired))
+	require.True(t, apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(expired), &v1alpha1.Rule{})))
+	require.Contains(t, <-recorder.Events, "Warning BreakGlassGranted")
+	require.Contains(t, <-recorder.Events, "Warning BreakGlassExpired")
+
+	require.NoError(t, c.Delete(ctx, revoked))
+	require.Zero(t, reconcile(revoked))
+	require.Contains(t, <-recorder.Events, `Warning BreakGlassRevoked Emergency access "g, bob, admin" granted by jane revoked`)
+
+	require.Zero(t, reconcile(permanent))
+	require.Empty(t, recorder.Events)
+
+	require.Equal(t, grants+3, testutil.ToFloat64(breakGlassGrants.WithLabelValues(DefaultNamespace)))
+	require.Equal(t, expiries+2, testutil.ToFloat64(breakGlassRevocations.WithLabelValues(DefaultNamespace, "expired")))
+	require.Equal(t, revocations+1, testutil.ToFloat64(breakGlassRevocations.WithLabelValues(DefaultNamespace, "revoked")))
+	require.InDelta(t, 1, testutil.ToFloat64(breakGlassActive.WithLabelValues(DefaultNamespace)), 0)
+
+	fakeClock.SetTime(now.Add(30 * time.Minute))
+	require.Zero(t, reconcile(granted))
+	require.Contains(t, <-recorder.Events, "Warning BreakGlassExpired")
+	require.InDelta(t, 0, testutil.ToFloat64(breakGlassActive.WithLabelValues(DefaultNamespace)), 0)
+}
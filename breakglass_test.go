@@ -0,0 +1,107 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func Test_AdapterBreakGlass(t *testing.T) {
+	bobAdmin := toRule(DefaultNamespace, CasbinRule{PType: "g", V0: "bob", V1: "admin"})
+	a, c := newRevisionTestAdapter(t, RevisionConfig{Enabled: true, Author: "ops"}, interceptor.Funcs{}, &bobAdmin)
+	a.breakGlass = BreakGlassConfig{MaxTTL: 4 * time.Hour}
+	ctx := context.Background()
+	now := time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC)
+
+	for _, invalid := range []struct {
+		req     BreakGlassRequest
+		wantErr string
+	}{
+		{req: BreakGlassRequest{PType: "e", Rule: []string{"some(where (p.eft == allow))"}, Reason: "INC-1", TTL: time.Hour}, wantErr: "not a policy or grouping type"},
+		{req: BreakGlassRequest{PType: "g", Rule: []string{"alice", "admin"}, Reason: " ", TTL: time.Hour}, wantErr: "reason is required"},
+		{req: BreakGlassRequest{PType: "g", Rule: []string{"alice", "admin"}, Reason: "INC-1"}, wantErr: "ttl must be positive"},
+		{req: BreakGlassRequest{PType: "g", Rule: []string{"alice", "admin"}, Reason: "INC-1", TTL: 5 * time.Hour}, wantErr: "exceeds the maximum of 4h0m0s"},
+		{req: BreakGlassRequest{PType: "g", Rule: []string{"bob", "admin"}, Reason: "INC-1", TTL: time.Hour}, wantErr: "policy line already exists"},
+	} {
+		_, err := a.GrantBreakGlass(ctx, invalid.req)
+		require.ErrorContains(t, err, invalid.wantErr)
+	}
+
+	grant, err := a.GrantBreakGlass(WithRevisionAuthor(ctx, "jane"), BreakGlassRequest{
+		PType: "g", Rule: []string{"alice", "admin"}, Reason: "INC-1 database outage", TTL: 2 * time.Hour,
+	})
+	require.NoError(t, err)
+	require.Equal(t, CasbinRule{PType: "g", V0: "alice", V1: "admin"}, grant.Line)
+	require.Equal(t, "jane", grant.Requester)
+	require.Equal(t, now.Add(2*time.Hour), grant.ExpiresAt.UTC())
+
+	rule := &v1alpha1.Rule{}
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: grant.Namespace, Name: grant.Name}, rule))
+	require.True(t, isBreakGlassRule(rule))
+	require.Equal(t, "INC-1 database outage", rule.Annotations[v1alpha1.BreakGlassReasonAnnotation])
+
+	_, err = a.GrantBreakGlass(ctx, BreakGlassRequest{PType: "p", Rule: []string{"carol", "data1", "write"}, Reason: "INC-2", TTL: time.Hour, Requester: "carol"})
+	require.NoError(t, err)
+
+	grants, err := a.ListBreakGlass(ctx)
+	require.NoError(t, err)
+	require.Len(t, grants, 2)
+	require.Equal(t, "carol", grants[0].Requester, "sorted by the expiry")
+	require.Equal(t, "jane", grants[1].Requester)
+
+	// the emergency Rules are not recorded in the revisions, a rollback keeps them until they expire
+	require.NoError(t, a.AddPolicyCtx(ctx, "p", "p", []string{"dave", "data1", "read"}))
+	revisions, err := a.ListRevisions(ctx)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	require.Equal(t, []CasbinRule{{PType: "g", V0: "bob", V1: "admin"}, {PType: "p", V0: "dave", V1: "data1", V2: "read"}}, revisions[0].Rules)
+	diff, err := a.DiffRevisions(ctx, 1, CurrentRevision)
+	require.NoError(t, err)
+	require.True(t, diff.Empty())
+
+	require.ErrorIs(t, a.RevokeBreakGlass(ctx, "", bobAdmin.Name), ErrBreakGlassNotFound)
+	require.NoError(t, a.RevokeBreakGlass(ctx, "", grant.Name))
+	require.True(t, apierrors.IsNotFound(c.Get(ctx, client.ObjectKey{Namespace: grant.Namespace, Name: grant.Name}, rule)))
+	require.ErrorIs(t, a.RevokeBreakGlass(ctx, "", grant.Name), ErrBreakGlassNotFound)
+
+	grants, err = a.ListBreakGlass(ctx)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+}
+
+func Test_AdapterSavePolicyKeepsBreakGlassRules(t *testing.T) {
+	now := time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC)
+	// an emergency Rule named by hand, SavePolicy does not create a permanent Rule for its line
+	incident := breakGlassRule("incident", now.Add(-time.Minute), time.Hour, "g", "alice", "admin")
+	a, c := newRevisionTestAdapter(t, RevisionConfig{}, interceptor.Funcs{}, incident, namedRule("bob", "p", "bob", "data1", "read"))
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+	ok, err := e.HasGroupingPolicy("alice", "admin")
+	requireTrue(t, ok, err)
+
+	e.EnableAutoSave(false)
+	_, err = e.RemovePolicy("bob", "data1", "read")
+	require.NoError(t, err)
+	require.NoError(t, e.SavePolicy())
+
+	ctx := context.Background()
+	rules := &v1alpha1.RuleList{}
+	require.NoError(t, c.List(ctx, rules))
+	require.Len(t, rules.Items, 1)
+	rule := rules.Items[0]
+	require.Equal(t, "incident", rule.Name)
+	require.True(t, isBreakGlassRule(&rule))
+	require.True(t, incident.Spec.ExpiresAt.Equal(rule.Spec.ExpiresAt), "the expiry is kept")
+
+	// the line is removed from the enforcer, the emergency Rule is still revoked by the reconciler only
+	e.ClearPolicy()
+	require.NoError(t, e.SavePolicy())
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(incident), &v1alpha1.Rule{}))
+}
@@ -60,3 +60,29 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Name of the controller resources
+*/}}
+{{- define "casbin-kube.controller.fullname" -}}
+{{- printf "%s-controller" (include "casbin-kube.fullname" .) | trunc 63 | trimSuffix "-" }}
+{{- end }}
+
+{{/*
+Controller selector labels
+*/}}
+{{- define "casbin-kube.controller.selectorLabels" -}}
+{{ include "casbin-kube.selectorLabels" . }}
+app.kubernetes.io/component: controller
+{{- end }}
+
+{{/*
+Create the name of the controller service account to use
+*/}}
+{{- define "casbin-kube.controller.serviceAccountName" -}}
+{{- if .Values.controller.serviceAccount.create }}
+{{- default (include "casbin-kube.controller.fullname" .) .Values.controller.serviceAccount.name }}
+{{- else }}
+{{- default "default" .Values.controller.serviceAccount.name }}
+{{- end }}
+{{- end }}
@@ -0,0 +1,34 @@
+package casbinkube
+
+import (
+	"fmt"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+)
+
+const KindClusterRule = "ClusterRule"
+
+// ClusterRulePrecedence defines whether the ClusterRules are loaded before or after the namespaced Rules and RuleSets.
+// The order matters for models with an order dependent effect, e.g. priority(p.eft) without a priority field.
+// The informer inserts the policy lines added after the initial load at their position in the load order.
+type ClusterRulePrecedence string
+
+const (
+	// ClusterRulesFirst loads the ClusterRules before the namespaced policies, it is the default.
+	ClusterRulesFirst ClusterRulePrecedence = "First"
+	// ClusterRulesLast loads the ClusterRules after the namespaced policies.
+	ClusterRulesLast ClusterRulePrecedence = "Last"
+)
+
+func (p ClusterRulePrecedence) validate() error {
+	switch p {
+	case "", ClusterRulesFirst, ClusterRulesLast:
+		return nil
+	default:
+		return fmt.Errorf("invalid cluster rule precedence %q", p)
+	}
+}
+
+func fromClusterRule(rule *v1alpha1.ClusterRule) CasbinRule {
+	return fromRuleSpec(&rule.Spec)
+}
@@ -0,0 +1,92 @@
+package casbinkube
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_AdapterLoadClusterRules(t *testing.T) {
+	for _, tc := range []struct {
+		precedence ClusterRulePrecedence
+		want       [][]string
+	}{
+		{
+			precedence: "",
+			want:       [][]string{{"admin", "data1", "write"}, {"alice", "data1", "read"}},
+		},
+		{
+			precedence: ClusterRulesFirst,
+			want:       [][]string{{"admin", "data1", "write"}, {"alice", "data1", "read"}},
+		},
+		{
+			precedence: ClusterRulesLast,
+			want:       [][]string{{"alice", "data1", "read"}, {"admin", "data1", "write"}},
+		},
+	} {
+		t.Run(string(tc.precedence), func(t *testing.T) {
+			a := newTestAdapter(t, KubeConfig{ClusterRules: true, ClusterRulePrecedence: tc.precedence, Labels: map[string]string{"app": "a"}},
+				namedRule("alice", "p", "alice", "data1", "read"),
+				namedClusterRule("admin", "p", "admin", "data1", "write"),
+			)
+			e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+			require.NoError(t, err)
+			policies, err := e.GetPolicy()
+			require.NoError(t, err)
+			require.Equal(t, tc.want, policies)
+		})
+	}
+}
+
+func Test_InformerClusterRule(t *testing.T) {
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	ch := make(chan PolicyEvent, 10)
+	w, err := NewInformer(&InformerConfig{
+		KubeConfig:          KubeConfig{Namespace: "ns1", ClusterRules: true},
+		PolicyEventHandlers: []PolicyEventHandler{PolicyEventChannel(ch)},
+	}, e)
+	require.NoError(t, err)
+	h := w.ruleEventHandler()
+
+	admin := namedClusterRule("admin", "p", "admin", "data1", "write")
+	require.True(t, w.filterRule(admin))
+	h.OnAdd(admin, true)
+	event := <-ch
+	require.Equal(t, KindClusterRule, event.Kind)
+	require.Equal(t, "admin", event.Name)
+	require.Empty(t, event.Namespace)
+
+	// the namespaced Rule cannot remove the global policy
+	r := namedRule("admin", "p", "admin", "data1", "write")
+	r.Namespace = "ns1"
+	h.OnAdd(r, false)
+	h.OnDelete(r)
+	require.Empty(t, ch)
+	ok, err := e.Enforce("admin", "data1", "write")
+	requireTrue(t, ok, err)
+
+	h.OnDelete(admin)
+	event = <-ch
+	require.Equal(t, PolicyRemoved, event.Type)
+	ok, err = e.Enforce("admin", "data1", "write")
+	requireFalse(t, ok, err)
+
+	_, err = NewInformer(&InformerConfig{KubeConfig: KubeConfig{ClusterRulePrecedence: "Middle"}}, e)
+	require.Error(t, err)
+}
+
+func namedClusterRule(name string, ptype string, vals ...string) *v1alpha1.ClusterRule {
+	r := rule(ptype, vals...)
+	return &v1alpha1.ClusterRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			UID:  types.UID("uid-cluster-" + name),
+		},
+		Spec: r.Spec,
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grepplabs/loggo/zlog"
+	"github.com/spf13/pflag"
+
+	casbinkube "github.com/grepplabs/casbin-kube"
+)
+
+const usage = `Usage: casbin-kube-break-glass [flags] <command> [args]
+
+Commands:
+  grant <ptype> <values...>   create an emergency Rule, e.g. grant g alice admin --reason INC-42 --ttl 1h
+  revoke <name>               delete an emergency Rule before its expiry
+  list                        list the active emergency Rules
+
+Flags:
+`
+
+type config struct {
+	kubeConfig string
+	kubeCtx    string
+	namespace  string
+	labels     map[string]string
+	requester  string
+	reason     string
+	ttl        time.Duration
+	maxTTL     time.Duration
+	timeout    time.Duration
+}
+
+func main() {
+	cfg := &config{}
+	fs := pflag.CommandLine
+	fs.StringVar(&cfg.kubeConfig, "kubeconfig", "", "Path to the kubeconfig file, the default loading rules apply if empty.")
+	fs.StringVar(&cfg.kubeCtx, "context", "", "Kubeconfig context to use.")
+	fs.StringVarP(&cfg.namespace, "namespace", "n", casbinkube.DefaultNamespace, "Namespace of the Rules.")
+	fs.StringToStringVar(&cfg.labels, "label", nil, "Label added to the emergency Rules (repeatable: --label key=value)")
+	fs.StringVar(&cfg.requester, "requester", os.Getenv("USER"), "Requester recorded in the emergency Rule.")
+	fs.StringVar(&cfg.reason, "reason", "", "Reason of the emergency access, required by grant.")
+	fs.DurationVar(&cfg.ttl, "ttl", 0, "Duration after which the emergency Rule expires, required by grant.")
+	fs.DurationVar(&cfg.maxTTL, "max-ttl", casbinkube.DefaultBreakGlassMaxTTL, "Upper limit of the --ttl.")
+	fs.DurationVar(&cfg.timeout, "timeout", time.Minute, "Timeout of the command.")
+	pflag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	pflag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	defer cancel()
+
+	if err := run(ctx, cfg, pflag.Args(), os.Stdout); err != nil {
+		cancel()
+		zlog.Fatalf("%v", err)
+	}
+}
+
+func run(ctx context.Context, cfg *config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		pflag.Usage()
+		return errors.New("command is required")
+	}
+	adapter, err := casbinkube.NewAdapter(&casbinkube.AdapterConfig{
+		KubeConfig: casbinkube.KubeConfig{
+			Path:      cfg.kubeConfig,
+			Context:   cfg.kubeCtx,
+			Namespace: cfg.namespace,
+			Labels:    cfg.labels,
+		},
+		BreakGlass: casbinkube.BreakGlassConfig{
+			MaxTTL: cfg.maxTTL,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create adapter err: %w", err)
+	}
+	ctx = casbinkube.WithRevisionAuthor(ctx, cfg.requester)
+	command, args := args[0], args[1:]
+	switch command {
+	case "grant":
+		if len(args) < 2 {
+			return fmt.Errorf("expected a ptype and at least one value, got %d arguments", len(args))
+		}
+		grant, err := adapter.GrantBreakGlass(ctx, casbinkube.BreakGlassRequest{
+			PType:     args[0],
+			Rule:      args[1:],
+			Reason:    cfg.reason,
+			TTL:       cfg.ttl,
+			Requester: cfg.requester,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "rule %s/%s %q granted until %s\n", grant.Namespace, grant.Name, lineString(grant.Line), grant.ExpiresAt.Format(time.RFC3339))
+		return nil
+	case "revoke":
+		if len(args) != 1 {
+			return fmt.Errorf("expected the rule name, got %d arguments", len(args))
+		}
+		if err := adapter.RevokeBreakGlass(ctx, cfg.namespace, args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "rule %s/%s revoked\n", cfg.namespace, args[0])
+		return nil
+	case "list":
+		return list(ctx, adapter, out)
+	default:
+		return fmt.Errorf("unknown command %q, expected one of grant, revoke, list", command)
+	}
+}
+
+func list(ctx context.Context, adapter *casbinkube.Adapter, out io.Writer) error {
+	grants, err := adapter.ListBreakGlass(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tPOLICY\tREQUESTER\tEXPIRES\tREASON")
+	for _, g := range grants {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", g.Namespace, g.Name, lineString(g.Line), g.Requester, g.ExpiresAt.Format(time.RFC3339), g.Reason)
+	}
+	return w.Flush()
+}
+
+// lineString returns the policy line in the CSV form of the casbin file adapter, e.g. "g, alice, admin".
+func lineString(line casbinkube.CasbinRule) string {
+	values := []string{line.PType, line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
+	n := len(values)
+	for n > 1 && values[n-1] == "" {
+		n--
+	}
+	return strings.Join(values[:n], ", ")
+}
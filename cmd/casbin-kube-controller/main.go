@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	crcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	casbinkube "github.com/grepplabs/casbin-kube"
+)
+
+// readyzTimeout bounds the wait for the cache in the readiness check.
+const readyzTimeout = 100 * time.Millisecond
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+}
+
+type config struct {
+	metricsAddr             string
+	probeAddr               string
+	leaderElect             bool
+	leaderElectionID        string
+	leaderElectionNamespace string
+
+	namespace    string
+	labels       map[string]string
+	clusterRules bool
+	modelFile    string
+	modelStatus  bool
+
+	namespaceAsDomain bool
+
+	expiredRules         bool
+	expiredRuleRetention time.Duration
+	orphanedRules        bool
+	orphanedGracePeriod  time.Duration
+	invalidRuleRetention time.Duration
+
+	changeRequests         bool
+	changeRequestApprovals int
+
+	breakGlass       bool
+	breakGlassMaxTTL time.Duration
+}
+
+func main() {
+	cfg := parseFlags()
+	ctrl.SetLogger(zlog.Logger)
+
+	if err := run(ctrl.SetupSignalHandler(), cfg); err != nil {
+		zlog.Fatalf("controller failed: %v", err)
+	}
+}
+
+func parseFlags() *config {
+	cfg := &config{}
+	fs := pflag.CommandLine
+	fs.StringVar(&cfg.metricsAddr, "metrics-bind-address", ":8080", "The address the metrics endpoint binds to. Use 0 to disable it.")
+	fs.StringVar(&cfg.probeAddr, "health-probe-bind-address", ":8081", "The address the health probe endpoint binds to.")
+	fs.BoolVar(&cfg.leaderElect, "leader-elect", false, "Enable leader election to ensure there is only one active controller.")
+	fs.StringVar(&cfg.leaderElectionID, "leader-election-id", "casbin-kube-controller", "Name of the lease used for the leader election.")
+	fs.StringVar(&cfg.leaderElectionNamespace, "leader-election-namespace", "", "Namespace of the lease, defaults to the namespace of the pod.")
+	fs.StringVarP(&cfg.namespace, "namespace", "n", "", "Namespace of the reconciled Rules, all namespaces if empty.")
+	fs.StringToStringVar(&cfg.labels, "label", nil, "Label selecting the reconciled rules (repeatable: --label key=value)")
+	fs.BoolVar(&cfg.clusterRules, "cluster-rules", false, "Reconcile the cluster-scoped ClusterRules as well.")
+	fs.StringVar(&cfg.modelFile, "model-file", "", "Casbin model used to set the Ready condition of the Rules. The status is not reconciled if empty.")
+	fs.BoolVar(&cfg.modelStatus, "model-status", true, "Set the Ready condition of the Model resources, the enforcers report their reloads as Events.")
+	fs.BoolVar(&cfg.namespaceAsDomain, "namespace-as-domain", false, "Validate the Rules and the PolicyChangeRequests with their namespace as the domain of the --model-file.")
+	fs.BoolVar(&cfg.expiredRules, "expired-rules", true, "Delete the rules after spec.expiresAt.")
+	fs.DurationVar(&cfg.expiredRuleRetention, "expired-rule-retention", 0, "Keep an expired rule for the duration before it is deleted.")
+	fs.BoolVar(&cfg.orphanedRules, "orphaned-rules", false, "Delete the Rules linked by the casbin.grepplabs.com/model label to a Model which does not exist.")
+	fs.DurationVar(&cfg.orphanedGracePeriod, "orphaned-grace-period", time.Minute, "Keep an orphaned Rule for the duration after its creation.")
+	fs.DurationVar(&cfg.invalidRuleRetention, "invalid-rule-retention", 0, "Delete the Rules invalid for the --model-file for longer than the duration, 0 keeps them.")
+	fs.BoolVar(&cfg.changeRequests, "change-requests", false, "Apply the approved PolicyChangeRequests, the policy lines are validated with the --model-file if set.")
+	fs.IntVar(&cfg.changeRequestApprovals, "change-request-approvals", casbinkube.DefaultRequiredApprovals, "Number of distinct approvers other than the requester of a PolicyChangeRequest.")
+	fs.BoolVar(&cfg.breakGlass, "break-glass", false, "Report the emergency Rules labelled casbin.grepplabs.com/break-glass=true as Events and metrics, and delete them at their expiry.")
+	fs.DurationVar(&cfg.breakGlassMaxTTL, "break-glass-max-ttl", casbinkube.DefaultBreakGlassMaxTTL, "Delete an emergency Rule at the latest after the duration since its creation.")
+	fs.AddGoFlagSet(flag.CommandLine) // --kubeconfig
+	pflag.Parse()
+	return cfg
+}
+
+func run(ctx context.Context, cfg *config) error {
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("get rest config err: %w", err)
+	}
+	opts := ctrl.Options{
+		Scheme:                        scheme,
+		Metrics:                       metricsserver.Options{BindAddress: cfg.metricsAddr},
+		HealthProbeBindAddress:        cfg.probeAddr,
+		LeaderElection:                cfg.leaderElect,
+		LeaderElectionID:              cfg.leaderElectionID,
+		LeaderElectionNamespace:       cfg.leaderElectionNamespace,
+		LeaderElectionReleaseOnCancel: true,
+	}
+	if cfg.namespace != "" {
+		opts.Cache = crcache.Options{DefaultNamespaces: map[string]crcache.Config{cfg.namespace: {}}}
+	}
+	mgr, err := ctrl.NewManager(restConfig, opts)
+	if err != nil {
+		return fmt.Errorf("create manager err: %w", err)
+	}
+	if err = setupReconcilers(mgr, cfg); err != nil {
+		return err
+	}
+	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return fmt.Errorf("add healthz check err: %w", err)
+	}
+	if err = mgr.AddReadyzCheck("readyz", cacheSynced(mgr)); err != nil {
+		return fmt.Errorf("add readyz check err: %w", err)
+	}
+	zlog.Infof("starting controller")
+	return mgr.Start(ctx)
+}
+
+// cacheSynced is ready once the informers of the manager cache have synced, the controller serves no webhooks.
+func cacheSynced(mgr ctrl.Manager) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), readyzTimeout)
+		defer cancel()
+		if !mgr.GetCache().WaitForCacheSync(ctx) {
+			return errors.New("cache is not synced")
+		}
+		return nil
+	}
+}
+
+func setupReconcilers(mgr ctrl.Manager, cfg *config) error {
+	var m model.Model
+	if cfg.modelFile != "" {
+		var err error
+		m, err = model.NewModelFromFile(cfg.modelFile)
+		if err != nil {
+			return fmt.Errorf("load model %s err: %w", cfg.modelFile, err)
+		}
+		r, err := casbinkube.NewRuleStatusReconciler(mgr.GetClient(), &casbinkube.RuleStatusReconcilerConfig{
+			Model:             m,
+			Namespace:         cfg.namespace,
+			Labels:            cfg.labels,
+			NamespaceAsDomain: cfg.namespaceAsDomain,
+		})
+		if err != nil {
+			return err
+		}
+		if err = r.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("setup rule status reconciler err: %w", err)
+		}
+	}
+	if cfg.modelStatus {
+		r, err := casbinkube.NewModelStatusReconciler(mgr.GetClient(), &casbinkube.ModelStatusReconcilerConfig{
+			Namespace: cfg.namespace,
+		})
+		if err != nil {
+			return err
+		}
+		if err = r.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("setup model status reconciler err: %w", err)
+		}
+	}
+	if cfg.expiredRules {
+		r, err := casbinkube.NewExpiredRuleReconciler(mgr.GetClient(), &casbinkube.ExpiredRuleReconcilerConfig{
+			Namespace:    cfg.namespace,
+			Labels:       cfg.labels,
+			ClusterRules: cfg.clusterRules,
+			Retention:    cfg.expiredRuleRetention,
+		})
+		if err != nil {
+			return err
+		}
+		if err = r.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("setup expired rule reconciler err: %w", err)
+		}
+	}
+	if cfg.orphanedRules || cfg.invalidRuleRetention > 0 {
+		r, err := casbinkube.NewRuleGarbageCollector(mgr.GetClient(), &casbinkube.RuleGarbageCollectorConfig{
+			Namespace:           cfg.namespace,
+			Labels:              cfg.labels,
+			Orphaned:            cfg.orphanedRules,
+			OrphanedGracePeriod: cfg.orphanedGracePeriod,
+			InvalidRetention:    cfg.invalidRuleRetention,
+			Model:               m,
+			NamespaceAsDomain:   cfg.namespaceAsDomain,
+		})
+		if err != nil {
+			return err
+		}
+		if err = r.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("setup rule garbage collector err: %w", err)
+		}
+	}
+	if cfg.changeRequests {
+		r, err := casbinkube.NewPolicyChangeRequestReconciler(mgr.GetClient(), &casbinkube.PolicyChangeRequestReconcilerConfig{
+			Namespace:         cfg.namespace,
+			Labels:            cfg.labels,
+			RequiredApprovals: cfg.changeRequestApprovals,
+			Model:             m,
+			NamespaceAsDomain: cfg.namespaceAsDomain,
+		})
+		if err != nil {
+			return err
+		}
+		if err = r.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("setup policy change request reconciler err: %w", err)
+		}
+	}
+	if cfg.breakGlass {
+		r, err := casbinkube.NewBreakGlassReconciler(mgr.GetClient(), &casbinkube.BreakGlassReconcilerConfig{
+			Namespace:     cfg.namespace,
+			Labels:        cfg.labels,
+			MaxTTL:        cfg.breakGlassMaxTTL,
+			EventRecorder: mgr.GetEventRecorder(casbinkube.DefaultBreakGlassControllerName),
+		})
+		if err != nil {
+			return err
+		}
+		if err = r.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("setup break-glass reconciler err: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grepplabs/loggo/zlog"
+	"github.com/spf13/pflag"
+
+	casbinkube "github.com/grepplabs/casbin-kube"
+)
+
+const usage = `Usage: casbin-kube-revision [flags] <command> [args]
+
+Commands:
+  list                   list the recorded revisions
+  show <revision>        print the policy lines of a revision
+  diff <from> [<to>]     print the lines added and removed from one revision to the other, to the current Rules by default
+  rollback <revision>    restore the Rules of a revision and record it as a new revision
+
+Flags:
+`
+
+type config struct {
+	kubeConfig string
+	kubeCtx    string
+	namespace  string
+	labels     map[string]string
+	history    string
+	author     string
+	limit      int
+	compress   bool
+	timeout    time.Duration
+}
+
+func main() {
+	cfg := &config{}
+	fs := pflag.CommandLine
+	fs.StringVar(&cfg.kubeConfig, "kubeconfig", "", "Path to the kubeconfig file, the default loading rules apply if empty.")
+	fs.StringVar(&cfg.kubeCtx, "context", "", "Kubeconfig context to use.")
+	fs.StringVarP(&cfg.namespace, "namespace", "n", casbinkube.DefaultNamespace, "Namespace of the Rules and the PolicyRevisions.")
+	fs.StringToStringVar(&cfg.labels, "label", nil, "Label selecting the rules (repeatable: --label key=value)")
+	fs.StringVar(&cfg.history, "history", casbinkube.DefaultRevisionHistory, "Name of the revision history.")
+	fs.StringVar(&cfg.author, "author", os.Getenv("USER"), "Author recorded in the rollback revision.")
+	fs.IntVar(&cfg.limit, "limit", casbinkube.DefaultRevisionLimit, "Number of kept revisions, the oldest ones are deleted after a rollback.")
+	fs.BoolVar(&cfg.compress, "compress", false, "Store the Rules of the rollback revision gzip compressed.")
+	fs.DurationVar(&cfg.timeout, "timeout", time.Minute, "Timeout of the command.")
+	pflag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	pflag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	defer cancel()
+
+	if err := run(ctx, cfg, pflag.Args(), os.Stdout); err != nil {
+		cancel()
+		zlog.Fatalf("%v", err)
+	}
+}
+
+func run(ctx context.Context, cfg *config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		pflag.Usage()
+		return errors.New("command is required")
+	}
+	adapter, err := casbinkube.NewAdapter(&casbinkube.AdapterConfig{
+		KubeConfig: casbinkube.KubeConfig{
+			Path:      cfg.kubeConfig,
+			Context:   cfg.kubeCtx,
+			Namespace: cfg.namespace,
+			Labels:    cfg.labels,
+		},
+		Revisions: casbinkube.RevisionConfig{
+			Enabled:  true,
+			History:  cfg.history,
+			Author:   cfg.author,
+			Limit:    cfg.limit,
+			Compress: cfg.compress,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create adapter err: %w", err)
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "list":
+		return list(ctx, adapter, out)
+	case "show":
+		revision, err := revisionArgs(args, 1, 1)
+		if err != nil {
+			return err
+		}
+		return show(ctx, adapter, revision[0], out)
+	case "diff":
+		revisions, err := revisionArgs(args, 1, 2)
+		if err != nil {
+			return err
+		}
+		to := casbinkube.CurrentRevision
+		if len(revisions) == 2 {
+			to = revisions[1]
+		}
+		diff, err := adapter.DiffRevisions(ctx, revisions[0], to)
+		if err != nil {
+			return err
+		}
+		printDiff(diff, out)
+		return nil
+	case "rollback":
+		revision, err := revisionArgs(args, 1, 1)
+		if err != nil {
+			return err
+		}
+		diff, err := adapter.RollbackToRevision(ctx, revision[0])
+		if err != nil {
+			return err
+		}
+		printDiff(diff, out)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, expected one of list, show, diff, rollback", command)
+	}
+}
+
+func revisionArgs(args []string, minArgs int, maxArgs int) ([]int64, error) {
+	if len(args) < minArgs || len(args) > maxArgs {
+		return nil, fmt.Errorf("expected %d to %d revision arguments, got %d", minArgs, maxArgs, len(args))
+	}
+	revisions := make([]int64, 0, len(args))
+	for _, arg := range args {
+		revision, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || revision <= 0 {
+			return nil, fmt.Errorf("invalid revision %q", arg)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func list(ctx context.Context, adapter *casbinkube.Adapter, out io.Writer) error {
+	revisions, err := adapter.ListRevisions(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tTIME\tOPERATION\tAUTHOR\tRULES\tMESSAGE")
+	for _, r := range revisions {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\n", r.Revision, r.Time.Format(time.RFC3339), r.Operation, r.Author, len(r.Rules), r.Message)
+	}
+	return w.Flush()
+}
+
+func show(ctx context.Context, adapter *casbinkube.Adapter, revision int64, out io.Writer) error {
+	r, err := adapter.GetRevision(ctx, revision)
+	if err != nil {
+		return err
+	}
+	for _, line := range r.Rules {
+		fmt.Fprintln(out, lineString(line))
+	}
+	return nil
+}
+
+func printDiff(diff *casbinkube.RevisionDiff, out io.Writer) {
+	for _, line := range diff.Removed {
+		fmt.Fprintln(out, "- "+lineString(line))
+	}
+	for _, line := range diff.Added {
+		fmt.Fprintln(out, "+ "+lineString(line))
+	}
+}
+
+// lineString returns the policy line in the CSV form of the casbin file adapter, e.g. "p, alice, data1, read".
+func lineString(line casbinkube.CasbinRule) string {
+	values := []string{line.PType, line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
+	n := len(values)
+	for n > 1 && values[n-1] == "" {
+		n--
+	}
+	return strings.Join(values[:n], ", ")
+}
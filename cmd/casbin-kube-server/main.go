@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/casbin-kube/pdp"
+	"github.com/grepplabs/loggo/zlog"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	crcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	casbinkube "github.com/grepplabs/casbin-kube"
+)
+
+const (
+	component       = "casbin-kube-server"
+	shutdownTimeout = 10 * time.Second
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+}
+
+type config struct {
+	httpAddr     string
+	grpcAddr     string
+	metricsAddr  string
+	probeAddr    string
+	maxBatchSize int
+	tlsCertFile  string
+	tlsKeyFile   string
+
+	subjectAccessReview bool
+	sar                 pdp.SubjectAccessReviewConfig
+	extAuthz            bool
+	extAuthzConfig      pdp.ExtAuthzConfig
+
+	modelFile    string
+	namespace    string
+	labels       map[string]string
+	ruleSets     bool
+	roles        bool
+	clusterRules bool
+	recordEvents bool
+
+	namespaceAsDomain bool
+	domainNamespaces  []string
+}
+
+func main() {
+	cfg := parseFlags()
+	ctrl.SetLogger(zlog.Logger)
+
+	if err := run(ctrl.SetupSignalHandler(), cfg); err != nil {
+		zlog.Fatalf("server failed: %v", err)
+	}
+}
+
+func parseFlags() *config {
+	cfg := &config{}
+	fs := pflag.CommandLine
+	fs.StringVar(&cfg.httpAddr, "http-bind-address", ":8000", "The address the HTTP/JSON API binds to. Use 0 to disable it.")
+	fs.StringVar(&cfg.grpcAddr, "grpc-bind-address", ":9000", "The address the gRPC API binds to. Use 0 to disable it.")
+	fs.StringVar(&cfg.metricsAddr, "metrics-bind-address", ":8080", "The address the metrics endpoint binds to. Use 0 to disable it.")
+	fs.StringVar(&cfg.probeAddr, "health-probe-bind-address", ":8081", "The address the health probe endpoint binds to.")
+	fs.IntVar(&cfg.maxBatchSize, "max-batch-size", pdp.DefaultMaxBatchSize, "Maximum number of requests of a batch enforce call.")
+	fs.StringVar(&cfg.tlsCertFile, "tls-cert-file", "", "TLS certificate of the HTTP/JSON API, it is served without TLS if empty.")
+	fs.StringVar(&cfg.tlsKeyFile, "tls-key-file", "", "TLS key of the HTTP/JSON API.")
+	fs.BoolVar(&cfg.subjectAccessReview, "subject-access-review", false, "Serve the Kubernetes authorization webhook on /v1/subjectaccessreview.")
+	fs.StringSliceVar(&cfg.sar.Request, "sar-request", pdp.DefaultSARRequest, "SubjectAccessReview fields mapped onto the request definition: subject, user, namespace, verb, apiGroup, resource, name. Without namespace the namespaced permissions apply to all namespaces.")
+	fs.StringVar(&cfg.sar.UserPrefix, "sar-user-prefix", "", "Prefix of the reviewed user.")
+	fs.StringVar(&cfg.sar.GroupPrefix, "sar-group-prefix", "", "Prefix of the reviewed groups.")
+	fs.BoolVar(&cfg.sar.Groups, "sar-groups", false, "Enforce the groups of the user as the subject if the user is denied.")
+	fs.BoolVar(&cfg.sar.Deny, "sar-deny", false, "Deny the reviews which are not allowed instead of having no opinion.")
+	fs.BoolVar(&cfg.extAuthz, "ext-authz", false, "Serve the Envoy ext_authz v3 Authorization service on the gRPC address.")
+	fs.StringSliceVar(&cfg.extAuthzConfig.Request, "ext-authz-request", pdp.DefaultExtAuthzRequest, "CheckRequest attributes mapped onto the request definition: principal, path, method, host, metadata:<filter>:<path>, header:<name>. A header is set by the client unless Envoy removes it.")
+	fs.BoolVar(&cfg.extAuthzConfig.FailOpen, "ext-authz-fail-open", false, "Allow the ext_authz checks until the informer has synced instead of denying them.")
+	fs.StringVar(&cfg.modelFile, "model-file", "", "Casbin model of the enforcer (required).")
+	fs.StringVarP(&cfg.namespace, "namespace", "n", casbinkube.DefaultNamespace, "Namespace of the Rules, RuleSets and Roles, see --namespace-as-domain to read several namespaces.")
+	fs.StringToStringVar(&cfg.labels, "label", nil, "Label selecting the rules (repeatable: --label key=value)")
+	fs.BoolVar(&cfg.ruleSets, "rule-sets", false, "Load the policy lines of the RuleSets as well.")
+	fs.BoolVar(&cfg.roles, "roles", false, "Expand the member lists of the Roles into grouping lines as well.")
+	fs.BoolVar(&cfg.clusterRules, "cluster-rules", false, "Load the cluster-scoped ClusterRules as well.")
+	fs.BoolVar(&cfg.namespaceAsDomain, "namespace-as-domain", false, "Use the namespace of the Rules, RuleSets and Roles as the domain of their policy lines, the Rules are read from the --domain-namespace.")
+	fs.StringSliceVar(&cfg.domainNamespaces, "domain-namespace", nil, "Tenant namespace read with --namespace-as-domain (repeatable), all namespaces if empty.")
+	fs.BoolVar(&cfg.recordEvents, "record-events", false, "Report the rules which could not be applied as Kubernetes Events.")
+	fs.AddGoFlagSet(flag.CommandLine) // --kubeconfig
+	pflag.Parse()
+	return cfg
+}
+
+func run(ctx context.Context, cfg *config) error {
+	if cfg.modelFile == "" {
+		return errors.New("--model-file is required")
+	}
+	if cfg.namespace == "" {
+		return errors.New("--namespace cannot be empty")
+	}
+	if (cfg.tlsCertFile == "") != (cfg.tlsKeyFile == "") {
+		return errors.New("--tls-cert-file and --tls-key-file must be set together")
+	}
+	enforcer, err := casbin.NewSyncedEnforcer(cfg.modelFile)
+	if err != nil {
+		return fmt.Errorf("create enforcer err: %w", err)
+	}
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("get rest config err: %w", err)
+	}
+	opts := ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: cfg.metricsAddr},
+		HealthProbeBindAddress: cfg.probeAddr,
+	}
+	if namespaces := cacheNamespaces(cfg); len(namespaces) != 0 {
+		// the ClusterRules are cluster-scoped and not restricted by the namespaces
+		opts.Cache = crcache.Options{DefaultNamespaces: namespaces}
+	}
+	mgr, err := ctrl.NewManager(restConfig, opts)
+	if err != nil {
+		return fmt.Errorf("create manager err: %w", err)
+	}
+	informerConfig := casbinkube.InformerConfig{
+		KubeConfig: casbinkube.KubeConfig{
+			Namespace:         cfg.namespace,
+			Labels:            cfg.labels,
+			RuleSets:          cfg.ruleSets,
+			Roles:             cfg.roles,
+			ClusterRules:      cfg.clusterRules,
+			NamespaceAsDomain: cfg.namespaceAsDomain,
+			DomainNamespaces:  cfg.domainNamespaces,
+		},
+	}
+	if cfg.recordEvents {
+		informerConfig.EventRecorder = mgr.GetEventRecorder(component)
+		informerConfig.EventComponent = component
+	}
+	informer, err := casbinkube.NewManagedInformer(mgr, &casbinkube.ManagedInformerConfig{InformerConfig: informerConfig}, enforcer)
+	if err != nil {
+		return err
+	}
+	serverConfig := &pdp.ServerConfig{
+		Enforcer:     enforcer,
+		Ready:        informer.HasSynced,
+		MaxBatchSize: cfg.maxBatchSize,
+	}
+	if cfg.subjectAccessReview {
+		serverConfig.SubjectAccessReview = &cfg.sar
+	}
+	if cfg.extAuthz {
+		if cfg.grpcAddr == "0" {
+			return errors.New("--ext-authz requires the gRPC address")
+		}
+		serverConfig.ExtAuthz = &cfg.extAuthzConfig
+	}
+	server, err := pdp.NewServer(serverConfig)
+	if err != nil {
+		return err
+	}
+	if err = addServers(mgr, server, cfg); err != nil {
+		return err
+	}
+	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return fmt.Errorf("add healthz check err: %w", err)
+	}
+	zlog.Infof("starting server")
+	return mgr.Start(ctx)
+}
+
+// cacheNamespaces returns the namespaces of the Rules watched by the manager cache, all namespaces if empty.
+func cacheNamespaces(cfg *config) map[string]crcache.Config {
+	namespaces := make(map[string]crcache.Config)
+	if !cfg.namespaceAsDomain {
+		namespaces[cfg.namespace] = crcache.Config{}
+		return namespaces
+	}
+	for _, ns := range cfg.domainNamespaces {
+		namespaces[ns] = crcache.Config{}
+	}
+	return namespaces
+}
+
+func addServers(mgr ctrl.Manager, server *pdp.Server, cfg *config) error {
+	if cfg.httpAddr != "0" {
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return serveHTTP(ctx, server, cfg)
+		})); err != nil {
+			return fmt.Errorf("add http server err: %w", err)
+		}
+	}
+	if cfg.grpcAddr != "0" {
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return serveGRPC(ctx, server, cfg.grpcAddr)
+		})); err != nil {
+			return fmt.Errorf("add grpc server err: %w", err)
+		}
+	}
+	return nil
+}
+
+func serveHTTP(ctx context.Context, server *pdp.Server, cfg *config) error {
+	srv := &http.Server{
+		Addr:              cfg.httpAddr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		zlog.Infof("serving http on %s", cfg.httpAddr)
+		if cfg.tlsCertFile != "" {
+			errCh <- srv.ListenAndServeTLS(cfg.tlsCertFile, cfg.tlsKeyFile)
+			return
+		}
+		errCh <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("serve http err: %w", err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown http server err: %w", err)
+	}
+	return nil
+}
+
+func serveGRPC(ctx context.Context, server *pdp.Server, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen grpc err: %w", err)
+	}
+	gs, hs := server.NewGRPCServer()
+	go server.WatchHealth(ctx, hs, time.Second)
+
+	errCh := make(chan error, 1)
+	go func() {
+		zlog.Infof("serving grpc on %s", addr)
+		errCh <- gs.Serve(lis)
+	}()
+	select {
+	case err = <-errCh:
+		return fmt.Errorf("serve grpc err: %w", err)
+	case <-ctx.Done():
+	}
+	stopped := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		gs.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package casbinkube
+
+import (
+	"slices"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/constant"
+	"github.com/casbin/casbin/v3/model"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// domainIndex returns the index of the domain field of the ptype, -1 if the ptype has no domain.
+// The domain of a policy definition is the field named dom, e.g. p = sub, dom, obj, act, the domain of a role definition is its third field.
+func domainIndex(m model.Model, ptype string) int {
+	if ptype == "" {
+		return -1
+	}
+	sec := ptype[:1]
+	ast, ok := m[sec][ptype]
+	if !ok {
+		return -1
+	}
+	switch sec {
+	case "p":
+		return slices.Index(ast.Tokens, ptype+"_"+constant.DomainIndex)
+	case "g":
+		if len(ast.Tokens) >= 3 {
+			return 2
+		}
+	}
+	return -1
+}
+
+// withDomain inserts the domain into the line at the domain field of its ptype.
+func withDomain(m model.Model, line CasbinRule, domain string) CasbinRule {
+	index := domainIndex(m, line.PType)
+	if index < 0 || domain == "" {
+		return line
+	}
+	_, ptype, rule := lineToPolicyParams(line)
+	for len(rule) < index {
+		rule = append(rule, "")
+	}
+	return toCasbinRule(ptype, slices.Insert(slices.Clone(rule), index, domain))
+}
+
+// validateRuleLine validates the line of a Rule in the namespace, in the NamespaceAsDomain mode with the namespace injected as the domain.
+func validateRuleLine(m model.Model, line CasbinRule, namespace string, namespaceAsDomain bool) error {
+	if namespaceAsDomain {
+		line = withDomain(m, line, namespace)
+	}
+	return validatePolicyLine(m, line)
+}
+
+// withoutDomain removes the domain field from the policy rule and returns the domain, empty if the ptype has no domain.
+func withoutDomain(m model.Model, ptype string, rule []string) (string, []string) {
+	index := domainIndex(m, ptype)
+	if index < 0 || index >= len(rule) {
+		return "", rule
+	}
+	return rule[index], slices.Delete(slices.Clone(rule), index, index+1)
+}
+
+// domainLine injects the namespace of the object as the domain in the NamespaceAsDomain mode.
+func (w *Informer) domainLine(obj client.Object, line CasbinRule) CasbinRule {
+	if !w.kubeConfig.NamespaceAsDomain || obj.GetNamespace() == "" {
+		return line
+	}
+	if se, ok := w.enforcer.(*casbin.SyncedEnforcer); ok {
+		lock := se.GetLock()
+		lock.RLock()
+		defer lock.RUnlock()
+	}
+	return withDomain(w.enforcer.GetModel(), line, obj.GetNamespace())
+}
+
+// domainLines injects the namespace of the object as the domain of the lines in the NamespaceAsDomain mode.
+func (w *Informer) domainLines(obj client.Object, lines []CasbinRule) []CasbinRule {
+	for i, line := range lines {
+		lines[i] = w.domainLine(obj, line)
+	}
+	return lines
+}
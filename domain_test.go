@@ -0,0 +1,285 @@
+package casbinkube
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const domainModel = `
+[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, dom, obj, act
+p2 = sub, obj, act
+
+[role_definition]
+g = _, _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub, r.dom) && r.dom == p.dom && r.obj == p.obj && r.act == p.act
+`
+
+func newDomainModel(t *testing.T) model.Model {
+	t.Helper()
+	m, err := model.NewModelFromString(domainModel)
+	require.NoError(t, err)
+	return m
+}
+
+func tenantRule(namespace, name, ptype string, vals ...string) *v1alpha1.Rule {
+	r := namedRule(name, ptype, vals...)
+	r.Namespace = namespace
+	r.UID = types.UID("uid-" + namespace + "-" + name)
+	return r
+}
+
+func Test_Domain(t *testing.T) {
+	m := newDomainModel(t)
+	require.Equal(t, 1, domainIndex(m, "p"))
+	require.Equal(t, -1, domainIndex(m, "p2"))
+	require.Equal(t, 2, domainIndex(m, "g"))
+	require.Equal(t, -1, domainIndex(m, "g2"))
+
+	line := withDomain(m, CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"}, "tenant1")
+	require.Equal(t, CasbinRule{PType: "p", V0: "alice", V1: "tenant1", V2: "data1", V3: "read"}, line)
+	line = withDomain(m, CasbinRule{PType: "g", V0: "alice", V1: "admin"}, "tenant1")
+	require.Equal(t, CasbinRule{PType: "g", V0: "alice", V1: "admin", V2: "tenant1"}, line)
+	line = withDomain(m, CasbinRule{PType: "p2", V0: "alice", V1: "data1", V2: "read"}, "tenant1")
+	require.Equal(t, CasbinRule{PType: "p2", V0: "alice", V1: "data1", V2: "read"}, line)
+
+	domain, rule := withoutDomain(m, "p", []string{"alice", "tenant1", "data1", "read"})
+	require.Equal(t, "tenant1", domain)
+	require.Equal(t, []string{"alice", "data1", "read"}, rule)
+	domain, rule = withoutDomain(m, "p2", []string{"alice", "data1", "read"})
+	require.Empty(t, domain)
+	require.Equal(t, []string{"alice", "data1", "read"}, rule)
+}
+
+func Test_AdapterNamespaceAsDomain(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAdapter(t, KubeConfig{NamespaceAsDomain: true, DomainNamespaces: []string{"tenant1", "tenant2"}, ClusterRules: true},
+		tenantRule("tenant1", "admin", "p", "admin", "data1", "read"),
+		tenantRule("tenant1", "alice", "g", "alice", "admin"),
+		tenantRule("tenant2", "admin", "p", "admin", "data2", "read"),
+		tenantRule("tenant3", "bob", "g", "bob", "admin"),
+		&v1alpha1.ClusterRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "root"},
+			Spec:       v1alpha1.RuleSpec{PType: "g", V0: "root", V1: "admin", V2: "tenant2"},
+		},
+	)
+	e, err := casbin.NewEnforcer(newDomainModel(t), a)
+	require.NoError(t, err)
+
+	ok, err := e.Enforce("alice", "tenant1", "data1", "read")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("alice", "tenant2", "data2", "read")
+	requireFalse(t, ok, err)
+	ok, err = e.Enforce("root", "tenant2", "data2", "read")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("bob", "tenant3", "data1", "read")
+	requireFalse(t, ok, err)
+
+	// the domain is stripped and used as the namespace
+	_, err = e.AddGroupingPolicy("carol", "admin", "tenant2")
+	require.NoError(t, err)
+	rule := &v1alpha1.Rule{}
+	require.NoError(t, a.store.k8sClient.Client.Get(ctx, types.NamespacedName{
+		Namespace: "tenant2",
+		Name:      keyFor(CasbinRule{PType: "g", V0: "carol", V1: "admin"}),
+	}, rule))
+	require.Equal(t, "carol", rule.Spec.V0)
+	require.Empty(t, rule.Spec.V2)
+
+	require.NoError(t, e.LoadPolicy())
+	ok, err = e.Enforce("carol", "tenant2", "data2", "read")
+	requireTrue(t, ok, err)
+
+	_, err = e.RemoveGroupingPolicy("carol", "admin", "tenant2")
+	require.NoError(t, err)
+	require.NoError(t, e.LoadPolicy())
+	ok, err = e.Enforce("carol", "tenant2", "data2", "read")
+	requireFalse(t, ok, err)
+
+	// a filter without a domain matches the Rules of all domain namespaces
+	_, err = e.AddGroupingPolicy("alice", "admin", "tenant2")
+	require.NoError(t, err)
+	_, err = e.DeleteUser("alice")
+	require.NoError(t, err)
+	require.Equal(t, []string{"tenant1/admin", "tenant2/admin", "tenant3/bob"}, storedRules(t, a))
+
+	// the domain of the filter selects the namespace
+	_, err = e.RemoveFilteredPolicy(1, "tenant2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"tenant1/admin", "tenant3/bob"}, storedRules(t, a))
+
+	// the lines are saved into the namespaces of their domains, the ClusterRules are not written back
+	_, err = e.AddGroupingPolicy("dave", "admin", "tenant1")
+	require.NoError(t, err)
+	require.NoError(t, e.LoadPolicy())
+	m := e.GetModel()
+	_, err = m.RemovePolicy("g", "g", []string{"dave", "admin", "tenant1"})
+	require.NoError(t, err)
+	require.NoError(t, m.AddPolicy("p", "p", []string{"admin", "tenant2", "data2", "write"}))
+	require.NoError(t, e.SavePolicy())
+	require.Equal(t, []string{"tenant1/admin", "tenant2/" + keyFor(CasbinRule{PType: "p", V0: "admin", V1: "data2", V2: "write"}), "tenant3/bob"}, storedRules(t, a))
+	require.NoError(t, e.LoadPolicy())
+	ok, err = e.Enforce("root", "tenant2", "data2", "write")
+	requireTrue(t, ok, err)
+}
+
+// storedRules returns the namespaced names of the stored Rules sorted.
+func storedRules(t *testing.T, a *Adapter) []string {
+	t.Helper()
+	l := &v1alpha1.RuleList{}
+	require.NoError(t, a.store.k8sClient.Client.List(context.Background(), l))
+	names := make([]string, 0, len(l.Items))
+	for _, rule := range l.Items {
+		names = append(names, rule.Namespace+"/"+rule.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func Test_InformerNamespaceAsDomain(t *testing.T) {
+	e, err := casbin.NewSyncedEnforcer(newDomainModel(t))
+	require.NoError(t, err)
+	w, err := NewInformer(&InformerConfig{
+		KubeConfig: KubeConfig{NamespaceAsDomain: true, DomainNamespaces: []string{"tenant1", "tenant2"}, Roles: true},
+	}, e)
+	require.NoError(t, err)
+
+	h := w.ruleEventHandler()
+	for _, obj := range []*v1alpha1.Rule{
+		tenantRule("tenant1", "admin", "p", "admin", "data1", "read"),
+		tenantRule("tenant2", "admin", "p", "admin", "data2", "read"),
+		tenantRule("tenant3", "admin", "p", "admin", "data3", "read"),
+	} {
+		h.OnAdd(obj, false) // tenant3 is filtered out
+	}
+	role := namedRole("admin", v1alpha1.RoleSpec{Members: []string{"alice"}})
+	role.Namespace = "tenant1"
+	w.roleEventHandler().OnAdd(role, false)
+
+	policies, err := e.GetPolicy()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"admin", "tenant1", "data1", "read"}, {"admin", "tenant2", "data2", "read"}}, policies)
+	ok, err := e.Enforce("alice", "tenant1", "data1", "read")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("alice", "tenant2", "data2", "read")
+	requireFalse(t, ok, err)
+
+	w.roleEventHandler().OnDelete(role)
+	ok, err = e.Enforce("alice", "tenant1", "data1", "read")
+	requireFalse(t, ok, err)
+}
+
+func Test_NamespaceAsDomainRejectsRoleDomain(t *testing.T) {
+	role := namedRole("admin", v1alpha1.RoleSpec{Domain: "tenant2", Members: []string{"alice"}})
+	role.Namespace = "tenant1"
+
+	e, err := casbin.NewSyncedEnforcer(newDomainModel(t))
+	require.NoError(t, err)
+	w, err := NewInformer(&InformerConfig{
+		KubeConfig: KubeConfig{NamespaceAsDomain: true, DomainNamespaces: []string{"tenant1"}, Roles: true},
+	}, e)
+	require.NoError(t, err)
+	w.roleEventHandler().OnAdd(role, false)
+	groupings, err := e.GetGroupingPolicy()
+	require.NoError(t, err)
+	require.Empty(t, groupings)
+	quarantined := w.QuarantinedRules()
+	require.Len(t, quarantined, 1)
+	require.Equal(t, KindRole, quarantined[0].Kind)
+	require.Contains(t, quarantined[0].Reason, errRoleDomain.Error())
+
+	// the domain is cleared, the namespace becomes the domain of the lines
+	fixed := role.DeepCopy()
+	fixed.Spec.Domain = ""
+	w.roleEventHandler().OnUpdate(role, fixed)
+	groupings, err = e.GetGroupingPolicy()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"alice", "admin", "tenant1"}}, groupings)
+	require.Empty(t, w.QuarantinedRules())
+
+	a := newTestAdapter(t, KubeConfig{NamespaceAsDomain: true, DomainNamespaces: []string{"tenant1"}, Roles: true}, role)
+	_, err = casbin.NewEnforcer(newDomainModel(t), a)
+	require.ErrorIs(t, err, errRoleDomain)
+
+	a.tolerant = true
+	e2, err := casbin.NewEnforcer(newDomainModel(t), a)
+	require.NoError(t, err)
+	groupings, err = e2.GetGroupingPolicy()
+	require.NoError(t, err)
+	require.Empty(t, groupings)
+	require.Len(t, a.QuarantinedRules(), 1)
+}
+
+func Test_NamespaceAsDomainValidation(t *testing.T) {
+	m := newDomainModel(t)
+	ctx := context.Background()
+	tenant := tenantRule("tenant1", "admin", "p", "admin", "data1", "read")
+	require.Error(t, validateRuleLine(m, fromRule(tenant), tenant.Namespace, false), "the domain is missing")
+	require.NoError(t, validateRuleLine(m, fromRule(tenant), tenant.Namespace, true))
+	withDom := tenantRule("tenant1", "dom", "p", "admin", "tenant2", "data1", "read")
+	require.Error(t, validateRuleLine(m, fromRule(withDom), withDom.Namespace, true), "the domain is set by the namespace")
+
+	v, err := NewRuleValidator(nil, &RuleValidatorConfig{Model: m, NamespaceAsDomain: true})
+	require.NoError(t, err)
+	_, err = v.ValidateCreate(ctx, tenant)
+	require.NoError(t, err)
+	_, err = v.ValidateCreate(ctx, withDom)
+	require.Error(t, err)
+
+	cond := ruleReadyCondition(m, tenant, true)
+	require.Equal(t, metav1.ConditionTrue, cond.Status)
+
+	// a Ready condition set without the mode does not delete the tenant Rule
+	tenant.Status.Conditions = []metav1.Condition{ruleReadyCondition(m, tenant, false)}
+	require.Equal(t, metav1.ConditionFalse, tenant.Status.Conditions[0].Status)
+	gc, err := NewRuleGarbageCollector(fake.NewClientBuilder().WithScheme(scheme).Build(), &RuleGarbageCollectorConfig{
+		InvalidRetention:  time.Hour,
+		Model:             m,
+		NamespaceAsDomain: true,
+	})
+	require.NoError(t, err)
+	_, invalid := gc.invalidWait(tenant, time.Now())
+	require.False(t, invalid)
+
+	pcr, err := NewPolicyChangeRequestReconciler(fake.NewClientBuilder().WithScheme(scheme).Build(), &PolicyChangeRequestReconcilerConfig{
+		Model:             m,
+		NamespaceAsDomain: true,
+	})
+	require.NoError(t, err)
+	request := &v1alpha1.PolicyChangeRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "grant", Namespace: "tenant1"},
+		Spec: v1alpha1.PolicyChangeRequestSpec{
+			Add: []v1alpha1.PolicyLine{{PType: "p", V0: "bob", V1: "data1", V2: "read"}, {PType: "g", V0: "bob", V1: "admin"}},
+		},
+	}
+	require.NoError(t, pcr.validate(request))
+
+	w, err := NewPolicyChangeRequestWebhook(&PolicyChangeRequestWebhookConfig{Model: m, NamespaceAsDomain: true, Controller: "controller"})
+	require.NoError(t, err)
+	request.Spec.Requester = "dave"
+	_, err = w.ValidateCreate(withAdmissionUser("dave"), request)
+	require.NoError(t, err)
+
+	request.Spec.Add[0].V3 = "write"
+	require.ErrorContains(t, pcr.validate(request), "is invalid")
+	_, err = w.ValidateCreate(withAdmissionUser("dave"), request)
+	require.ErrorContains(t, err, "spec.add[0]")
+}
@@ -0,0 +1,126 @@
+package casbinkube
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ReasonApplyFailed is reported on a Rule, RuleSet, Role or RBAC binding whose valid line could not be updated in or removed from the enforcer.
+	ReasonApplyFailed = "ApplyFailed"
+	// ReasonPoliciesDeleted is reported on the AdapterConfig.EventReference after a bulk deletion of Rules.
+	ReasonPoliciesDeleted = "PoliciesDeleted"
+	// ReasonDeletePoliciesFailed is reported on the AdapterConfig.EventReference if a bulk deletion of Rules failed.
+	ReasonDeletePoliciesFailed = "DeletePoliciesFailed"
+	// ReasonModelLoaded is reported on a Model by each ModelEnforcer which loaded its generation.
+	ReasonModelLoaded = "ModelLoaded"
+	// ReasonModelRejected is reported on a Model by each ModelEnforcer which could not load its generation and kept the previous model.
+	ReasonModelRejected = "ModelRejected"
+
+	ActionUpdateRule     = "Update"
+	ActionRemoveRule     = "Remove"
+	ActionDeletePolicies = "DeletePolicies"
+	ActionSavePolicy     = "SavePolicy"
+	ActionReloadModel    = "ReloadModel"
+
+	DefaultAdapterEventComponent  = "casbin-kube-adapter"
+	DefaultInformerEventComponent = "casbin-kube-informer"
+)
+
+// eventReporter records the Kubernetes Events of the adapter or the informer, each note names the reporting component.
+type eventReporter struct {
+	recorder  events.EventRecorder
+	reference *corev1.ObjectReference
+	identity  string
+}
+
+// newEventReporter returns a reporter identified by the component and the host name, i.e. the pod name in a cluster.
+func newEventReporter(recorder events.EventRecorder, reference *corev1.ObjectReference, component string, defaultComponent string) eventReporter {
+	if component == "" {
+		component = defaultComponent
+	}
+	identity := component
+	if host, err := os.Hostname(); err == nil && host != "" {
+		identity += " on " + host
+	}
+	return eventReporter{recorder: recorder, reference: reference, identity: identity}
+}
+
+func (r eventReporter) eventf(regarding client.Object, eventtype, reason, action, note string, args ...interface{}) {
+	if r.recorder == nil || regarding.GetUID() == "" {
+		return
+	}
+	r.recorder.Eventf(regarding, nil, eventtype, reason, action, "%s; reported by %s", fmt.Sprintf(note, args...), r.identity)
+}
+
+// invalidRule reports the line rejected by the enforcer, quarantined is the number of quarantined lines of the object.
+func (r eventReporter) invalidRule(obj client.Object, line CasbinRule, reason error, quarantined int) {
+	kind := objectKind(obj)
+	if multiLine(kind) {
+		r.eventf(obj, corev1.EventTypeWarning, ReasonInvalidRule, ActionApplyRule, "Policy line %q rejected by the enforcer, %d line(s) of the %s quarantined: %s",
+			lineString(line), quarantined, kind, reason.Error())
+		return
+	}
+	r.eventf(obj, corev1.EventTypeWarning, ReasonInvalidRule, ActionApplyRule, "Rule rejected by the enforcer: %s", reason.Error())
+}
+
+// applyFailed reports the line which could not be updated in or removed from the enforcer.
+func (r eventReporter) applyFailed(obj client.Object, action string, line CasbinRule, err error) {
+	r.eventf(obj, corev1.EventTypeWarning, ReasonApplyFailed, action, "Policy line %q not applied to the enforcer: %s", lineString(line), err.Error())
+}
+
+// modelLoaded reports the generation of the Model loaded by the enforcer.
+func (r eventReporter) modelLoaded(obj *v1alpha1.Model) {
+	r.eventf(obj, corev1.EventTypeNormal, ReasonModelLoaded, ActionReloadModel, "Model generation %d loaded", obj.Generation)
+}
+
+// modelRejected reports the generation of the Model which the enforcer could not load, kept is the generation still in use.
+func (r eventReporter) modelRejected(obj *v1alpha1.Model, kept int64, err error) {
+	r.eventf(obj, corev1.EventTypeWarning, ReasonModelRejected, ActionReloadModel, "Model generation %d not loaded, keeping generation %d: %s",
+		obj.Generation, kept, err.Error())
+}
+
+// bulkEnabled returns true if the bulk operations are reported, the Rules to be deleted are counted only then.
+func (r eventReporter) bulkEnabled() bool {
+	return r.recorder != nil && r.reference != nil
+}
+
+// policiesDeleted reports the number of Rules deleted by a bulk operation.
+func (r eventReporter) policiesDeleted(action string, filter CasbinRule, count int) {
+	if !r.bulkEnabled() {
+		return
+	}
+	r.recorder.Eventf(r.reference, nil, corev1.EventTypeNormal, ReasonPoliciesDeleted, action, "Deleted %d Rule(s) matching %s; reported by %s",
+		count, filterString(filter), r.identity)
+}
+
+// deletePoliciesFailed reports a failed bulk operation with the number of Rules which were to be deleted.
+func (r eventReporter) deletePoliciesFailed(action string, filter CasbinRule, count int, err error) {
+	if !r.bulkEnabled() {
+		return
+	}
+	r.recorder.Eventf(r.reference, nil, corev1.EventTypeWarning, ReasonDeletePoliciesFailed, action, "Deleting %d Rule(s) matching %s failed: %s; reported by %s",
+		count, filterString(filter), err.Error(), r.identity)
+}
+
+// filterString returns the non-empty fields of the filter, e.g. "ptype=p, v0=alice", or "all" if there are none.
+func filterString(filter CasbinRule) string {
+	var fields []string
+	for _, f := range []struct{ name, value string }{
+		{"ptype", filter.PType}, {"v0", filter.V0}, {"v1", filter.V1}, {"v2", filter.V2}, {"v3", filter.V3}, {"v4", filter.V4}, {"v5", filter.V5},
+	} {
+		if f.value != "" {
+			fields = append(fields, f.name+"="+f.value)
+		}
+	}
+	if len(fields) == 0 {
+		return "all"
+	}
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,120 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newEventsTestAdapter(t *testing.T, recorder events.EventRecorder, funcs interceptor.Funcs, objs ...client.Object) *Adapter {
+	t.Helper()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithIndex(&v1alpha1.Rule{}, "spec.ptype", func(obj client.Object) []string {
+			return []string{obj.(*v1alpha1.Rule).Spec.PType} //nolint:forcetypeassert
+		}).
+		WithIndex(&v1alpha1.Rule{}, "spec.v0", func(obj client.Object) []string {
+			return []string{obj.(*v1alpha1.Rule).Spec.V0} //nolint:forcetypeassert
+		}).
+		WithIndex(&v1alpha1.Rule{}, "spec.v1", func(obj client.Object) []string {
+			return []string{obj.(*v1alpha1.Rule).Spec.V1} //nolint:forcetypeassert
+		}).
+		WithInterceptorFuncs(funcs).
+		Build()
+	reference := &corev1.ObjectReference{Kind: "Deployment", APIVersion: "apps/v1", Namespace: DefaultNamespace, Name: "app", UID: "uid-app"}
+	a := &Adapter{
+		events: newEventReporter(recorder, reference, "my-app", DefaultAdapterEventComponent),
+		store: &k8sAdapter{
+			clock:      clock.RealClock{},
+			namespaces: []string{DefaultNamespace},
+			k8sClient: &k8sClient[*v1alpha1.Rule, *v1alpha1.RuleList]{
+				New:       func() *v1alpha1.Rule { return &v1alpha1.Rule{} },
+				NewList:   func() *v1alpha1.RuleList { return &v1alpha1.RuleList{} },
+				Client:    c,
+				Namespace: DefaultNamespace,
+			},
+		},
+	}
+	return a
+}
+
+func Test_AdapterBulkDeleteEvents(t *testing.T) {
+	recorder := events.NewFakeRecorder(10)
+	var deleted []client.DeleteAllOfOption
+	// the fake client ignores the field selectors of DeleteAllOf, the deletions are recorded instead
+	a := newEventsTestAdapter(t, recorder, interceptor.Funcs{
+		DeleteAllOf: func(_ context.Context, _ client.WithWatch, _ client.Object, opts ...client.DeleteAllOfOption) error {
+			deleted = append(deleted, opts...)
+			return nil
+		},
+	},
+		namedRule("alice-read", "p", "alice", "data1", "read"),
+		namedRule("alice-write", "p", "alice", "data1", "write"),
+		namedRule("bob-read", "p", "bob", "data1", "read"),
+		namedRule("bob-admin", "g", "bob", "admin"),
+	)
+
+	require.NoError(t, a.RemoveFilteredPolicy("p", "p", 0, "alice"))
+	require.NotEmpty(t, deleted)
+	require.Len(t, recorder.Events, 1)
+	require.Contains(t, <-recorder.Events, "Normal PoliciesDeleted Deleted 2 Rule(s) matching ptype=p, v0=alice; reported by my-app")
+
+	require.NoError(t, a.RemoveFilteredPolicy("g", "g", -1))
+	require.Contains(t, <-recorder.Events, "Deleted 1 Rule(s) matching ptype=g;")
+
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	require.NoError(t, err)
+	require.NoError(t, a.SavePolicy(m))
+	require.Contains(t, <-recorder.Events, "Deleted 4 Rule(s) matching all;")
+}
+
+func Test_AdapterBulkDeleteFailedEvent(t *testing.T) {
+	recorder := events.NewFakeRecorder(10)
+	a := newEventsTestAdapter(t, recorder, interceptor.Funcs{
+		DeleteAllOf: func(_ context.Context, _ client.WithWatch, _ client.Object, _ ...client.DeleteAllOfOption) error {
+			return errors.New("forbidden")
+		},
+	}, namedRule("alice-read", "p", "alice", "data1", "read"))
+
+	require.ErrorContains(t, a.RemoveFilteredPolicy("p", "p", 1, "data1"), "forbidden")
+	require.Len(t, recorder.Events, 1)
+	require.Contains(t, <-recorder.Events, "Warning DeletePoliciesFailed Deleting 1 Rule(s) matching ptype=p, v1=data1 failed: forbidden; reported by my-app")
+
+	// no events without a reference
+	a.events.reference = nil
+	require.Error(t, a.RemoveFilteredPolicy("p", "p", 0, "alice"))
+	require.Empty(t, recorder.Events)
+}
+
+func Test_EventReporter(t *testing.T) {
+	recorder := events.NewFakeRecorder(10)
+	r := newEventReporter(recorder, nil, "", DefaultInformerEventComponent)
+	require.Contains(t, r.identity, DefaultInformerEventComponent)
+	require.False(t, r.bulkEnabled())
+
+	rs := namedRuleSet("readers", "p, alice, data1, read")
+	line := CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"}
+	r.applyFailed(rs, ActionRemoveRule, line, errors.New("boom"))
+	require.Contains(t, <-recorder.Events, `Warning ApplyFailed Policy line "p, alice, data1, read" not applied to the enforcer: boom; reported by casbin-kube-informer`)
+
+	r.invalidRule(rs, line, errors.New("invalid"), 2)
+	require.Contains(t, <-recorder.Events, "Warning InvalidRule Policy line \"p, alice, data1, read\" rejected by the enforcer, 2 line(s) of the RuleSet quarantined: invalid")
+
+	// objects without UID, e.g. in unit tests, are not reported
+	rs.UID = types.UID("")
+	r.applyFailed(rs, ActionRemoveRule, line, errors.New("boom"))
+	require.Empty(t, recorder.Events)
+
+	require.Equal(t, "all", filterString(CasbinRule{}))
+	require.Equal(t, "ptype=p, v2=read, v5=x", filterString(CasbinRule{PType: "p", V2: "read", V5: "x"}))
+}
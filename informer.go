@@ -4,13 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/casbin/casbin/v3"
 	"github.com/grepplabs/casbin-kube/api/v1alpha1"
 	"github.com/grepplabs/loggo/zlog"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	crcache "sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,12 +29,44 @@ type InformerConfig struct {
 	SyncPeriod *time.Duration
 	// SkipDisableAuto keeps Casbin AutoSave and AutoNotifyWatcher enabled if true.
 	SkipDisableAuto bool
+	// Snapshot of the policy used to seed the enforcer if the informer cannot sync, e.g. the API server is unreachable.
+	Snapshot SnapshotConfig
+	// EventRecorder reports the quarantined Rules and the lines which could not be applied as Kubernetes Events if set.
+	EventRecorder events.EventRecorder
+	// EventComponent identifies the reporter in the Events, defaults to DefaultInformerEventComponent.
+	EventComponent string
+	// PolicyEventHandlers are notified after a policy change was applied to the enforcer. See also Informer.Subscribe.
+	PolicyEventHandlers []PolicyEventHandler
+	// PolicyEventQueueSize is the number of policy events queued for the handlers, defaults to DefaultPolicyEventQueueSize.
+	PolicyEventQueueSize int
+	// Clock activates the time-bound and the scheduled rules at their transitions, defaults to the real clock.
+	Clock clock.WithDelayedExecution
 }
 
 type Informer struct {
 	enforcer   casbin.IEnforcer
 	kubeConfig KubeConfig
 	syncPeriod *time.Duration
+	handlers   policyEventHandlers
+	snapshot   SnapshotConfig
+	stale      staleState
+	dirty      atomic.Bool
+	quarantine quarantine
+	events     eventReporter
+	clock      clock.WithDelayedExecution
+
+	// refs are the objects providing a policy line, the line is removed from the enforcer with the last one.
+	// orders keeps the load order of the lines, a line provided by several objects keeps the order of the first one.
+	// seeded are the lines seeded from the snapshot, each one holds a reference until the informer is synced.
+	refsMu sync.Mutex
+	refs   map[string]*lineRefs
+	orders map[string]lineOrder
+	seeded []CasbinRule
+
+	// ruleMu serializes the Rule and ClusterRule events and the activity transitions of the rules.
+	ruleMu   sync.Mutex
+	inactive map[string]struct{}
+	timers   map[string]*ruleTimer
 
 	stop context.CancelFunc
 }
@@ -41,15 +79,40 @@ func NewInformer(config *InformerConfig, e casbin.IEnforcer) (*Informer, error)
 	if kubeConfig.Namespace == "" {
 		kubeConfig.Namespace = DefaultNamespace
 	}
+	if err := kubeConfig.ClusterRulePrecedence.validate(); err != nil {
+		return nil, err
+	}
 	if !config.SkipDisableAuto {
 		e.EnableAutoSave(false) // must be set for readonly i.e. when it is used with informer
 		e.EnableAutoNotifyWatcher(false)
 	}
-	return &Informer{
+	w := &Informer{
 		enforcer:   e,
 		kubeConfig: kubeConfig,
 		syncPeriod: config.SyncPeriod,
-	}, nil
+		snapshot:   config.Snapshot,
+		events:     newEventReporter(config.EventRecorder, nil, config.EventComponent, DefaultInformerEventComponent),
+		clock:      config.Clock,
+	}
+	w.handlers.queueSize = config.PolicyEventQueueSize
+	if w.clock == nil {
+		w.clock = clock.RealClock{}
+	}
+	for _, h := range config.PolicyEventHandlers {
+		w.Subscribe(h)
+	}
+	return w, nil
+}
+
+// Subscribe adds a handler notified after each policy change applied to the enforcer.
+// The returned function removes the handler.
+func (w *Informer) Subscribe(handler PolicyEventHandler) func() {
+	return w.handlers.subscribe(handler)
+}
+
+// PolicyEventOverflows returns how many times the queue of the policy event handlers was full and a PolicyResync event was sent.
+func (w *Informer) PolicyEventOverflows() uint64 {
+	return w.handlers.overflows.Load()
 }
 
 func (w *Informer) Start(ctx context.Context) error { //nolint:cyclop,funlen
@@ -62,12 +125,15 @@ func (w *Informer) Start(ctx context.Context) error { //nolint:cyclop,funlen
 	if err != nil {
 		return fmt.Errorf("get rest config err: %w", err)
 	}
+	namespaces := make(map[string]crcache.Config)
+	for _, ns := range w.kubeConfig.ruleNamespaces() {
+		namespaces[ns] = crcache.Config{}
+	}
 	opts := crcache.Options{
-		Scheme: scheme,
-		DefaultNamespaces: map[string]crcache.Config{
-			w.kubeConfig.Namespace: {},
-		},
-		SyncPeriod: w.syncPeriod, // nil disables periodic resync (normal)
+		Scheme:            scheme,
+		DefaultNamespaces: namespaces,
+		SyncPeriod:        w.syncPeriod,    // nil disables periodic resync (normal)
+		Mapper:            newRESTMapper(), // no discovery, the cache can be created when the API server is unreachable
 	}
 	if len(w.kubeConfig.Labels) > 0 {
 		opts.ByObject = map[client.Object]crcache.ByObject{
@@ -75,54 +141,46 @@ func (w *Informer) Start(ctx context.Context) error { //nolint:cyclop,funlen
 				Label: labels.SelectorFromSet(w.kubeConfig.Labels),
 			},
 		}
+		if w.kubeConfig.RuleSets {
+			opts.ByObject[&v1alpha1.RuleSet{}] = crcache.ByObject{
+				Label: labels.SelectorFromSet(w.kubeConfig.Labels),
+			}
+		}
+		if w.kubeConfig.Roles {
+			opts.ByObject[&v1alpha1.Role{}] = crcache.ByObject{
+				Label: labels.SelectorFromSet(w.kubeConfig.Labels),
+			}
+		}
+		if w.kubeConfig.ClusterRules {
+			opts.ByObject[&v1alpha1.ClusterRule{}] = crcache.ByObject{
+				Label: labels.SelectorFromSet(w.kubeConfig.Labels),
+			}
+		}
+	}
+	if w.kubeConfig.RBAC.Enabled {
+		// the bindings are selected by their own namespaces and labels
+		if opts.ByObject == nil {
+			opts.ByObject = make(map[client.Object]crcache.ByObject)
+		}
+		bindingNamespaces := make(map[string]crcache.Config)
+		for _, ns := range w.kubeConfig.RBAC.namespaces() {
+			bindingNamespaces[ns] = crcache.Config{}
+		}
+		opts.ByObject[&rbacv1.RoleBinding{}] = crcache.ByObject{
+			Namespaces: bindingNamespaces,
+			Label:      labels.SelectorFromSet(w.kubeConfig.RBAC.Labels),
+		}
+		opts.ByObject[&rbacv1.ClusterRoleBinding{}] = crcache.ByObject{
+			Label: labels.SelectorFromSet(w.kubeConfig.RBAC.Labels),
+		}
 	}
 	c, err := crcache.New(cfg, opts)
 	if err != nil {
 		return fmt.Errorf("create cache err: %w", err)
 	}
-	inf, err := c.GetInformer(ctx, &v1alpha1.Rule{})
+	reg, err := w.addEventHandler(ctx, c)
 	if err != nil {
-		return fmt.Errorf("get informer err: %w", err)
-	}
-	reg, err := inf.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
-		AddFunc: func(obj interface{}, isInInitialList bool) {
-			if r, ok := obj.(*v1alpha1.Rule); ok {
-				level := 0 // info
-				if isInInitialList {
-					level = 1 // debug
-				}
-				zlog.Vf(level, "ADD(%t) %s/%s ptype=%s v0=%s", isInInitialList, r.Namespace, r.Name, r.Spec.PType, r.Spec.V0)
-				_, err := w.enforcer.SelfAddPolicy(toPolicyParams(r))
-				if err != nil {
-					zlog.Errorf("add policy err: %s", err)
-				}
-			}
-		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			rNew, ok1 := newObj.(*v1alpha1.Rule)
-			rOld, ok2 := oldObj.(*v1alpha1.Rule)
-			if ok1 && ok2 {
-				zlog.Infof("UPDATE %s/%s ptype=%s v0=%s", rNew.Namespace, rNew.Name, rNew.Spec.PType, rNew.Spec.V0)
-				sec, ptype, newRule := toPolicyParams(rNew)
-				oldRule := toPolicyRuleArray(rOld)
-				_, err := w.enforcer.SelfUpdatePolicy(sec, ptype, oldRule, newRule)
-				if err != nil {
-					zlog.Errorf("update policy err: %s", err)
-				}
-			}
-		},
-		DeleteFunc: func(obj interface{}) {
-			if r, ok := obj.(*v1alpha1.Rule); ok {
-				zlog.Infof("DELETE %s/%s ptype=%s v0=%s", r.Namespace, r.Name, r.Spec.PType, r.Spec.V0)
-				_, err := w.enforcer.SelfRemovePolicy(toPolicyParams(r))
-				if err != nil {
-					zlog.Errorf("remoove policy err: %s", err)
-				}
-			}
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("adds an event handler err: %w", err)
+		return err
 	}
 	go func() {
 		defer zlog.Infof("informer stopped")
@@ -132,16 +190,400 @@ func (w *Informer) Start(ctx context.Context) error { //nolint:cyclop,funlen
 		}
 	}()
 	zlog.Infof("wait for the informer to sync")
-	if ok := cache.WaitForCacheSync(ctx.Done(), reg.HasSynced); !ok {
-		return fmt.Errorf("failed to wait for caches to sync: %w", err)
+	if err = w.waitForSync(ctx, reg); err != nil {
+		return err
 	}
 	zlog.Infof("informer started")
 	return nil
 }
+
+// StaleSince returns the time of the snapshot and true while the enforcer serves policies seeded from the snapshot.
+func (w *Informer) StaleSince() (time.Time, bool) {
+	return w.stale.get()
+}
+
 func (w *Informer) Close() {
 	if w.stop != nil {
 		w.stop()
 	}
+	w.stopTimers()
+	w.handlers.close()
+}
+
+func (w *Informer) addEventHandler(ctx context.Context, c crcache.Informers) (cache.ResourceEventHandlerRegistration, error) {
+	inf, err := c.GetInformer(ctx, &v1alpha1.Rule{})
+	if err != nil {
+		return nil, fmt.Errorf("get informer err: %w", err)
+	}
+	reg, err := inf.AddEventHandler(w.ruleEventHandler())
+	if err != nil {
+		return nil, fmt.Errorf("adds an event handler err: %w", err)
+	}
+	regs := registrations{reg}
+	if w.kubeConfig.RuleSets {
+		inf, err = c.GetInformer(ctx, &v1alpha1.RuleSet{})
+		if err != nil {
+			return nil, fmt.Errorf("get ruleset informer err: %w", err)
+		}
+		reg, err = inf.AddEventHandler(w.ruleSetEventHandler())
+		if err != nil {
+			return nil, fmt.Errorf("adds a ruleset event handler err: %w", err)
+		}
+		regs = append(regs, reg)
+	}
+	if w.kubeConfig.Roles {
+		inf, err = c.GetInformer(ctx, &v1alpha1.Role{})
+		if err != nil {
+			return nil, fmt.Errorf("get role informer err: %w", err)
+		}
+		reg, err = inf.AddEventHandler(w.roleEventHandler())
+		if err != nil {
+			return nil, fmt.Errorf("adds a role event handler err: %w", err)
+		}
+		regs = append(regs, reg)
+	}
+	if w.kubeConfig.RBAC.Enabled {
+		inf, err = c.GetInformer(ctx, &rbacv1.RoleBinding{})
+		if err != nil {
+			return nil, fmt.Errorf("get rolebinding informer err: %w", err)
+		}
+		reg, err = inf.AddEventHandler(w.bindingEventHandler())
+		if err != nil {
+			return nil, fmt.Errorf("adds a rolebinding event handler err: %w", err)
+		}
+		regs = append(regs, reg)
+		inf, err = c.GetInformer(ctx, &rbacv1.ClusterRoleBinding{})
+		if err != nil {
+			return nil, fmt.Errorf("get clusterrolebinding informer err: %w", err)
+		}
+		reg, err = inf.AddEventHandler(w.bindingEventHandler())
+		if err != nil {
+			return nil, fmt.Errorf("adds a clusterrolebinding event handler err: %w", err)
+		}
+		regs = append(regs, reg)
+	}
+	if w.kubeConfig.ClusterRules {
+		inf, err = c.GetInformer(ctx, &v1alpha1.ClusterRule{})
+		if err != nil {
+			return nil, fmt.Errorf("get clusterrule informer err: %w", err)
+		}
+		reg, err = inf.AddEventHandler(w.ruleEventHandler())
+		if err != nil {
+			return nil, fmt.Errorf("adds a clusterrule event handler err: %w", err)
+		}
+		regs = append(regs, reg)
+	}
+	return regs, nil
+}
+
+// registrations has synced when all registrations have synced.
+type registrations []cache.ResourceEventHandlerRegistration
+
+func (r registrations) HasSynced() bool {
+	for _, reg := range r {
+		if !reg.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleEventHandler handles both the Rules and the ClusterRules.
+func (w *Informer) ruleEventHandler() cache.ResourceEventHandler {
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: w.filterRule,
+		Handler: cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				if r, line, ok := ruleObject(obj); ok {
+					w.ruleMu.Lock()
+					defer w.ruleMu.Unlock()
+					w.onAdd(r, w.domainLine(r, line), isInInitialList)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				rNew, newLine, ok1 := ruleObject(newObj)
+				rOld, oldLine, ok2 := ruleObject(oldObj)
+				if ok1 && ok2 {
+					w.ruleMu.Lock()
+					defer w.ruleMu.Unlock()
+					w.onUpdate(rOld, w.domainLine(rOld, oldLine), rNew, w.domainLine(rNew, newLine))
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = d.Obj
+				}
+				if r, line, ok := ruleObject(obj); ok {
+					w.ruleMu.Lock()
+					defer w.ruleMu.Unlock()
+					w.onDelete(r, w.domainLine(r, line))
+				}
+			},
+		},
+	}
+}
+
+// ruleObject returns the Rule or ClusterRule with its policy line.
+func ruleObject(obj interface{}) (client.Object, CasbinRule, bool) {
+	switch r := obj.(type) {
+	case *v1alpha1.Rule:
+		return r, fromRule(r), true
+	case *v1alpha1.ClusterRule:
+		return r, fromClusterRule(r), true
+	default:
+		return nil, CasbinRule{}, false
+	}
+}
+
+func (w *Informer) onAdd(r client.Object, line CasbinRule, isInInitialList bool) {
+	level := 0 // info
+	if isInInitialList {
+		level = 1 // debug
+	}
+	zlog.Vf(level, "ADD(%t) %s/%s ptype=%s v0=%s", isInInitialList, r.GetNamespace(), r.GetName(), line.PType, line.V0)
+	if !w.schedule(r, line) {
+		zlog.Vf(level, "%s %s/%s is not active", strings.ToLower(objectKind(r)), r.GetNamespace(), r.GetName())
+		return
+	}
+	w.addRule(r, line, isInInitialList)
+}
+
+// addRule adds the line of an active rule to the enforcer.
+func (w *Informer) addRule(r client.Object, line CasbinRule, isInInitialList bool) {
+	if err := w.validate(line); err != nil {
+		w.reject(r, line, err)
+		return
+	}
+	added, err := w.addLine(r, line, w.orderOf(r))
+	if err != nil {
+		zlog.Errorf("add policy err: %s", err)
+		w.reject(r, line, err)
+		return
+	}
+	w.quarantine.remove(r, line)
+	if !added {
+		return
+	}
+	w.dirty.Store(true)
+	event := newPolicyEvent(PolicyAdded, r, line)
+	event.InitialList = isInInitialList
+	w.handlers.notify(event)
+}
+
+func (w *Informer) onUpdate(rOld client.Object, oldLine CasbinRule, rNew client.Object, newLine CasbinRule) {
+	zlog.Infof("UPDATE %s/%s ptype=%s v0=%s", rNew.GetNamespace(), rNew.GetName(), newLine.PType, newLine.V0)
+	wasActive := w.isActive(rOld)
+	active := w.schedule(rNew, newLine)
+	switch {
+	case !wasActive && !active:
+		return
+	case !wasActive:
+		w.addRule(rNew, newLine, false)
+		return
+	case !active:
+		w.removeRule(rOld, oldLine)
+		return
+	}
+	if w.quarantine.contains(rOld, oldLine) {
+		// the old rule was never applied
+		w.addRule(rNew, newLine, false)
+		return
+	}
+	if keyFor(oldLine) == keyFor(newLine) {
+		// e.g. the labels, the expiry or the priority changed
+		w.reorder(newLine, w.orderOf(rNew))
+		return
+	}
+	if err := w.validate(newLine); err != nil {
+		w.removeRule(rOld, oldLine)
+		w.reject(rNew, newLine, err)
+		return
+	}
+	if err := w.updateLine(rNew, oldLine, newLine, w.orderOf(rNew)); err != nil {
+		zlog.Errorf("update policy err: %s", err)
+		w.events.applyFailed(rNew, ActionUpdateRule, newLine, err)
+		return
+	}
+	w.dirty.Store(true)
+	event := newPolicyEvent(PolicyUpdated, rNew, newLine)
+	event.OldRule = oldLine
+	w.handlers.notify(event)
+}
+
+func (w *Informer) onDelete(r client.Object, line CasbinRule) {
+	zlog.Infof("DELETE %s/%s ptype=%s v0=%s", r.GetNamespace(), r.GetName(), line.PType, line.V0)
+	if !w.unschedule(r) {
+		// the inactive rule was never applied
+		return
+	}
+	w.removeRule(r, line)
+}
+
+// removeRule removes the line of an active rule from the enforcer.
+func (w *Informer) removeRule(r client.Object, line CasbinRule) {
+	if w.quarantine.remove(r, line) {
+		return
+	}
+	removed, err := w.removeLine(r, line)
+	if err != nil {
+		zlog.Errorf("remove policy err: %s", err)
+		w.events.applyFailed(r, ActionRemoveRule, line, err)
+		return
+	}
+	if !removed {
+		return
+	}
+	w.dirty.Store(true)
+	w.handlers.notify(newPolicyEvent(PolicyRemoved, r, line))
+}
+
+// lineRefs are the objects providing a policy line, keyed by refKey.
+type lineRefs struct {
+	line    CasbinRule
+	objects map[string]client.Object
+}
+
+// refKey identifies the object providing a line, the lines seeded from the snapshot have no object.
+func refKey(obj client.Object) string {
+	if obj == nil {
+		return ""
+	}
+	return ruleKey(obj)
+}
+
+// addLine adds the line of the object to the enforcer in the load order unless another object already provides it.
+func (w *Informer) addLine(obj client.Object, line CasbinRule, order lineOrder) (bool, error) {
+	w.refsMu.Lock()
+	defer w.refsMu.Unlock()
+	return w.addLineLocked(obj, line, order)
+}
+
+func (w *Informer) addLineLocked(obj client.Object, line CasbinRule, order lineOrder) (bool, error) {
+	key := keyFor(line)
+	if refs, ok := w.refs[key]; ok {
+		refs.objects[refKey(obj)] = obj
+		return false, nil
+	}
+	sec, ptype, rule := lineToPolicyParams(line)
+	if _, err := w.enforcer.SelfAddPolicy(sec, ptype, rule); err != nil {
+		return false, err
+	}
+	if w.refs == nil {
+		w.refs = make(map[string]*lineRefs)
+		w.orders = make(map[string]lineOrder)
+	}
+	w.refs[key] = &lineRefs{line: line, objects: map[string]client.Object{refKey(obj): obj}}
+	w.orders[orderKey(ptype, rule)] = order
+	w.placeLine(ptype, rule)
+	return true, nil
+}
+
+// reorder moves the line after the order of its object changed.
+func (w *Informer) reorder(line CasbinRule, order lineOrder) {
+	w.refsMu.Lock()
+	defer w.refsMu.Unlock()
+	w.reorderLine(line, order)
+}
+
+// removeLine removes the line of the object from the enforcer unless another object still provides it.
+func (w *Informer) removeLine(obj client.Object, line CasbinRule) (bool, error) {
+	w.refsMu.Lock()
+	defer w.refsMu.Unlock()
+	return w.removeLineLocked(obj, line)
+}
+
+func (w *Informer) removeLineLocked(obj client.Object, line CasbinRule) (bool, error) {
+	key := keyFor(line)
+	refs, ok := w.refs[key]
+	if !ok {
+		return false, nil
+	}
+	if _, ok = refs.objects[refKey(obj)]; !ok {
+		return false, nil
+	}
+	if len(refs.objects) > 1 {
+		delete(refs.objects, refKey(obj))
+		return false, nil
+	}
+	delete(w.refs, key)
+	sec, ptype, rule := lineToPolicyParams(line)
+	delete(w.orders, orderKey(ptype, rule))
+	last := w.lastLine(line)
+	if _, err := w.enforcer.SelfRemovePolicy(sec, ptype, rule); err != nil {
+		return false, err
+	}
+	if last != nil {
+		// casbin moved the last line to the position of the removed one
+		w.placeLine(ptype, last)
+	}
+	return true, nil
+}
+
+// updateLine replaces the line of the object in place, if other objects provide the old or the new line it is removed and added instead.
+func (w *Informer) updateLine(obj client.Object, oldLine, newLine CasbinRule, order lineOrder) error {
+	oldKey, newKey := keyFor(oldLine), keyFor(newLine)
+	if oldKey == newKey {
+		return nil
+	}
+	w.refsMu.Lock()
+	defer w.refsMu.Unlock()
+	oldRefs, ok := w.refs[oldKey]
+	if _, exists := w.refs[newKey]; !ok || exists || len(oldRefs.objects) > 1 {
+		if _, err := w.removeLineLocked(obj, oldLine); err != nil {
+			return err
+		}
+		_, err := w.addLineLocked(obj, newLine, order)
+		return err
+	}
+	sec, ptype, newRule := lineToPolicyParams(newLine)
+	_, _, oldRule := lineToPolicyParams(oldLine)
+	if _, err := w.enforcer.SelfUpdatePolicy(sec, ptype, oldRule, newRule); err != nil {
+		return err
+	}
+	delete(w.refs, oldKey)
+	w.refs[newKey] = &lineRefs{line: newLine, objects: map[string]client.Object{refKey(obj): obj}}
+	delete(w.orders, orderKey(ptype, oldRule))
+	w.orders[orderKey(ptype, newRule)] = order
+	w.placeLine(ptype, newRule)
+	return nil
+}
+
+func (w *Informer) validate(line CasbinRule) error {
+	if se, ok := w.enforcer.(*casbin.SyncedEnforcer); ok {
+		// the model can be replaced e.g. by ModelEnforcer
+		lock := se.GetLock()
+		lock.RLock()
+		defer lock.RUnlock()
+	}
+	return validatePolicyLine(w.enforcer.GetModel(), line)
+}
+
+// reject quarantines the line and reports it.
+func (w *Informer) reject(obj client.Object, line CasbinRule, reason error) {
+	zlog.Warnf("%s %s/%s quarantined: %v", strings.ToLower(objectKind(obj)), obj.GetNamespace(), obj.GetName(), reason)
+	w.quarantine.add(obj, line, reason)
+	w.events.invalidRule(obj, line, reason, w.quarantine.count(obj))
+}
+
+// QuarantinedRules returns the Rules and the RuleSet, Role and RBAC binding lines which were rejected by the enforcer.
+func (w *Informer) QuarantinedRules() []QuarantinedRule {
+	return w.quarantine.list()
+}
+
+// filterRule selects the Rules, RuleSets and Roles from the configured namespaces and the ClusterRules matching the configured labels.
+// The cache created by Start is already restricted, but a shared (manager) cache can contain other objects.
+func (w *Informer) filterRule(obj interface{}) bool {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = d.Obj
+	}
+	r, ok := obj.(client.Object)
+	if !ok {
+		return false
+	}
+	if _, ok = r.(*v1alpha1.ClusterRule); ok {
+		return matchesRule(r, "", w.kubeConfig.Labels)
+	}
+	return w.kubeConfig.inRuleNamespaces(r.GetNamespace()) && matchesRule(r, "", w.kubeConfig.Labels)
 }
 
 func toPolicyParams(obj *v1alpha1.Rule) (string, string, []string) {
@@ -153,7 +595,17 @@ func toPolicyParams(obj *v1alpha1.Rule) (string, string, []string) {
 
 func toPolicyRuleArray(obj *v1alpha1.Rule) []string {
 	spec := &obj.Spec
-	var p = []string{spec.V0, spec.V1, spec.V2, spec.V3, spec.V4, spec.V5}
+	return trimPolicyRuleArray([]string{spec.V0, spec.V1, spec.V2, spec.V3, spec.V4, spec.V5})
+}
+
+func lineToPolicyParams(line CasbinRule) (string, string, []string) {
+	if len(line.PType) == 0 {
+		return "", "", []string{}
+	}
+	return string(line.PType[0]), line.PType, trimPolicyRuleArray([]string{line.V0, line.V1, line.V2, line.V3, line.V4, line.V5})
+}
+
+func trimPolicyRuleArray(p []string) []string {
 	index := len(p) - 1
 	for index >= 0 && p[index] == "" {
 		index--
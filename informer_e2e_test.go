@@ -15,6 +15,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	ctrl "sigs.k8s.io/controller-runtime"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
 func TestE2E(t *testing.T) {
@@ -259,6 +260,60 @@ func TestE2ESameLabels(t *testing.T) { //nolint:funlen
 	}, 3*time.Second, 500*time.Millisecond, "reader2 enforce false")
 }
 
+func TestE2EManagedInformer(t *testing.T) {
+	ctrl.SetLogger(zlog.Logger)
+
+	adapter, err := NewAdapter(&AdapterConfig{})
+	require.NoError(t, err)
+
+	model := "examples/rbac_model.conf"
+
+	admin, err := casbin.NewSyncedEnforcer(model, adapter)
+	require.NoError(t, err)
+
+	reader, err := casbin.NewSyncedEnforcer(model, adapter)
+	require.NoError(t, err)
+
+	cfg, err := getRESTConfig(KubeConfig{})
+	require.NoError(t, err)
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: "0"},
+		HealthProbeBindAddress: "0",
+	})
+	require.NoError(t, err)
+
+	informer, err := NewManagedInformer(mgr, &ManagedInformerConfig{}, reader)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		assert.NoError(t, mgr.Start(ctx))
+	}()
+	require.Eventually(t, informer.HasSynced, 10*time.Second, 100*time.Millisecond, "informer synced")
+
+	sub := "sub-" + uuid.NewString()
+	obj := "obj-" + uuid.NewString()
+	act := "read"
+
+	added, err := admin.AddPolicy(sub, obj, act)
+	requireTrue(t, added, err)
+
+	assert.Eventually(t, func() bool {
+		ok, err := reader.Enforce(sub, obj, act)
+		return err == nil && ok
+	}, 3*time.Second, 500*time.Millisecond, "reader enforce true")
+
+	removed, err := admin.RemovePolicy(sub, obj, act)
+	requireTrue(t, removed, err)
+
+	assert.Eventually(t, func() bool {
+		ok, err := reader.Enforce(sub, obj, act)
+		return err == nil && !ok
+	}, 3*time.Second, 500*time.Millisecond, "reader enforce false")
+}
+
 func TestK8sInformer(t *testing.T) {
 	t.SkipNow()
 	ctrl.SetLogger(zlog.Logger)
@@ -0,0 +1,209 @@
+package casbinkube
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/grepplabs/loggo/zlog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultPolicyEventQueueSize is the number of policy events queued for the handlers.
+const DefaultPolicyEventQueueSize = 1024
+
+type PolicyEventType string
+
+const (
+	PolicyAdded   PolicyEventType = "Added"
+	PolicyUpdated PolicyEventType = "Updated"
+	PolicyRemoved PolicyEventType = "Removed"
+	// PolicyResync replaces the events which did not fit into the queue. The handlers must rebuild their state from the enforcer,
+	// e.g. re-check the roles of all sessions, the other fields of the event are empty.
+	PolicyResync PolicyEventType = "Resync"
+)
+
+// PolicyEvent describes a policy change which was applied to the enforcer.
+type PolicyEvent struct {
+	Type PolicyEventType
+	// Policy line after the change, or the removed line for PolicyRemoved.
+	Rule CasbinRule
+	// Policy line before the change, set for PolicyUpdated only.
+	OldRule CasbinRule
+	// Kind of the object providing the line, KindRule, KindRuleSet, KindRole, KindRoleBinding, KindClusterRoleBinding or KindClusterRule.
+	Kind string
+	// Name and namespace (empty for a ClusterRule) of the object.
+	Name      string
+	Namespace string
+	// Labels of the object.
+	Labels map[string]string
+	// InitialList is true for the events delivered while the informer is syncing.
+	InitialList bool
+}
+
+// PolicyEventHandler is notified after the enforcer was updated.
+// The events are queued and handlers are called sequentially in the order of the changes from a dispatcher goroutine,
+// the enforcer can already contain later changes. A slow handler delays the other handlers only. When the queue is full
+// the following events are coalesced into a single PolicyResync event, delivered after the queued ones.
+type PolicyEventHandler interface {
+	OnPolicyEvent(event PolicyEvent)
+}
+
+// PolicyEventHandlerFunc is an adapter to use an ordinary function as a PolicyEventHandler.
+type PolicyEventHandlerFunc func(event PolicyEvent)
+
+func (f PolicyEventHandlerFunc) OnPolicyEvent(event PolicyEvent) {
+	f(event)
+}
+
+// PolicyEventHandlerFuncs dispatches the events by type, nil functions are skipped.
+type PolicyEventHandlerFuncs struct {
+	AddFunc    func(event PolicyEvent)
+	UpdateFunc func(event PolicyEvent)
+	RemoveFunc func(event PolicyEvent)
+	ResyncFunc func(event PolicyEvent)
+}
+
+func (f PolicyEventHandlerFuncs) OnPolicyEvent(event PolicyEvent) {
+	var fn func(event PolicyEvent)
+	switch event.Type {
+	case PolicyAdded:
+		fn = f.AddFunc
+	case PolicyUpdated:
+		fn = f.UpdateFunc
+	case PolicyRemoved:
+		fn = f.RemoveFunc
+	case PolicyResync:
+		fn = f.ResyncFunc
+	}
+	if fn != nil {
+		fn(event)
+	}
+}
+
+// PolicyEventChannel returns a handler sending the events to the channel.
+// The send blocks the dispatcher, the channel must be drained or buffered.
+func PolicyEventChannel(ch chan<- PolicyEvent) PolicyEventHandler {
+	return PolicyEventHandlerFunc(func(event PolicyEvent) {
+		ch <- event
+	})
+}
+
+type policyEventHandlers struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]PolicyEventHandler
+	order    []int
+
+	// queue decouples the handlers from the locks held while the enforcer is updated.
+	queueSize int
+	queue     chan PolicyEvent
+	closed    bool
+	// overflow is set while the events are coalesced into a PolicyResync event.
+	overflow  bool
+	overflows atomic.Uint64
+}
+
+func (p *policyEventHandlers) subscribe(handler PolicyEventHandler) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.handlers == nil {
+		p.handlers = make(map[int]PolicyEventHandler)
+	}
+	id := p.nextID
+	p.nextID++
+	p.handlers[id] = handler
+	p.order = append(p.order, id)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			delete(p.handlers, id)
+			for i, v := range p.order {
+				if v == id {
+					p.order = append(p.order[:i], p.order[i+1:]...)
+					break
+				}
+			}
+		})
+	}
+}
+
+// notify queues the event without blocking. If the queue is full, the event and the following ones are coalesced into
+// a PolicyResync event, which is delivered once the queued events were dispatched.
+func (p *policyEventHandlers) notify(event PolicyEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || p.overflow {
+		return
+	}
+	if p.queue == nil {
+		size := p.queueSize
+		if size <= 0 {
+			size = DefaultPolicyEventQueueSize
+		}
+		p.queue = make(chan PolicyEvent, size)
+		go p.dispatch(p.queue)
+	}
+	select {
+	case p.queue <- event:
+	default:
+		p.overflow = true
+		p.overflows.Add(1)
+		zlog.Warnf("policy event queue of %d events is full, the handlers will be notified to resync", cap(p.queue))
+	}
+}
+
+// dispatch calls the handlers for the queued events until the queue is closed.
+func (p *policyEventHandlers) dispatch(queue <-chan PolicyEvent) {
+	for event := range queue {
+		p.deliver(event)
+		p.mu.Lock()
+		resync := p.overflow && len(queue) == 0
+		if resync {
+			p.overflow = false
+		}
+		p.mu.Unlock()
+		if resync {
+			p.deliver(PolicyEvent{Type: PolicyResync})
+		}
+	}
+}
+
+func (p *policyEventHandlers) deliver(event PolicyEvent) {
+	p.mu.RLock()
+	handlers := make([]PolicyEventHandler, 0, len(p.order))
+	for _, id := range p.order {
+		handlers = append(handlers, p.handlers[id])
+	}
+	p.mu.RUnlock()
+
+	for _, h := range handlers {
+		h.OnPolicyEvent(event)
+	}
+}
+
+// close stops the dispatcher after the queued events were delivered, later events are discarded.
+func (p *policyEventHandlers) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	if p.queue != nil {
+		close(p.queue)
+	}
+}
+
+func newPolicyEvent(eventType PolicyEventType, obj client.Object, line CasbinRule) PolicyEvent {
+	return PolicyEvent{
+		Type:      eventType,
+		Rule:      line,
+		Kind:      objectKind(obj),
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		Labels:    obj.GetLabels(),
+	}
+}
@@ -0,0 +1,120 @@
+package casbinkube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PolicyEventHandlers(t *testing.T) {
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+
+	ch := make(chan PolicyEvent, 10)
+	removed := make(chan PolicyEvent, 10)
+	w, err := NewInformer(&InformerConfig{
+		PolicyEventHandlers: []PolicyEventHandler{PolicyEventChannel(ch)},
+	}, e)
+	require.NoError(t, err)
+	unsubscribe := w.Subscribe(PolicyEventHandlerFuncs{
+		RemoveFunc: func(event PolicyEvent) {
+			// the enforcer is updated before the handler is called
+			has, err := e.HasPolicy(event.Rule.V0, event.Rule.V1, event.Rule.V2)
+			require.NoError(t, err)
+			require.False(t, has)
+			removed <- event
+		},
+	})
+
+	r := rule("p", "alice", "data1", "read")
+	r.Name = "rule-1"
+	r.Namespace = DefaultNamespace
+	r.Labels = map[string]string{"team": "a"}
+
+	h := w.ruleEventHandler()
+	h.OnAdd(r, true)
+	event := <-ch
+	require.Equal(t, PolicyAdded, event.Type)
+	require.Equal(t, CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"}, event.Rule)
+	require.Equal(t, "rule-1", event.Name)
+	require.Equal(t, DefaultNamespace, event.Namespace)
+	require.Equal(t, map[string]string{"team": "a"}, event.Labels)
+	require.True(t, event.InitialList)
+
+	rNew := rule("p", "alice", "data1", "write")
+	rNew.ObjectMeta = r.ObjectMeta
+	h.OnUpdate(r, rNew)
+	event = <-ch
+	require.Equal(t, PolicyUpdated, event.Type)
+	require.Equal(t, "write", event.Rule.V2)
+	require.Equal(t, "read", event.OldRule.V2)
+
+	h.OnDelete(rNew)
+	event = <-ch
+	require.Equal(t, PolicyRemoved, event.Type)
+	require.Equal(t, event, <-removed)
+
+	// other namespaces are filtered out
+	other := rule("p", "bob", "data1", "read")
+	other.Namespace = "other"
+	h.OnAdd(other, false)
+	require.Empty(t, ch)
+
+	unsubscribe()
+	unsubscribe()
+	h.OnAdd(r, false)
+	h.OnDelete(r)
+	require.Equal(t, PolicyAdded, (<-ch).Type)
+	require.Equal(t, PolicyRemoved, (<-ch).Type)
+	require.Empty(t, removed)
+
+	// the queued events are delivered, later ones are discarded
+	h.OnAdd(r, false)
+	w.Close()
+	require.Equal(t, PolicyAdded, (<-ch).Type)
+	h.OnDelete(r)
+	require.Never(t, func() bool { return len(ch) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func Test_PolicyEventQueueFull(t *testing.T) {
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	ch := make(chan PolicyEvent, 10)
+	w, err := NewInformer(&InformerConfig{
+		PolicyEventQueueSize: 1,
+		PolicyEventHandlers: []PolicyEventHandler{PolicyEventHandlerFunc(func(event PolicyEvent) {
+			<-release
+			ch <- event
+		})},
+	}, e)
+	require.NoError(t, err)
+	defer w.Close()
+
+	// the blocked handler does not block the informer
+	h := w.ruleEventHandler()
+	h.OnAdd(namedRule("alice", "p", "alice", "data1", "read"), false)
+	require.Eventually(t, func() bool { return len(w.handlers.queue) == 0 }, time.Second, 10*time.Millisecond)
+	h.OnAdd(namedRule("bob", "p", "bob", "data1", "read"), false)
+	h.OnAdd(namedRule("carol", "p", "carol", "data1", "read"), false)
+	h.OnDelete(namedRule("alice", "p", "alice", "data1", "read"))
+	require.Equal(t, uint64(1), w.PolicyEventOverflows())
+	ok, err := e.Enforce("carol", "data1", "read")
+	requireTrue(t, ok, err)
+
+	// the overflowed events are coalesced into a resync after the queued ones
+	close(release)
+	require.Equal(t, "alice", (<-ch).Name)
+	require.Equal(t, "bob", (<-ch).Name)
+	require.Equal(t, PolicyEvent{Type: PolicyResync}, <-ch)
+	require.Never(t, func() bool { return len(ch) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+// requireEvents waits until the handlers sent n events to the channel.
+func requireEvents(t *testing.T, ch chan PolicyEvent, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return len(ch) == n }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,156 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	rbacv1 "k8s.io/api/rbac/v1"
+	crcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const DefaultInformerReadyzCheckName = "casbin-informer"
+
+type ManagedInformerConfig struct {
+	// Informer configuration. KubeConfig.Context, KubeConfig.Path and SyncPeriod are ignored as the manager's client and cache are used.
+	InformerConfig
+	// NeedLeaderElection starts the informer only on the elected leader if true. By default, it runs on every replica.
+	NeedLeaderElection bool
+	// Name of the readiness check added to the manager, defaults to DefaultInformerReadyzCheckName.
+	ReadyzCheckName string
+}
+
+// ManagedInformer is an Informer registering the Rule event handler on the controller-runtime manager's cache.
+type ManagedInformer struct {
+	informer           *Informer
+	cache              crcache.Cache
+	needLeaderElection bool
+	synced             atomic.Bool
+}
+
+var _ manager.Runnable = (*ManagedInformer)(nil)
+var _ manager.LeaderElectionRunnable = (*ManagedInformer)(nil)
+
+// NewManagedInformer creates the informer and adds it to the manager together with a readiness check.
+// The manager's scheme must contain the casbin v1alpha1 types.
+func NewManagedInformer(mgr manager.Manager, config *ManagedInformerConfig, e casbin.IEnforcer) (*ManagedInformer, error) {
+	if mgr == nil {
+		return nil, errors.New("manager cannot be nil")
+	}
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if _, _, err := mgr.GetScheme().ObjectKinds(&v1alpha1.Rule{}); err != nil {
+		return nil, fmt.Errorf("manager scheme err: %w", err)
+	}
+	if config.KubeConfig.RuleSets {
+		if _, _, err := mgr.GetScheme().ObjectKinds(&v1alpha1.RuleSet{}); err != nil {
+			return nil, fmt.Errorf("manager scheme err: %w", err)
+		}
+	}
+	if config.KubeConfig.Roles {
+		if _, _, err := mgr.GetScheme().ObjectKinds(&v1alpha1.Role{}); err != nil {
+			return nil, fmt.Errorf("manager scheme err: %w", err)
+		}
+	}
+	if config.KubeConfig.RBAC.Enabled {
+		if _, _, err := mgr.GetScheme().ObjectKinds(&rbacv1.RoleBinding{}); err != nil {
+			return nil, fmt.Errorf("manager scheme err: %w", err)
+		}
+	}
+	if config.KubeConfig.ClusterRules {
+		if _, _, err := mgr.GetScheme().ObjectKinds(&v1alpha1.ClusterRule{}); err != nil {
+			return nil, fmt.Errorf("manager scheme err: %w", err)
+		}
+	}
+	informer, err := NewInformer(&config.InformerConfig, e)
+	if err != nil {
+		return nil, err
+	}
+	m := &ManagedInformer{
+		informer:           informer,
+		cache:              mgr.GetCache(),
+		needLeaderElection: config.NeedLeaderElection,
+	}
+	if err = mgr.Add(m); err != nil {
+		return nil, fmt.Errorf("add informer to manager err: %w", err)
+	}
+	checkName := config.ReadyzCheckName
+	if checkName == "" {
+		checkName = DefaultInformerReadyzCheckName
+	}
+	if err = mgr.AddReadyzCheck(checkName, m.ReadyzCheck); err != nil {
+		return nil, fmt.Errorf("add readyz check err: %w", err)
+	}
+	return m, nil
+}
+
+// Subscribe adds a handler notified after each policy change applied to the enforcer.
+// The returned function removes the handler.
+func (m *ManagedInformer) Subscribe(handler PolicyEventHandler) func() {
+	return m.informer.Subscribe(handler)
+}
+
+// PolicyEventOverflows returns how many times the queue of the policy event handlers was full and a PolicyResync event was sent.
+func (m *ManagedInformer) PolicyEventOverflows() uint64 {
+	return m.informer.PolicyEventOverflows()
+}
+
+// QuarantinedRules returns the Rules and the RuleSet, Role and RBAC binding lines which were rejected by the enforcer.
+func (m *ManagedInformer) QuarantinedRules() []QuarantinedRule {
+	return m.informer.QuarantinedRules()
+}
+
+// Start registers the event handler and blocks until the context is done.
+func (m *ManagedInformer) Start(ctx context.Context) error {
+	reg, err := m.informer.addEventHandler(ctx, m.cache)
+	if err != nil {
+		return err
+	}
+	zlog.Infof("wait for the managed informer to sync")
+	if err = m.informer.waitForSync(ctx, reg); err != nil {
+		return err
+	}
+	m.synced.Store(true)
+	zlog.Infof("managed informer started")
+
+	<-ctx.Done()
+
+	m.synced.Store(false)
+	m.informer.Close()
+	zlog.Infof("managed informer stopped")
+	return nil
+}
+
+func (m *ManagedInformer) NeedLeaderElection() bool {
+	return m.needLeaderElection
+}
+
+// HasSynced returns true if the initial list of Rules was applied to the enforcer.
+// It is false while the enforcer serves policies seeded from the snapshot.
+func (m *ManagedInformer) HasSynced() bool {
+	if _, stale := m.informer.StaleSince(); stale {
+		return false
+	}
+	return m.synced.Load()
+}
+
+// StaleSince returns the time of the snapshot and true while the enforcer serves policies seeded from the snapshot.
+func (m *ManagedInformer) StaleSince() (time.Time, bool) {
+	return m.informer.StaleSince()
+}
+
+// ReadyzCheck fails until the informer has synced.
+func (m *ManagedInformer) ReadyzCheck(_ *http.Request) error {
+	if !m.HasSynced() {
+		return errors.New("casbin informer not synced")
+	}
+	return nil
+}
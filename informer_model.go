@@ -0,0 +1,157 @@
+package casbinkube
+
+import (
+	"sort"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/loggo/zlog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// replaceModel applies the lines of the informer objects to the model and passes it to setModel, which must swap the model of the enforcer.
+// The lines rejected by the model are quarantined and the quarantined lines accepted by the model are applied.
+// The events and the rule transitions wait until the model is replaced, the policies are not read from the API server again.
+func (w *Informer) replaceModel(m model.Model, setModel func(model.Model) error) error {
+	w.ruleMu.Lock()
+	defer w.ruleMu.Unlock()
+	w.refsMu.Lock()
+	defer w.refsMu.Unlock()
+
+	refs := make(map[string]*lineRefs, len(w.refs))
+	orders := make(map[string]lineOrder, len(w.orders))
+	var rejected []*lineRefs
+	var rejectErrs []error
+	for _, r := range w.appliedLines() {
+		_, ptype, rule := lineToPolicyParams(r.line)
+		if err := loadPolicyLine(r.line, m); err != nil {
+			rejected = append(rejected, r)
+			rejectErrs = append(rejectErrs, err)
+			continue
+		}
+		refs[keyFor(r.line)] = r
+		if order, ok := w.orders[orderKey(ptype, rule)]; ok {
+			orders[orderKey(ptype, rule)] = order
+		}
+	}
+	var accepted []quarantinedLine
+	for _, q := range w.quarantinedLines() {
+		if validateRoleDomain(q.obj, w.kubeConfig.NamespaceAsDomain) != nil || validatePolicyLine(m, q.line) != nil {
+			continue
+		}
+		accepted = append(accepted, q)
+		key := keyFor(q.line)
+		if r, ok := refs[key]; ok {
+			r.objects[refKey(q.obj)] = q.obj
+			continue
+		}
+		if err := loadPolicyLine(q.line, m); err != nil {
+			return err
+		}
+		refs[key] = &lineRefs{line: q.line, objects: map[string]client.Object{refKey(q.obj): q.obj}}
+		_, ptype, rule := lineToPolicyParams(q.line)
+		orders[orderKey(ptype, rule)] = w.orderOf(q.obj)
+	}
+	if err := m.SortPoliciesBySubjectHierarchy(); err != nil {
+		return err
+	}
+	if err := m.SortPoliciesByPriority(); err != nil {
+		return err
+	}
+	if err := setModel(m); err != nil {
+		return err
+	}
+	w.refs, w.orders = refs, orders
+	w.dirty.Store(true)
+
+	for i, r := range rejected {
+		var first client.Object
+		for _, obj := range r.objects {
+			if obj == nil {
+				zlog.Warnf("stale snapshot line %q dropped: %v", lineString(r.line), rejectErrs[i])
+				continue
+			}
+			w.reject(obj, r.line, rejectErrs[i])
+			first = obj
+		}
+		if first != nil {
+			w.handlers.notify(newPolicyEvent(PolicyRemoved, first, r.line))
+		}
+	}
+	notified := make(map[string]struct{}, len(accepted))
+	for _, q := range accepted {
+		w.quarantine.remove(q.obj, q.line)
+		key := keyFor(q.line)
+		if _, ok := notified[key]; ok {
+			continue
+		}
+		notified[key] = struct{}{}
+		_, ptype, rule := lineToPolicyParams(q.line)
+		w.placeLine(ptype, rule)
+		w.handlers.notify(newPolicyEvent(PolicyAdded, q.obj, q.line))
+	}
+	return nil
+}
+
+// appliedLines returns the lines provided by the objects in the order of the enforcer. The caller must hold refsMu.
+func (w *Informer) appliedLines() []*lineRefs {
+	out := make([]*lineRefs, 0, len(w.refs))
+	seen := make(map[string]struct{}, len(w.refs))
+	for _, line := range w.enforcerLines() {
+		key := keyFor(line)
+		if r, ok := w.refs[key]; ok {
+			if _, ok = seen[key]; !ok {
+				seen[key] = struct{}{}
+				out = append(out, r)
+			}
+		}
+	}
+	// e.g. removed from the enforcer by the application
+	var missing []*lineRefs
+	for key, r := range w.refs {
+		if _, ok := seen[key]; !ok {
+			missing = append(missing, r)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return keyFor(missing[i].line) < keyFor(missing[j].line)
+	})
+	return append(out, missing...)
+}
+
+// enforcerLines returns the policy lines of the enforcer by section and ptype in the order of the model.
+func (w *Informer) enforcerLines() []CasbinRule {
+	if se, ok := w.enforcer.(*casbin.SyncedEnforcer); ok {
+		lock := se.GetLock()
+		lock.RLock()
+		defer lock.RUnlock()
+	}
+	m := w.enforcer.GetModel()
+	var lines []CasbinRule
+	for _, sec := range []string{"p", "g"} {
+		ptypes := make([]string, 0, len(m[sec]))
+		for ptype := range m[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+		for _, ptype := range ptypes {
+			for _, rule := range m[sec][ptype].Policy {
+				lines = append(lines, toCasbinRule(ptype, rule))
+			}
+		}
+	}
+	return lines
+}
+
+// quarantinedLines returns the quarantined lines of the objects in the load order.
+func (w *Informer) quarantinedLines() []quarantinedLine {
+	lines := w.quarantine.lines()
+	sort.SliceStable(lines, func(i, j int) bool {
+		oi, oj := w.orderOf(lines[i].obj), w.orderOf(lines[j].obj)
+		if oi != oj {
+			return oi.less(oj)
+		}
+		return keyFor(lines[i].line) < keyFor(lines[j].line)
+	})
+	return lines
+}
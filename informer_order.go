@@ -0,0 +1,96 @@
+package casbinkube
+
+import (
+	"strings"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/constant"
+	"github.com/casbin/casbin/v3/model"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The informer keeps the policy lines in the load order of the Adapter, casbin appends the added lines
+// and fills the gap of a removed line with the last one. Lines unknown to the informer are not moved.
+
+func (w *Informer) orderOf(obj client.Object) lineOrder {
+	return orderOf(obj, w.kubeConfig.ClusterRulePrecedence)
+}
+
+func orderKey(ptype string, rule []string) string {
+	return ptype + model.DefaultSep + strings.Join(rule, model.DefaultSep)
+}
+
+// reorderLine moves the line after the order of its object changed. The caller must hold refsMu.
+func (w *Informer) reorderLine(line CasbinRule, order lineOrder) {
+	_, ptype, rule := lineToPolicyParams(line)
+	key := orderKey(ptype, rule)
+	if current, ok := w.orders[key]; !ok || current == order {
+		return
+	}
+	w.orders[key] = order
+	w.placeLine(ptype, rule)
+}
+
+// lastLine returns the line which replaces the removed one in the enforcer, nil if the removed line is the last one.
+func (w *Informer) lastLine(line CasbinRule) []string {
+	sec, ptype, rule := lineToPolicyParams(line)
+	if sec != "p" {
+		return nil
+	}
+	if se, ok := w.enforcer.(*casbin.SyncedEnforcer); ok {
+		lock := se.GetLock()
+		lock.RLock()
+		defer lock.RUnlock()
+	}
+	ast, err := w.enforcer.GetModel().GetAssertion(sec, ptype)
+	if err != nil || len(ast.Policy) == 0 {
+		return nil
+	}
+	last := ast.Policy[len(ast.Policy)-1]
+	if index, ok := ast.PolicyMap[strings.Join(rule, model.DefaultSep)]; !ok || index == len(ast.Policy)-1 {
+		return nil
+	}
+	return last
+}
+
+// placeLine moves the policy line after the last line which does not follow it in the load order.
+// Models with a priority field are ordered by casbin. The caller must hold refsMu.
+func (w *Informer) placeLine(ptype string, rule []string) {
+	order, ok := w.orders[orderKey(ptype, rule)]
+	if !ok || !strings.HasPrefix(ptype, "p") {
+		return
+	}
+	if se, ok := w.enforcer.(*casbin.SyncedEnforcer); ok {
+		lock := se.GetLock()
+		lock.Lock()
+		defer lock.Unlock()
+	}
+	m := w.enforcer.GetModel()
+	if _, err := m.GetFieldIndex(ptype, constant.PriorityIndex); err == nil {
+		return
+	}
+	ast, err := m.GetAssertion("p", ptype)
+	if err != nil {
+		return
+	}
+	from, ok := ast.PolicyMap[strings.Join(rule, model.DefaultSep)]
+	if !ok {
+		return
+	}
+	moved := ast.Policy[from]
+	policy := append(ast.Policy[:from], ast.Policy[from+1:]...)
+	to := len(policy)
+	for ; to > 0; to-- {
+		prev, known := w.orders[orderKey(ptype, policy[to-1])]
+		if !known || !order.less(prev) {
+			break
+		}
+	}
+	policy = append(policy, nil)
+	copy(policy[to+1:], policy[to:])
+	policy[to] = moved
+	ast.Policy = policy
+	for i := min(from, to); i <= max(from, to); i++ {
+		ast.PolicyMap[strings.Join(policy[i], model.DefaultSep)] = i
+	}
+}
@@ -0,0 +1,78 @@
+package casbinkube
+
+import (
+	"strings"
+
+	"github.com/grepplabs/loggo/zlog"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// bindingEventHandler handles both the RoleBindings and the ClusterRoleBindings.
+func (w *Informer) bindingEventHandler() cache.ResourceEventHandler {
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: w.filterBinding,
+		Handler: cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				if b, lines, ok := w.bindingObject(obj); ok {
+					w.onBindingAdd(b, lines, isInInitialList)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				bNew, newLines, ok1 := w.bindingObject(newObj)
+				bOld, oldLines, ok2 := w.bindingObject(oldObj)
+				if ok1 && ok2 {
+					added, removed := w.updateObjectLines(bOld, oldLines, bNew, newLines)
+					zlog.Infof("UPDATE %s %s/%s added=%d removed=%d", strings.ToLower(objectKind(bNew)), bNew.GetNamespace(), bNew.GetName(), added, removed)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = d.Obj
+				}
+				if b, lines, ok := w.bindingObject(obj); ok {
+					zlog.Infof("DELETE %s %s/%s lines=%d", strings.ToLower(objectKind(b)), b.GetNamespace(), b.GetName(), len(lines))
+					for _, line := range lines {
+						w.removeObjectLine(b, line)
+					}
+				}
+			},
+		},
+	}
+}
+
+// bindingObject returns the RoleBinding or ClusterRoleBinding with its grouping lines.
+func (w *Informer) bindingObject(obj interface{}) (client.Object, []CasbinRule, bool) {
+	switch b := obj.(type) {
+	case *rbacv1.RoleBinding:
+		return b, roleBindingLines(b, w.kubeConfig.RBAC), true
+	case *rbacv1.ClusterRoleBinding:
+		return b, clusterRoleBindingLines(b, w.kubeConfig.RBAC), true
+	default:
+		return nil, nil, false
+	}
+}
+
+func (w *Informer) onBindingAdd(b client.Object, lines []CasbinRule, isInInitialList bool) {
+	level := 0 // info
+	if isInInitialList {
+		level = 1 // debug
+	}
+	zlog.Vf(level, "ADD(%t) %s %s/%s lines=%d", isInInitialList, strings.ToLower(objectKind(b)), b.GetNamespace(), b.GetName(), len(lines))
+	for _, line := range lines {
+		w.addObjectLine(b, line, isInInitialList)
+	}
+}
+
+// filterBinding selects the RoleBindings and ClusterRoleBindings matching the RBAC namespaces and labels.
+func (w *Informer) filterBinding(obj interface{}) bool {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = d.Obj
+	}
+	b, ok := obj.(client.Object)
+	if !ok {
+		return false
+	}
+	return w.kubeConfig.RBAC.matches(b)
+}
@@ -0,0 +1,63 @@
+package casbinkube
+
+import (
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	"k8s.io/client-go/tools/cache"
+)
+
+func (w *Informer) roleEventHandler() cache.ResourceEventHandler {
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: w.filterRule,
+		Handler: cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				if r, ok := obj.(*v1alpha1.Role); ok {
+					w.onRoleAdd(r, isInInitialList)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				rNew, ok1 := newObj.(*v1alpha1.Role)
+				rOld, ok2 := oldObj.(*v1alpha1.Role)
+				if ok1 && ok2 {
+					w.onRoleUpdate(rOld, rNew)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = d.Obj
+				}
+				if r, ok := obj.(*v1alpha1.Role); ok {
+					w.onRoleDelete(r)
+				}
+			},
+		},
+	}
+}
+
+func (w *Informer) onRoleAdd(r *v1alpha1.Role, isInInitialList bool) {
+	level := 0 // info
+	if isInInitialList {
+		level = 1 // debug
+	}
+	lines := w.domainLines(r, roleLines(r))
+	zlog.Vf(level, "ADD(%t) role %s/%s lines=%d", isInInitialList, r.Namespace, r.Name, len(lines))
+	for _, line := range lines {
+		w.addObjectLine(r, line, isInInitialList)
+	}
+}
+
+// onRoleUpdate adds the lines of the new members and removes the lines of the removed ones.
+// A change of the role name, domain or ptype replaces all lines.
+func (w *Informer) onRoleUpdate(rOld, rNew *v1alpha1.Role) {
+	oldLines, newLines := w.domainLines(rOld, roleLines(rOld)), w.domainLines(rNew, roleLines(rNew))
+	added, removed := w.updateObjectLines(rOld, oldLines, rNew, newLines)
+	zlog.Infof("UPDATE role %s/%s added=%d removed=%d", rNew.Namespace, rNew.Name, added, removed)
+}
+
+func (w *Informer) onRoleDelete(r *v1alpha1.Role) {
+	lines := w.domainLines(r, roleLines(r))
+	zlog.Infof("DELETE role %s/%s lines=%d", r.Namespace, r.Name, len(lines))
+	for _, line := range lines {
+		w.removeObjectLine(r, line)
+	}
+}
@@ -0,0 +1,143 @@
+package casbinkube
+
+import (
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (w *Informer) ruleSetEventHandler() cache.ResourceEventHandler {
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: w.filterRule,
+		Handler: cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				if rs, ok := obj.(*v1alpha1.RuleSet); ok {
+					w.onRuleSetAdd(rs, isInInitialList)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				rsNew, ok1 := newObj.(*v1alpha1.RuleSet)
+				rsOld, ok2 := oldObj.(*v1alpha1.RuleSet)
+				if ok1 && ok2 {
+					w.onRuleSetUpdate(rsOld, rsNew)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				// a missed delete of a RuleSet would leave all its lines in the enforcer
+				if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = d.Obj
+				}
+				if rs, ok := obj.(*v1alpha1.RuleSet); ok {
+					w.onRuleSetDelete(rs)
+				}
+			},
+		},
+	}
+}
+
+func (w *Informer) onRuleSetAdd(rs *v1alpha1.RuleSet, isInInitialList bool) {
+	level := 0 // info
+	if isInInitialList {
+		level = 1 // debug
+	}
+	lines := w.domainLines(rs, ruleSetLines(rs))
+	zlog.Vf(level, "ADD(%t) ruleset %s/%s lines=%d", isInInitialList, rs.Namespace, rs.Name, len(lines))
+	for _, line := range lines {
+		w.addObjectLine(rs, line, isInInitialList)
+	}
+}
+
+// onRuleSetUpdate applies the difference between the old and the new lines.
+func (w *Informer) onRuleSetUpdate(rsOld, rsNew *v1alpha1.RuleSet) {
+	oldLines, newLines := w.domainLines(rsOld, ruleSetLines(rsOld)), w.domainLines(rsNew, ruleSetLines(rsNew))
+	added, removed := w.updateObjectLines(rsOld, oldLines, rsNew, newLines)
+	zlog.Infof("UPDATE ruleset %s/%s added=%d removed=%d", rsNew.Namespace, rsNew.Name, added, removed)
+}
+
+func (w *Informer) onRuleSetDelete(rs *v1alpha1.RuleSet) {
+	lines := w.domainLines(rs, ruleSetLines(rs))
+	zlog.Infof("DELETE ruleset %s/%s lines=%d", rs.Namespace, rs.Name, len(lines))
+	for _, line := range lines {
+		w.removeObjectLine(rs, line)
+	}
+}
+
+// updateObjectLines applies the difference between the old and the new lines of a RuleSet, Role or RBAC binding.
+// The unchanged lines are retried if they are quarantined and moved if the order of the object changed.
+func (w *Informer) updateObjectLines(objOld client.Object, oldLines []CasbinRule, objNew client.Object, newLines []CasbinRule) (int, int) {
+	oldKeys := make(map[string]struct{}, len(oldLines))
+	for _, line := range oldLines {
+		oldKeys[keyFor(line)] = struct{}{}
+	}
+	newKeys := make(map[string]struct{}, len(newLines))
+	for _, line := range newLines {
+		newKeys[keyFor(line)] = struct{}{}
+	}
+	var added, removed int
+	for _, line := range oldLines {
+		if _, ok := newKeys[keyFor(line)]; !ok {
+			w.removeObjectLine(objOld, line)
+			removed++
+		}
+	}
+	reorder := w.orderOf(objOld) != w.orderOf(objNew)
+	for _, line := range newLines {
+		switch _, ok := oldKeys[keyFor(line)]; {
+		case !ok:
+			w.addObjectLine(objNew, line, false)
+			added++
+		case w.quarantine.contains(objOld, line):
+			// e.g. rejected by validateRoleDomain, the line is retried with the new object
+			w.addObjectLine(objNew, line, false)
+		case reorder:
+			// e.g. the priority changed
+			w.reorder(line, w.orderOf(objNew))
+		}
+	}
+	return added, removed
+}
+
+// addObjectLine adds a line of a RuleSet, Role or RBAC binding.
+func (w *Informer) addObjectLine(obj client.Object, line CasbinRule, isInInitialList bool) {
+	err := validateRoleDomain(obj, w.kubeConfig.NamespaceAsDomain)
+	if err == nil {
+		err = w.validate(line)
+	}
+	if err != nil {
+		w.reject(obj, line, err)
+		return
+	}
+	added, err := w.addLine(obj, line, w.orderOf(obj))
+	if err != nil {
+		zlog.Errorf("add policy err: %s", err)
+		w.reject(obj, line, err)
+		return
+	}
+	w.quarantine.remove(obj, line)
+	if !added {
+		return
+	}
+	w.dirty.Store(true)
+	event := newPolicyEvent(PolicyAdded, obj, line)
+	event.InitialList = isInInitialList
+	w.handlers.notify(event)
+}
+
+// removeObjectLine removes a line of a RuleSet, Role or RBAC binding.
+func (w *Informer) removeObjectLine(obj client.Object, line CasbinRule) {
+	if w.quarantine.remove(obj, line) {
+		return
+	}
+	removed, err := w.removeLine(obj, line)
+	if err != nil {
+		zlog.Errorf("remove policy err: %s", err)
+		w.events.applyFailed(obj, ActionRemoveRule, line, err)
+		return
+	}
+	if !removed {
+		return
+	}
+	w.dirty.Store(true)
+	w.handlers.notify(newPolicyEvent(PolicyRemoved, obj, line))
+}
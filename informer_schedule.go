@@ -0,0 +1,102 @@
+package casbinkube
+
+import (
+	"strings"
+
+	"github.com/grepplabs/loggo/zlog"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ruleTimer re-evaluates a rule when it becomes active or expires, without waiting for a watch event.
+type ruleTimer struct {
+	timer clock.Timer
+}
+
+func ruleKey(obj client.Object) string {
+	return objectKind(obj) + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// isActive reports whether the rule was active when it was scheduled last. The caller must hold ruleMu.
+func (w *Informer) isActive(r client.Object) bool {
+	_, ok := w.inactive[ruleKey(r)]
+	return !ok
+}
+
+// schedule records whether the rule is active now and starts a timer for its next transition.
+// It returns true if the rule is active. The caller must hold ruleMu.
+func (w *Informer) schedule(r client.Object, line CasbinRule) bool {
+	key := ruleKey(r)
+	w.stopTimer(key)
+
+	now := w.clock.Now()
+	active := ruleActive(r, now)
+	if active {
+		delete(w.inactive, key)
+	} else {
+		if w.inactive == nil {
+			w.inactive = make(map[string]struct{})
+		}
+		w.inactive[key] = struct{}{}
+	}
+	if next := nextTransition(r, now); !next.IsZero() {
+		t := &ruleTimer{}
+		// the callback waits for ruleMu, i.e. until the timer is registered
+		t.timer = w.clock.AfterFunc(next.Sub(now), func() {
+			w.onTransition(key, t, r, line)
+		})
+		if w.timers == nil {
+			w.timers = make(map[string]*ruleTimer)
+		}
+		w.timers[key] = t
+	}
+	return active
+}
+
+// unschedule forgets the rule and returns true if it was active. The caller must hold ruleMu.
+func (w *Informer) unschedule(r client.Object) bool {
+	key := ruleKey(r)
+	w.stopTimer(key)
+	if _, ok := w.inactive[key]; ok {
+		delete(w.inactive, key)
+		return false
+	}
+	return true
+}
+
+// onTransition adds the rule which became active or removes the expired one.
+func (w *Informer) onTransition(key string, t *ruleTimer, r client.Object, line CasbinRule) {
+	w.ruleMu.Lock()
+	defer w.ruleMu.Unlock()
+
+	if w.timers[key] != t {
+		// the rule was updated or deleted meanwhile
+		return
+	}
+	delete(w.timers, key)
+	wasActive := w.isActive(r)
+	active := w.schedule(r, line)
+	switch {
+	case active && !wasActive:
+		zlog.Infof("ACTIVATE %s %s/%s ptype=%s v0=%s", strings.ToLower(objectKind(r)), r.GetNamespace(), r.GetName(), line.PType, line.V0)
+		w.addRule(r, line, false)
+	case !active && wasActive:
+		zlog.Infof("EXPIRE %s %s/%s ptype=%s v0=%s", strings.ToLower(objectKind(r)), r.GetNamespace(), r.GetName(), line.PType, line.V0)
+		w.removeRule(r, line)
+	}
+}
+
+func (w *Informer) stopTimer(key string) {
+	if t, ok := w.timers[key]; ok {
+		t.timer.Stop()
+		delete(w.timers, key)
+	}
+}
+
+func (w *Informer) stopTimers() {
+	w.ruleMu.Lock()
+	defer w.ruleMu.Unlock()
+	for key := range w.timers {
+		w.stopTimer(key)
+	}
+}
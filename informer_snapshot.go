@@ -0,0 +1,136 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grepplabs/loggo/zlog"
+	"k8s.io/client-go/tools/cache"
+)
+
+// waitForSync waits for the initial list of Rules. If a snapshot is configured and the informer does not sync in time,
+// the enforcer is seeded from the snapshot and the informer takes over in the background once it is synced.
+func (w *Informer) waitForSync(ctx context.Context, reg cache.ResourceEventHandlerRegistration) error {
+	if !w.snapshot.enabled() {
+		if ok := cache.WaitForCacheSync(ctx.Done(), reg.HasSynced); !ok {
+			return errors.New("failed to wait for caches to sync")
+		}
+		return nil
+	}
+	syncCtx, cancel := context.WithTimeout(ctx, w.snapshot.syncTimeout())
+	defer cancel()
+	if cache.WaitForCacheSync(syncCtx.Done(), reg.HasSynced) {
+		w.afterSync(ctx)
+		return nil
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("failed to wait for caches to sync: %w", ctx.Err())
+	}
+	s, err := w.seedFromSnapshot()
+	if err != nil {
+		zlog.Warnf("informer not synced after %v, snapshot not available: %v", w.snapshot.syncTimeout(), err)
+		if ok := cache.WaitForCacheSync(ctx.Done(), reg.HasSynced); !ok {
+			return errors.New("failed to wait for caches to sync")
+		}
+		w.afterSync(ctx)
+		return nil
+	}
+	w.stale.set(s.Time)
+	zlog.Warnf("informer not synced after %v, enforcer seeded with %d rules from stale snapshot %s from %s",
+		w.snapshot.syncTimeout(), len(s.Rules), w.snapshot.Path, s.Time.Format(time.RFC3339))
+	go func() {
+		if cache.WaitForCacheSync(ctx.Done(), reg.HasSynced) {
+			w.afterSync(ctx)
+		}
+	}()
+	return nil
+}
+
+// seedFromSnapshot adds the snapshot lines as references of the snapshot, the objects of the initial list add their own references.
+func (w *Informer) seedFromSnapshot() (*snapshot, error) {
+	s, err := readSnapshot(w.snapshot.Path)
+	if err != nil {
+		return nil, err
+	}
+	w.refsMu.Lock()
+	defer w.refsMu.Unlock()
+	var errs []error
+	for _, line := range s.Rules {
+		if _, err := w.addLineLocked(nil, line, lineOrder{}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		w.seeded = append(w.seeded, line)
+	}
+	if len(errs) != 0 {
+		zlog.Warnf("seeding from snapshot %s skipped %d rules: %v", w.snapshot.Path, len(errs), errors.Join(errs...))
+	}
+	return s, nil
+}
+
+// afterSync replaces the stale snapshot policies, writes the snapshot and starts the periodic snapshot writer.
+func (w *Informer) afterSync(ctx context.Context) {
+	since, stale := w.stale.get()
+	if stale {
+		w.pruneStale()
+	}
+	w.dirty.Store(false) // the snapshot contains all changes applied so far
+	w.writeSnapshot()
+	if stale {
+		w.stale.clear()
+		zlog.Infof("informer synced, replaced stale snapshot policies from %s", since.Format(time.RFC3339))
+	}
+	go w.runSnapshotWriter(ctx)
+}
+
+// pruneStale releases the references of the snapshot, the lines not provided by any synced object are removed.
+func (w *Informer) pruneStale() {
+	w.refsMu.Lock()
+	defer w.refsMu.Unlock()
+	var removed int
+	for _, line := range w.seeded {
+		ok, err := w.removeLineLocked(nil, line)
+		if err != nil {
+			zlog.Errorf("prune stale policy %q err: %v", lineString(line), err)
+			continue
+		}
+		if ok {
+			removed++
+		}
+	}
+	w.seeded = nil
+	zlog.Infof("removed %d stale policies", removed)
+}
+
+func (w *Informer) runSnapshotWriter(ctx context.Context) {
+	ticker := time.NewTicker(w.snapshot.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if w.dirty.Swap(false) {
+				w.writeSnapshot()
+			}
+			return
+		case <-ticker.C:
+			if w.dirty.Swap(false) {
+				w.writeSnapshot()
+			}
+		}
+	}
+}
+
+func (w *Informer) writeSnapshot() {
+	lines, err := enforcerPolicyLines(w.enforcer)
+	if err != nil {
+		zlog.Errorf("read policies for snapshot err: %v", err)
+		return
+	}
+	if err = writeSnapshot(w.snapshot.Path, &snapshot{Time: w.clock.Now(), Rules: lines}); err != nil {
+		zlog.Errorf("write snapshot err: %v", err)
+		return
+	}
+	zlog.Debugf("written snapshot %s with %d rules", w.snapshot.Path, len(lines))
+}
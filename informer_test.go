@@ -2,10 +2,12 @@ package casbinkube
 
 import (
 	"testing"
+	"time"
 
 	"github.com/casbin/casbin/v3"
 	"github.com/grepplabs/casbin-kube/api/v1alpha1"
 	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/cache"
 )
 
 func Test_toPolicyRuleArray(t *testing.T) {
@@ -125,3 +127,43 @@ func rule(ptype string, vals ...string) *v1alpha1.Rule {
 	r.Spec.V3, r.Spec.V4, r.Spec.V5 = buf[3], buf[4], buf[5]
 	return r
 }
+
+func Test_filterRule(t *testing.T) {
+	w := &Informer{kubeConfig: KubeConfig{
+		Namespace: "ns1",
+		Labels:    map[string]string{"app": "a"},
+	}}
+	r := rule("p", "alice", "data1", "read")
+	r.Namespace = "ns1"
+	r.Labels = map[string]string{"app": "a", "other": "x"}
+	require.True(t, w.filterRule(r))
+	require.True(t, w.filterRule(cache.DeletedFinalStateUnknown{Key: "ns1/r", Obj: r}))
+
+	r.Labels = map[string]string{"app": "b"}
+	require.False(t, w.filterRule(r))
+
+	r.Labels = map[string]string{"app": "a"}
+	r.Namespace = "ns2"
+	require.False(t, w.filterRule(r))
+
+	require.False(t, w.filterRule("not a rule"))
+
+	// no labels selects every Rule in the namespace
+	w.kubeConfig.Labels = nil
+	r.Namespace = "ns1"
+	r.Labels = nil
+	require.True(t, w.filterRule(r))
+}
+
+func Test_ManagedInformerReadyzCheck(t *testing.T) {
+	m := &ManagedInformer{informer: &Informer{}, needLeaderElection: true}
+	require.Error(t, m.ReadyzCheck(nil))
+	require.True(t, m.NeedLeaderElection())
+
+	m.synced.Store(true)
+	require.NoError(t, m.ReadyzCheck(nil))
+
+	// not ready while serving the snapshot
+	m.informer.stale.set(time.Now())
+	require.Error(t, m.ReadyzCheck(nil))
+}
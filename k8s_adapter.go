@@ -6,18 +6,34 @@ import (
 	"encoding/hex"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type k8sAdapter struct {
-	k8sClient *k8sClient[*v1alpha1.Rule, *v1alpha1.RuleList]
+	k8sClient                *k8sClient[*v1alpha1.Rule, *v1alpha1.RuleList]
+	ruleSetClient            *k8sClient[*v1alpha1.RuleSet, *v1alpha1.RuleSetList]
+	roleClient               *k8sClient[*v1alpha1.Role, *v1alpha1.RoleList]
+	clusterRuleClient        *k8sClient[*v1alpha1.ClusterRule, *v1alpha1.ClusterRuleList]
+	roleBindingClient        *k8sClient[*rbacv1.RoleBinding, *rbacv1.RoleBindingList]
+	clusterRoleBindingClient *k8sClient[*rbacv1.ClusterRoleBinding, *rbacv1.ClusterRoleBindingList]
+	revisionClient           *k8sClient[*v1alpha1.PolicyRevision, *v1alpha1.PolicyRevisionList]
+	clock                    clock.PassiveClock
+	// namespaces of the Rules, RuleSets and Roles, see KubeConfig.ruleNamespaces.
+	namespaces []string
+	rbac       RBACConfig
 }
 
 func newK8sAdapter(config *AdapterConfig) (*k8sAdapter, error) {
 	kubeConfig := config.KubeConfig
+	if err := kubeConfig.ClusterRulePrecedence.validate(); err != nil {
+		return nil, err
+	}
 	c, err := newClient(kubeConfig)
 	if err != nil {
 		return nil, err
@@ -26,6 +42,7 @@ func newK8sAdapter(config *AdapterConfig) (*k8sAdapter, error) {
 	if namespace == "" {
 		namespace = DefaultNamespace
 	}
+	kubeConfig.Namespace = namespace
 	kc := &k8sClient[*v1alpha1.Rule, *v1alpha1.RuleList]{
 		New: func() *v1alpha1.Rule {
 			return &v1alpha1.Rule{}
@@ -37,8 +54,96 @@ func newK8sAdapter(config *AdapterConfig) (*k8sAdapter, error) {
 		Namespace: namespace,
 		Labels:    kubeConfig.Labels,
 	}
+	var rsc *k8sClient[*v1alpha1.RuleSet, *v1alpha1.RuleSetList]
+	if kubeConfig.RuleSets {
+		rsc = &k8sClient[*v1alpha1.RuleSet, *v1alpha1.RuleSetList]{
+			New: func() *v1alpha1.RuleSet {
+				return &v1alpha1.RuleSet{}
+			},
+			NewList: func() *v1alpha1.RuleSetList {
+				return &v1alpha1.RuleSetList{}
+			},
+			Client:    c,
+			Namespace: namespace,
+			Labels:    kubeConfig.Labels,
+		}
+	}
+	var rc *k8sClient[*v1alpha1.Role, *v1alpha1.RoleList]
+	if kubeConfig.Roles {
+		rc = &k8sClient[*v1alpha1.Role, *v1alpha1.RoleList]{
+			New: func() *v1alpha1.Role {
+				return &v1alpha1.Role{}
+			},
+			NewList: func() *v1alpha1.RoleList {
+				return &v1alpha1.RoleList{}
+			},
+			Client:    c,
+			Namespace: namespace,
+			Labels:    kubeConfig.Labels,
+		}
+	}
+	var crc *k8sClient[*v1alpha1.ClusterRule, *v1alpha1.ClusterRuleList]
+	if kubeConfig.ClusterRules {
+		crc = &k8sClient[*v1alpha1.ClusterRule, *v1alpha1.ClusterRuleList]{
+			New: func() *v1alpha1.ClusterRule {
+				return &v1alpha1.ClusterRule{}
+			},
+			NewList: func() *v1alpha1.ClusterRuleList {
+				return &v1alpha1.ClusterRuleList{}
+			},
+			Client: c,
+			Labels: kubeConfig.Labels, // cluster-scoped, no namespace
+		}
+	}
+	var rbc *k8sClient[*rbacv1.RoleBinding, *rbacv1.RoleBindingList]
+	var crbc *k8sClient[*rbacv1.ClusterRoleBinding, *rbacv1.ClusterRoleBindingList]
+	if kubeConfig.RBAC.Enabled {
+		rbc = &k8sClient[*rbacv1.RoleBinding, *rbacv1.RoleBindingList]{
+			New: func() *rbacv1.RoleBinding {
+				return &rbacv1.RoleBinding{}
+			},
+			NewList: func() *rbacv1.RoleBindingList {
+				return &rbacv1.RoleBindingList{}
+			},
+			Client: c,
+			Labels: kubeConfig.RBAC.Labels, // the namespaces are listed one by one
+		}
+		crbc = &k8sClient[*rbacv1.ClusterRoleBinding, *rbacv1.ClusterRoleBindingList]{
+			New: func() *rbacv1.ClusterRoleBinding {
+				return &rbacv1.ClusterRoleBinding{}
+			},
+			NewList: func() *rbacv1.ClusterRoleBindingList {
+				return &rbacv1.ClusterRoleBindingList{}
+			},
+			Client: c,
+			Labels: kubeConfig.RBAC.Labels, // cluster-scoped, no namespace
+		}
+	}
+	var prc *k8sClient[*v1alpha1.PolicyRevision, *v1alpha1.PolicyRevisionList]
+	if config.Revisions.Enabled {
+		prc = &k8sClient[*v1alpha1.PolicyRevision, *v1alpha1.PolicyRevisionList]{
+			New: func() *v1alpha1.PolicyRevision {
+				return &v1alpha1.PolicyRevision{}
+			},
+			NewList: func() *v1alpha1.PolicyRevisionList {
+				return &v1alpha1.PolicyRevisionList{}
+			},
+			Client:    c,
+			Namespace: namespace,
+			Labels:    mergeLabels(kubeConfig.Labels, map[string]string{v1alpha1.PolicyRevisionLabel: config.Revisions.history()}),
+		}
+	}
 	return &k8sAdapter{
-		k8sClient: kc,
+		k8sClient:                kc,
+		ruleSetClient:            rsc,
+		roleClient:               rc,
+		clusterRuleClient:        crc,
+		roleBindingClient:        rbc,
+		clusterRoleBindingClient: crbc,
+		revisionClient:           prc,
+		clock:                    clock.RealClock{},
+		namespaces:               kubeConfig.ruleNamespaces(),
+		rbac:                     kubeConfig.RBAC,
 	}, nil
 }
 
@@ -51,40 +156,181 @@ func keyFor(r CasbinRule) string {
 }
 
 func (s *k8sAdapter) GetAllPolicies(ctx context.Context) ([]CasbinRule, error) {
-	l, err := s.k8sClient.List(ctx)
+	rules, err := s.GetAllRules(ctx)
 	if err != nil {
 		return nil, err
 	}
-	type record struct {
-		line            CasbinRule
-		created         metav1.Time
-		resourceVersion string
+	lines := make([]CasbinRule, 0, len(rules))
+	for i := range rules {
+		lines = append(lines, fromRule(&rules[i]))
+	}
+	return lines, nil
+}
+
+// GetAllRules returns the active Rules in the order they are loaded into the model.
+func (s *k8sAdapter) GetAllRules(ctx context.Context) ([]v1alpha1.Rule, error) {
+	now := s.clock.Now()
+	var rules []v1alpha1.Rule
+	for _, ns := range s.namespaces {
+		l, err := s.k8sClient.List(ctx, client.InNamespace(ns))
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range l.Items {
+			if checkResultRuleValidState(&rule, now) {
+				rules = append(rules, rule)
+			}
+		}
 	}
-	recs := make([]record, 0, len(l.Items))
+	sort.SliceStable(rules, func(i, j int) bool {
+		return lessRule(&rules[i], &rules[j])
+	})
+	return rules, nil
+}
+
+// GetStoredRules returns the Rules written by the adapter in the configured namespace, including the inactive ones.
+// The emergency Rules are not part of the revisions, they are revoked at their expiry instead.
+func (s *k8sAdapter) GetStoredRules(ctx context.Context) ([]v1alpha1.Rule, error) {
+	l, err := s.k8sClient.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]v1alpha1.Rule, 0, len(l.Items))
 	for _, rule := range l.Items {
-		if checkResultRuleValidState(&rule) {
-			recs = append(recs, record{
-				line:            fromRule(&rule),
-				created:         rule.CreationTimestamp,
-				resourceVersion: rule.ResourceVersion,
-			})
+		if rule.GetDeletionTimestamp().IsZero() && !isBreakGlassRule(&rule) {
+			rules = append(rules, rule)
 		}
 	}
-	sort.Slice(recs, func(i, j int) bool {
-		if recs[i].created.Equal(&recs[j].created) {
-			return recs[i].resourceVersion < recs[j].resourceVersion
+	return rules, nil
+}
+
+// GetAllRevisions returns the PolicyRevisions of the history sorted by the revision number.
+func (s *k8sAdapter) GetAllRevisions(ctx context.Context) ([]v1alpha1.PolicyRevision, error) {
+	l, err := s.revisionClient.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(l.Items, func(i, j int) bool {
+		return l.Items[i].Spec.Revision < l.Items[j].Spec.Revision
+	})
+	return l.Items, nil
+}
+
+// GetAllRuleSets returns the RuleSets sorted by namespace and name, nil if RuleSets are not enabled.
+func (s *k8sAdapter) GetAllRuleSets(ctx context.Context) ([]v1alpha1.RuleSet, error) {
+	if s.ruleSetClient == nil {
+		return nil, nil
+	}
+	var ruleSets []v1alpha1.RuleSet
+	for _, ns := range s.namespaces {
+		l, err := s.ruleSetClient.List(ctx, client.InNamespace(ns))
+		if err != nil {
+			return nil, err
 		}
-		return recs[i].created.Before(&recs[j].created)
+		for _, rs := range l.Items {
+			if rs.GetDeletionTimestamp().IsZero() {
+				ruleSets = append(ruleSets, rs)
+			}
+		}
+	}
+	sort.Slice(ruleSets, func(i, j int) bool {
+		return lessName(&ruleSets[i], &ruleSets[j])
 	})
-	lines := make([]CasbinRule, 0, len(recs))
-	for _, r := range recs {
-		lines = append(lines, r.line)
+	return ruleSets, nil
+}
+
+// GetAllRoles returns the Roles sorted by namespace and name, nil if Roles are not enabled.
+func (s *k8sAdapter) GetAllRoles(ctx context.Context) ([]v1alpha1.Role, error) {
+	if s.roleClient == nil {
+		return nil, nil
 	}
-	return lines, nil
+	var roles []v1alpha1.Role
+	for _, ns := range s.namespaces {
+		l, err := s.roleClient.List(ctx, client.InNamespace(ns))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range l.Items {
+			if r.GetDeletionTimestamp().IsZero() {
+				roles = append(roles, r)
+			}
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return lessName(&roles[i], &roles[j])
+	})
+	return roles, nil
+}
+
+// GetAllRoleBindings returns the imported RoleBindings sorted by namespace and name, nil if the RBAC import is not enabled.
+func (s *k8sAdapter) GetAllRoleBindings(ctx context.Context) ([]rbacv1.RoleBinding, error) {
+	if s.roleBindingClient == nil {
+		return nil, nil
+	}
+	var bindings []rbacv1.RoleBinding
+	for _, ns := range s.rbac.namespaces() {
+		l, err := s.roleBindingClient.List(ctx, client.InNamespace(ns))
+		if err != nil {
+			return nil, err
+		}
+		for _, rb := range l.Items {
+			if rb.GetDeletionTimestamp().IsZero() {
+				bindings = append(bindings, rb)
+			}
+		}
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return lessName(&bindings[i], &bindings[j])
+	})
+	return bindings, nil
 }
 
-func (s *k8sAdapter) CreatePolicy(ctx context.Context, r CasbinRule) error {
-	rule := toRule(s.k8sClient.Namespace, r)
+// GetAllClusterRoleBindings returns the imported ClusterRoleBindings sorted by name, nil if the RBAC import is not enabled.
+func (s *k8sAdapter) GetAllClusterRoleBindings(ctx context.Context) ([]rbacv1.ClusterRoleBinding, error) {
+	if s.clusterRoleBindingClient == nil {
+		return nil, nil
+	}
+	l, err := s.clusterRoleBindingClient.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]rbacv1.ClusterRoleBinding, 0, len(l.Items))
+	for _, crb := range l.Items {
+		if crb.GetDeletionTimestamp().IsZero() {
+			bindings = append(bindings, crb)
+		}
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return lessName(&bindings[i], &bindings[j])
+	})
+	return bindings, nil
+}
+
+// GetAllClusterRules returns the active ClusterRules in the order they are loaded into the model, nil if ClusterRules are not enabled.
+func (s *k8sAdapter) GetAllClusterRules(ctx context.Context) ([]v1alpha1.ClusterRule, error) {
+	if s.clusterRuleClient == nil {
+		return nil, nil
+	}
+	l, err := s.clusterRuleClient.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	rules := make([]v1alpha1.ClusterRule, 0, len(l.Items))
+	for _, rule := range l.Items {
+		if rule.GetDeletionTimestamp().IsZero() && ruleActive(&rule, now) {
+			rules = append(rules, rule)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return lessRule(&rules[i], &rules[j])
+	})
+	return rules, nil
+}
+
+// CreatePolicy creates the Rule in the namespace, in the configured namespace if empty.
+func (s *k8sAdapter) CreatePolicy(ctx context.Context, namespace string, r CasbinRule) error {
+	rule := toRule(namespace, r)
 	err := s.k8sClient.Create(ctx, &rule)
 	if err != nil {
 		return client.IgnoreAlreadyExists(err)
@@ -92,8 +338,20 @@ func (s *k8sAdapter) CreatePolicy(ctx context.Context, r CasbinRule) error {
 	return nil
 }
 
-func (s *k8sAdapter) DeletePolicy(ctx context.Context, r CasbinRule) error {
-	rule, err := s.k8sClient.Get(ctx, keyFor(r))
+// CreateRule creates the Rule with the spec in the configured namespace, e.g. to restore a recorded Rule.
+func (s *k8sAdapter) CreateRule(ctx context.Context, spec v1alpha1.RuleSpec) error {
+	rule := toRule("", fromRuleSpec(&spec))
+	rule.Spec = spec
+	return client.IgnoreAlreadyExists(s.k8sClient.Create(ctx, &rule))
+}
+
+// DeletePolicy deletes the Rule from the namespace, from the configured namespace if empty.
+func (s *k8sAdapter) DeletePolicy(ctx context.Context, namespace string, r CasbinRule) error {
+	if namespace == "" {
+		namespace = s.k8sClient.Namespace
+	}
+	rule := s.k8sClient.New()
+	err := s.k8sClient.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: keyFor(r)}, rule)
 	if err != nil {
 		return client.IgnoreNotFound(err)
 	}
@@ -113,6 +371,54 @@ func (s *k8sAdapter) DeleteAllPolicies(ctx context.Context) error {
 }
 
 func (s *k8sAdapter) DeleteFilteredPolicies(ctx context.Context, pattern CasbinRule) error {
+	var opts []client.DeleteAllOfOption
+	if fields := patternFields(pattern); len(fields) > 0 {
+		opts = append(opts, client.MatchingFields(fields))
+	}
+	err := s.k8sClient.DeleteAllOf(ctx, &v1alpha1.Rule{}, opts...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CountPolicies returns the number of Rules deleted by DeleteFilteredPolicies with the pattern.
+func (s *k8sAdapter) CountPolicies(ctx context.Context, pattern CasbinRule) (int, error) {
+	var opts []client.ListOption
+	if fields := patternFields(pattern); len(fields) > 0 {
+		opts = append(opts, client.MatchingFields(fields))
+	}
+	l, err := s.k8sClient.List(ctx, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return len(l.Items), nil
+}
+
+// GetFilteredRules returns the Rules matching the pattern in the namespaces, metav1.NamespaceAll selects all namespaces.
+// The Rules being deleted are skipped.
+func (s *k8sAdapter) GetFilteredRules(ctx context.Context, namespaces []string, pattern CasbinRule) ([]v1alpha1.Rule, error) {
+	var rules []v1alpha1.Rule
+	for _, ns := range namespaces {
+		opts := []client.ListOption{client.InNamespace(ns)}
+		if fields := patternFields(pattern); len(fields) > 0 {
+			opts = append(opts, client.MatchingFields(fields))
+		}
+		l, err := s.k8sClient.List(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range l.Items {
+			if rule.DeletionTimestamp.IsZero() {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules, nil
+}
+
+// patternFields returns the field selector of the non-empty fields of the pattern.
+func patternFields(pattern CasbinRule) map[string]string {
 	fields := map[string]string{}
 	if pattern.PType != "" {
 		fields["spec.ptype"] = pattern.PType
@@ -135,30 +441,26 @@ func (s *k8sAdapter) DeleteFilteredPolicies(ctx context.Context, pattern CasbinR
 	if pattern.V5 != "" {
 		fields["spec.v5"] = pattern.V5
 	}
-	var opts []client.DeleteAllOfOption
-	if len(fields) > 0 {
-		opts = append(opts, client.MatchingFields(fields))
-	}
-	err := s.k8sClient.DeleteAllOf(ctx, &v1alpha1.Rule{}, opts...)
-	if err != nil {
-		return err
-	}
-	return nil
+	return fields
 }
 
-func checkResultRuleValidState(rule *v1alpha1.Rule) bool {
-	return rule.GetDeletionTimestamp().IsZero()
+func checkResultRuleValidState(rule *v1alpha1.Rule, now time.Time) bool {
+	return rule.GetDeletionTimestamp().IsZero() && ruleActive(rule, now)
 }
 
 func fromRule(rule *v1alpha1.Rule) CasbinRule {
+	return fromRuleSpec(&rule.Spec)
+}
+
+func fromRuleSpec(spec *v1alpha1.RuleSpec) CasbinRule {
 	return CasbinRule{
-		PType: rule.Spec.PType,
-		V0:    rule.Spec.V0,
-		V1:    rule.Spec.V1,
-		V2:    rule.Spec.V2,
-		V3:    rule.Spec.V3,
-		V4:    rule.Spec.V4,
-		V5:    rule.Spec.V5,
+		PType: spec.PType,
+		V0:    spec.V0,
+		V1:    spec.V1,
+		V2:    spec.V2,
+		V3:    spec.V3,
+		V4:    spec.V4,
+		V5:    spec.V5,
 	}
 }
 
@@ -4,7 +4,12 @@ import (
 	"context"
 
 	casbinv1alpha1 "github.com/grepplabs/casbin-kube/api/v1alpha1"
+	casbinv1beta1 "github.com/grepplabs/casbin-kube/api/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/rest"
@@ -18,6 +23,8 @@ var (
 
 func init() {
 	utilruntime.Must(casbinv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(casbinv1beta1.AddToScheme(scheme))
+	utilruntime.Must(rbacv1.AddToScheme(scheme))
 }
 
 const (
@@ -30,6 +37,42 @@ type KubeConfig struct {
 	Namespace string
 	Path      string
 	Labels    map[string]string
+	// RuleSets reads the policy lines of RuleSets in addition to Rules. RuleSets are never written by the adapter.
+	RuleSets bool
+	// Roles expands the member lists of Roles into grouping lines in addition to Rules. Roles are never written by the adapter.
+	Roles bool
+	// ClusterRules reads the cluster-scoped ClusterRules in addition to Rules. ClusterRules are never written by the adapter.
+	ClusterRules bool
+	// ClusterRulePrecedence defines the load order of the ClusterRules, defaults to ClusterRulesFirst.
+	ClusterRulePrecedence ClusterRulePrecedence
+	// NamespaceAsDomain uses the namespace of a Rule, RuleSet or Role as the domain of its policy lines, e.g. for rbac_with_domains models.
+	// The domain is injected when the lines are loaded and stripped when they are written. The Rules are read from DomainNamespaces instead of Namespace.
+	NamespaceAsDomain bool
+	// DomainNamespaces are the tenant namespaces read in the NamespaceAsDomain mode, all namespaces if empty.
+	DomainNamespaces []string
+	// RBAC imports the subjects of the Kubernetes RoleBindings and ClusterRoleBindings as grouping lines.
+	RBAC RBACConfig
+}
+
+// ruleNamespaces returns the namespaces of the Rules, RuleSets and Roles, metav1.NamespaceAll selects all namespaces.
+func (k KubeConfig) ruleNamespaces() []string {
+	if !k.NamespaceAsDomain {
+		return []string{k.Namespace}
+	}
+	if len(k.DomainNamespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return k.DomainNamespaces
+}
+
+// inRuleNamespaces reports whether the Rules, RuleSets and Roles of the namespace are read.
+func (k KubeConfig) inRuleNamespaces(namespace string) bool {
+	for _, ns := range k.ruleNamespaces() {
+		if ns == metav1.NamespaceAll || ns == namespace {
+			return true
+		}
+	}
+	return false
 }
 
 type k8sClient[T client.Object, L client.ObjectList] struct {
@@ -120,6 +163,21 @@ func newClient(kubeConfig KubeConfig) (client.Client, error) {
 	return c, nil
 }
 
+// newRESTMapper returns a static mapper for the casbin types and the imported RBAC bindings.
+func newRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{casbinv1alpha1.GroupVersion, rbacv1.SchemeGroupVersion})
+	mapper.Add(casbinv1alpha1.GroupVersion.WithKind("ClusterRule"), meta.RESTScopeRoot)
+	mapper.Add(casbinv1alpha1.GroupVersion.WithKind("Model"), meta.RESTScopeNamespace)
+	mapper.Add(casbinv1alpha1.GroupVersion.WithKind("PolicyChangeRequest"), meta.RESTScopeNamespace)
+	mapper.Add(casbinv1alpha1.GroupVersion.WithKind("PolicyRevision"), meta.RESTScopeNamespace)
+	mapper.Add(casbinv1alpha1.GroupVersion.WithKind("Role"), meta.RESTScopeNamespace)
+	mapper.Add(casbinv1alpha1.GroupVersion.WithKind("Rule"), meta.RESTScopeNamespace)
+	mapper.Add(casbinv1alpha1.GroupVersion.WithKind("RuleSet"), meta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRoleBinding"), meta.RESTScopeRoot)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("RoleBinding"), meta.RESTScopeNamespace)
+	return mapper
+}
+
 func getRESTConfig(kubeConfig KubeConfig) (*rest.Config, error) {
 	loading := &clientcmd.ClientConfigLoadingRules{}
 
@@ -0,0 +1,225 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	crcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type ModelEnforcerConfig struct {
+	// Informer configuration. The Model and its Rules are read from KubeConfig.Namespace.
+	InformerConfig
+	// ModelName is the name of the Model. Rules are linked to the Model by the v1alpha1.ModelLabel label.
+	ModelName string
+}
+
+// ModelEnforcer is a synced enforcer built from a Model resource.
+// The policies are kept in sync by an Informer and the model is reloaded when the Model changes.
+// Each enforcer reports the result of a reload as an Event of the Model, the Ready condition is set by the ModelStatusReconciler.
+type ModelEnforcer struct {
+	*casbin.SyncedEnforcer
+
+	adapter    *Adapter
+	informer   *Informer
+	kubeConfig KubeConfig
+	modelName  string
+	generation int64
+
+	skipDisableAuto bool
+
+	stop context.CancelFunc
+}
+
+// NewModelEnforcer reads the Model and loads the linked Rules. Call Start to keep the enforcer in sync.
+func NewModelEnforcer(ctx context.Context, config *ModelEnforcerConfig) (*ModelEnforcer, error) {
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.ModelName == "" {
+		return nil, errors.New("model name cannot be empty")
+	}
+	kubeConfig := config.KubeConfig
+	if kubeConfig.Namespace == "" {
+		kubeConfig.Namespace = DefaultNamespace
+	}
+	kubeConfig.Labels = mergeLabels(kubeConfig.Labels, map[string]string{v1alpha1.ModelLabel: config.ModelName})
+
+	adapter, err := NewAdapter(&AdapterConfig{
+		KubeConfig:     kubeConfig,
+		Snapshot:       config.Snapshot,
+		Tolerant:       true,
+		EventRecorder:  config.EventRecorder,
+		EventComponent: config.EventComponent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	obj, err := getModel(ctx, adapter.store.k8sClient.Client, kubeConfig.Namespace, config.ModelName)
+	if err != nil {
+		return nil, err
+	}
+	m, err := model.NewModelFromString(obj.Spec.Model)
+	if err != nil {
+		return nil, fmt.Errorf("parse model %s/%s: %w", obj.Namespace, obj.Name, err)
+	}
+	e, err := casbin.NewSyncedEnforcer(m, adapter)
+	if err != nil {
+		return nil, err
+	}
+	informerConfig := config.InformerConfig
+	informerConfig.KubeConfig = kubeConfig
+	informer, err := NewInformer(&informerConfig, e)
+	if err != nil {
+		return nil, err
+	}
+	return &ModelEnforcer{
+		SyncedEnforcer: e,
+		adapter:        adapter,
+		informer:       informer,
+		kubeConfig:     kubeConfig,
+		modelName:      config.ModelName,
+		generation:     obj.Generation,
+
+		skipDisableAuto: config.SkipDisableAuto,
+	}, nil
+}
+
+// Informer returns the informer keeping the policies in sync.
+func (m *ModelEnforcer) Informer() *Informer {
+	return m.informer
+}
+
+// Start starts the Rule informer and watches the Model for changes.
+func (m *ModelEnforcer) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	m.stop = cancel
+
+	if err := m.informer.Start(ctx); err != nil {
+		return err
+	}
+	cfg, err := getRESTConfig(m.kubeConfig)
+	if err != nil {
+		return fmt.Errorf("get rest config err: %w", err)
+	}
+	c, err := crcache.New(cfg, crcache.Options{
+		Scheme: scheme,
+		Mapper: newRESTMapper(),
+		DefaultNamespaces: map[string]crcache.Config{
+			m.kubeConfig.Namespace: {},
+		},
+		ByObject: map[client.Object]crcache.ByObject{
+			&v1alpha1.Model{}: {
+				Field: fields.OneTermEqualSelector("metadata.name", m.modelName),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create model cache err: %w", err)
+	}
+	inf, err := c.GetInformer(ctx, &v1alpha1.Model{})
+	if err != nil {
+		return fmt.Errorf("get model informer err: %w", err)
+	}
+	reg, err := inf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if o, ok := obj.(*v1alpha1.Model); ok {
+				m.onModel(o)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if o, ok := newObj.(*v1alpha1.Model); ok {
+				m.onModel(o)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if o, ok := obj.(*v1alpha1.Model); ok {
+				zlog.Warnf("model %s/%s was deleted, keeping the last model", o.Namespace, o.Name)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("adds a model event handler err: %w", err)
+	}
+	go func() {
+		if err := c.Start(ctx); err != nil {
+			zlog.Errorf("model informer start failed: %v", err)
+		}
+	}()
+	if ok := cache.WaitForCacheSync(ctx.Done(), reg.HasSynced); !ok {
+		return errors.New("failed to wait for model cache to sync")
+	}
+	return nil
+}
+
+func (m *ModelEnforcer) Close() {
+	if m.stop != nil {
+		m.stop()
+	}
+	m.informer.Close()
+}
+
+func (m *ModelEnforcer) onModel(obj *v1alpha1.Model) {
+	if obj.Generation == m.generation {
+		return
+	}
+	if err := m.reload(obj); err != nil {
+		zlog.Errorf("reload model %s/%s generation %d err: %v", obj.Namespace, obj.Name, obj.Generation, err)
+		m.informer.events.modelRejected(obj, m.generation, err)
+		return
+	}
+	m.generation = obj.Generation
+	zlog.Infof("reloaded model %s/%s generation %d", obj.Namespace, obj.Name, obj.Generation)
+	m.informer.events.modelLoaded(obj)
+}
+
+// reload replaces the model, the informer applies the lines of its objects to the new model.
+func (m *ModelEnforcer) reload(obj *v1alpha1.Model) error {
+	newModel, err := model.NewModelFromString(obj.Spec.Model)
+	if err != nil {
+		return fmt.Errorf("parse model: %w", err)
+	}
+	return m.informer.replaceModel(newModel, func(newModel model.Model) error {
+		lock := m.GetLock()
+		lock.Lock()
+		defer lock.Unlock()
+
+		oldModel := m.GetModel()
+		m.setModel(newModel)
+		if err := m.Enforcer.BuildRoleLinks(); err != nil {
+			m.setModel(oldModel)
+			if rerr := m.Enforcer.BuildRoleLinks(); rerr != nil {
+				err = errors.Join(err, rerr)
+			}
+			return fmt.Errorf("build role links: %w", err)
+		}
+		m.adapter.setModel(newModel)
+		return nil
+	})
+}
+
+// setModel sets the model and restores the auto flags reset by SetModel.
+func (m *ModelEnforcer) setModel(newModel model.Model) {
+	m.SetModel(newModel)
+	if !m.skipDisableAuto {
+		m.EnableAutoSave(false)
+		m.EnableAutoNotifyWatcher(false)
+	}
+}
+
+func getModel(ctx context.Context, c client.Client, namespace string, name string) (*v1alpha1.Model, error) {
+	obj := &v1alpha1.Model{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		return nil, fmt.Errorf("get model %s/%s: %w", namespace, name, err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,163 @@
+package casbinkube
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testRBACModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+const testACLModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`
+
+func newTestModelEnforcer(t *testing.T, objs ...client.Object) *ModelEnforcer {
+	t.Helper()
+
+	lbls := map[string]string{v1alpha1.ModelLabel: "test"}
+	adapter := newTestAdapter(t, KubeConfig{Labels: lbls}, objs...)
+	adapter.tolerant = true
+	m, err := model.NewModelFromString(testRBACModel)
+	require.NoError(t, err)
+	e, err := casbin.NewSyncedEnforcer(m, adapter)
+	require.NoError(t, err)
+	informer, err := NewInformer(&InformerConfig{KubeConfig: KubeConfig{Labels: lbls}, EventRecorder: events.NewFakeRecorder(10)}, e)
+	require.NoError(t, err)
+	h := informer.ruleEventHandler()
+	for _, obj := range objs {
+		if r, ok := obj.(*v1alpha1.Rule); ok {
+			h.OnAdd(r, true)
+		}
+	}
+	return &ModelEnforcer{
+		SyncedEnforcer: e,
+		adapter:        adapter,
+		informer:       informer,
+		modelName:      "test",
+		generation:     1,
+	}
+}
+
+func Test_ModelEnforcerReload(t *testing.T) {
+	alice := namedRule("alice", "p", "alice", "data1", "read")
+	bob := namedRule("bob", "g", "bob", "alice")
+	e := newTestModelEnforcer(t, testModel(1, testRBACModel), alice, bob)
+	ok, err := e.Enforce("bob", "data1", "read")
+	requireTrue(t, ok, err)
+	e.onModel(testModel(1, testRBACModel))
+	requireModelEvent(t, e, "")
+
+	// the grouping rule is rejected by the ACL model
+	e.onModel(testModel(2, testACLModel))
+	require.Equal(t, int64(2), e.generation)
+	requireModelEvent(t, e, "Normal ModelLoaded Model generation 2 loaded")
+	ok, err = e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("bob", "data1", "read")
+	requireFalse(t, ok, err)
+	quarantined := e.Informer().QuarantinedRules()
+	require.Len(t, quarantined, 1)
+	require.Equal(t, "bob", quarantined[0].Name)
+
+	// an invalid model is ignored
+	e.onModel(testModel(3, "[request_definition]"))
+	require.Equal(t, int64(2), e.generation)
+	requireModelEvent(t, e, "Warning ModelRejected Model generation 3 not loaded, keeping generation 2")
+	ok, err = e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+
+	// back to RBAC
+	e.onModel(testModel(4, testRBACModel))
+	require.Equal(t, int64(4), e.generation)
+	requireModelEvent(t, e, "Normal ModelLoaded Model generation 4 loaded")
+	ok, err = e.Enforce("bob", "data1", "read")
+	requireTrue(t, ok, err)
+	require.Empty(t, e.Informer().QuarantinedRules())
+
+	// the informer state follows the reloaded model
+	w := e.Informer()
+	w.ruleEventHandler().OnDelete(bob)
+	ok, err = e.Enforce("bob", "data1", "read")
+	requireFalse(t, ok, err)
+	w.refsMu.Lock()
+	require.Len(t, w.refs, 1)
+	require.Contains(t, w.refs, keyFor(fromRule(alice)))
+	w.refsMu.Unlock()
+}
+
+func Test_ModelEnforcerReloadKeepsSharedLines(t *testing.T) {
+	alice := namedRule("alice", "p", "alice", "data1", "read")
+	dup := namedRule("alice-dup", "p", "alice", "data1", "read")
+	e := newTestModelEnforcer(t, alice, dup)
+
+	e.onModel(testModel(2, testACLModel))
+	require.Equal(t, int64(2), e.generation)
+
+	// the line is removed with the last Rule providing it
+	h := e.Informer().ruleEventHandler()
+	h.OnDelete(alice)
+	ok, err := e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+	h.OnDelete(dup)
+	ok, err = e.Enforce("alice", "data1", "read")
+	requireFalse(t, ok, err)
+}
+
+// requireModelEvent requires an Event of the Model reload starting with the prefix, none if empty. The other Events are discarded.
+func requireModelEvent(t *testing.T, e *ModelEnforcer, prefix string) {
+	t.Helper()
+
+	recorder := e.informer.events.recorder.(*events.FakeRecorder)
+	var reloads []string
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.Contains(event, "Model generation") {
+			reloads = append(reloads, event)
+		}
+	}
+	if prefix == "" {
+		require.Empty(t, reloads)
+		return
+	}
+	require.Len(t, reloads, 1)
+	require.True(t, strings.HasPrefix(reloads[0], prefix), reloads[0])
+}
+
+func testModel(generation int64, text string) *v1alpha1.Model {
+	return &v1alpha1.Model{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: DefaultNamespace, Generation: generation, UID: "uid-model"},
+		Spec:       v1alpha1.ModelSpec{Model: text},
+	}
+}
@@ -0,0 +1,94 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const DefaultModelStatusControllerName = "casbin-model-status"
+
+type ModelStatusReconcilerConfig struct {
+	// Namespace of the reconciled Models, all namespaces if empty.
+	Namespace string
+}
+
+// ModelStatusReconciler sets the Ready condition of the Models parsed as casbin models.
+// The status has a single writer, the ModelEnforcers report their reloads as Events of the Model.
+type ModelStatusReconciler struct {
+	client    client.Client
+	namespace string
+}
+
+func NewModelStatusReconciler(c client.Client, config *ModelStatusReconcilerConfig) (*ModelStatusReconciler, error) {
+	if c == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	return &ModelStatusReconciler{
+		client:    c,
+		namespace: config.Namespace,
+	}, nil
+}
+
+func (r *ModelStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Model{}, builder.WithPredicates(
+			predicate.GenerationChangedPredicate{},
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return r.namespace == "" || obj.GetNamespace() == r.namespace
+			}),
+		)).
+		Named(DefaultModelStatusControllerName).
+		Complete(r)
+}
+
+func (r *ModelStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	obj := &v1alpha1.Model{}
+	if err := r.client.Get(ctx, req.NamespacedName, obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !obj.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	base := obj.DeepCopy()
+	changed := meta.SetStatusCondition(&obj.Status.Conditions, modelReadyCondition(obj))
+	if obj.Status.ObservedGeneration != obj.Generation {
+		obj.Status.ObservedGeneration = obj.Generation
+		changed = true
+	}
+	if !changed {
+		return ctrl.Result{}, nil
+	}
+	if err := client.IgnoreNotFound(r.client.Status().Patch(ctx, obj, client.MergeFrom(base))); err != nil {
+		return ctrl.Result{}, fmt.Errorf("patch model status err: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+func modelReadyCondition(obj *v1alpha1.Model) metav1.Condition {
+	cond := metav1.Condition{
+		Type:               v1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: obj.Generation,
+		Reason:             v1alpha1.ReasonAccepted,
+		Message:            "Model is valid",
+	}
+	if _, err := model.NewModelFromString(obj.Spec.Model); err != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = v1alpha1.ReasonInvalid
+		cond.Message = err.Error()
+	}
+	return cond
+}
@@ -0,0 +1,56 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_ModelStatusReconciler(t *testing.T) {
+	valid := testModel(2, testRBACModel)
+	invalid := testModel(1, "[request_definition]")
+	invalid.Name = "invalid"
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(valid, invalid).
+		WithStatusSubresource(&v1alpha1.Model{}).
+		Build()
+	r, err := NewModelStatusReconciler(c, &ModelStatusReconcilerConfig{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, name := range []string{"test", "invalid", "missing"} {
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Namespace: DefaultNamespace, Name: name}})
+		require.NoError(t, err)
+	}
+
+	got := &v1alpha1.Model{}
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(valid), got))
+	require.Equal(t, int64(2), got.Status.ObservedGeneration)
+	cond := meta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeReady)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionTrue, cond.Status)
+	require.Equal(t, v1alpha1.ReasonAccepted, cond.Reason)
+	require.Equal(t, int64(2), cond.ObservedGeneration)
+
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(invalid), got))
+	require.Equal(t, int64(1), got.Status.ObservedGeneration)
+	cond = meta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeReady)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, v1alpha1.ReasonInvalid, cond.Reason)
+
+	// no change, no update
+	rv := got.ResourceVersion
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(invalid)})
+	require.NoError(t, err)
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(invalid), got))
+	require.Equal(t, rv, got.ResourceVersion)
+}
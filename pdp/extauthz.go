@@ -0,0 +1,172 @@
+package pdp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/grepplabs/loggo/zlog"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// The attributes of a CheckRequest which can be mapped onto the request definition.
+const (
+	// ExtAuthzFieldPrincipal is the principal of the source peer, e.g. the SPIFFE ID of the client certificate.
+	ExtAuthzFieldPrincipal = "principal"
+	// ExtAuthzFieldPath is the HTTP path without the query string and the fragment.
+	ExtAuthzFieldPath = "path"
+	// ExtAuthzFieldMethod is the HTTP method.
+	ExtAuthzFieldMethod = "method"
+	// ExtAuthzFieldHost is the HTTP host.
+	ExtAuthzFieldHost = "host"
+	// ExtAuthzFieldHeaderPrefix followed by the header name maps a request header, e.g. "header:x-user".
+	// The header is set by the client unless Envoy removes it before the ext_authz filter, e.g. with the request_headers_to_remove of the route
+	// or by a filter overwriting it, so a header is a subject only behind such a sanitization. Prefer ExtAuthzFieldMetadataPrefix for the JWT claims.
+	ExtAuthzFieldHeaderPrefix = "header:"
+	// ExtAuthzFieldMetadataPrefix followed by the filter name, a colon and the dotted path of a value maps the dynamic metadata of a filter,
+	// e.g. "metadata:envoy.filters.http.jwt_authn:jwt_payload.sub" for the verified claims of a JWT filter with payload_in_metadata: jwt_payload.
+	// The ext_authz filter forwards the metadata of the namespaces listed in its metadata_context_namespaces.
+	ExtAuthzFieldMetadataPrefix = "metadata:"
+)
+
+var extAuthzFields = []string{ExtAuthzFieldPrincipal, ExtAuthzFieldPath, ExtAuthzFieldMethod, ExtAuthzFieldHost}
+
+// DefaultExtAuthzRequest matches the request definition "r = sub, obj, act".
+var DefaultExtAuthzRequest = []string{ExtAuthzFieldPrincipal, ExtAuthzFieldPath, ExtAuthzFieldMethod}
+
+type ExtAuthzConfig struct {
+	// Request lists the ExtAuthzField* of each value of the request definition in order, defaults to DefaultExtAuthzRequest.
+	Request []string
+	// FailOpen allows the requests while the enforcer is not in sync. By default, they are denied with 503.
+	FailOpen bool
+}
+
+func (c *ExtAuthzConfig) validate() error {
+	for _, f := range c.Request {
+		if strings.HasPrefix(f, ExtAuthzFieldHeaderPrefix) {
+			if f == ExtAuthzFieldHeaderPrefix {
+				return fmt.Errorf("ext_authz field %q has no header name", f)
+			}
+			continue
+		}
+		if strings.HasPrefix(f, ExtAuthzFieldMetadataPrefix) {
+			if filter, path, ok := strings.Cut(strings.TrimPrefix(f, ExtAuthzFieldMetadataPrefix), ":"); !ok || filter == "" || path == "" {
+				return fmt.Errorf("ext_authz field %q is not %s<filter>:<path>", f, ExtAuthzFieldMetadataPrefix)
+			}
+			continue
+		}
+		if !slices.Contains(extAuthzFields, f) {
+			return fmt.Errorf("unknown ext_authz field %q, expected one of %s, %s<name> or %s<filter>:<path>",
+				f, strings.Join(extAuthzFields, ", "), ExtAuthzFieldHeaderPrefix, ExtAuthzFieldMetadataPrefix)
+		}
+	}
+	return nil
+}
+
+// values maps the check request onto the values of the request definition.
+func (c *ExtAuthzConfig) values(req *authv3.CheckRequest) []string {
+	request := c.Request
+	if len(request) == 0 {
+		request = DefaultExtAuthzRequest
+	}
+	attrs := req.GetAttributes()
+	httpReq := attrs.GetRequest().GetHttp()
+	values := make([]string, len(request))
+	for i, f := range request {
+		switch f {
+		case ExtAuthzFieldPrincipal:
+			values[i] = attrs.GetSource().GetPrincipal()
+		case ExtAuthzFieldPath:
+			path, _, _ := strings.Cut(httpReq.GetPath(), "?")
+			path, _, _ = strings.Cut(path, "#")
+			values[i] = path
+		case ExtAuthzFieldMethod:
+			values[i] = httpReq.GetMethod()
+		case ExtAuthzFieldHost:
+			values[i] = httpReq.GetHost()
+		default:
+			if strings.HasPrefix(f, ExtAuthzFieldMetadataPrefix) {
+				filter, path, _ := strings.Cut(strings.TrimPrefix(f, ExtAuthzFieldMetadataPrefix), ":")
+				values[i] = metadataValue(attrs.GetMetadataContext().GetFilterMetadata()[filter], path)
+				continue
+			}
+			// Envoy sends the header names in lower case
+			values[i] = httpReq.GetHeaders()[strings.ToLower(strings.TrimPrefix(f, ExtAuthzFieldHeaderPrefix))]
+		}
+	}
+	return values
+}
+
+// metadataValue returns the string, number or bool at the dotted path of the filter metadata, empty if missing.
+func metadataValue(metadata *structpb.Struct, path string) string {
+	var value *structpb.Value
+	for _, key := range strings.Split(path, ".") {
+		if metadata == nil {
+			return ""
+		}
+		value = metadata.GetFields()[key]
+		metadata = value.GetStructValue()
+	}
+	switch v := value.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return v.StringValue
+	case *structpb.Value_NumberValue:
+		return strconv.FormatFloat(v.NumberValue, 'f', -1, 64)
+	case *structpb.Value_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	default:
+		return ""
+	}
+}
+
+// extAuthzService implements the Envoy ext_authz v3 gRPC service.
+type extAuthzService struct {
+	server *Server
+	config *ExtAuthzConfig
+}
+
+func (a *extAuthzService) Check(_ context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	if !a.server.Ready() {
+		if a.config.FailOpen {
+			return allowed(), nil
+		}
+		return denied(codes.Unavailable, typev3.StatusCode_ServiceUnavailable, ErrNotReady.Error()), nil
+	}
+	values := a.config.values(req)
+	ok, err := a.server.enforcer.Enforce(toInterfaces(values)...)
+	if err != nil {
+		zlog.Warnf("ext_authz check %v err: %v", values, err)
+		return denied(codes.Internal, typev3.StatusCode_InternalServerError, "authorization failed"), nil
+	}
+	if !ok {
+		return denied(codes.PermissionDenied, typev3.StatusCode_Forbidden, "denied by casbin policy"), nil
+	}
+	return allowed(), nil
+}
+
+func allowed() *authv3.CheckResponse {
+	return &authv3.CheckResponse{
+		Status:       &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: &authv3.OkHttpResponse{}},
+	}
+}
+
+func denied(code codes.Code, httpCode typev3.StatusCode, message string) *authv3.CheckResponse {
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(code), Message: message},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: &authv3.DeniedHttpResponse{
+			Status: &typev3.HttpStatus{Code: httpCode},
+			Headers: []*corev3.HeaderValueOption{
+				{Header: &corev3.HeaderValue{Key: "content-type", Value: "text/plain"}},
+			},
+			Body: message,
+		}},
+	}
+}
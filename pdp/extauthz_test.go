@@ -0,0 +1,179 @@
+package pdp
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const keyMatchModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && keyMatch(r.obj, p.obj) && r.act == p.act
+`
+
+func newExtAuthzClient(t *testing.T, ready *atomic.Bool, config *ExtAuthzConfig) authv3.AuthorizationClient {
+	t.Helper()
+	m, err := model.NewModelFromString(keyMatchModel)
+	require.NoError(t, err)
+	e, err := casbin.NewSyncedEnforcer(m)
+	require.NoError(t, err)
+	_, err = e.AddPolicies([][]string{
+		{"spiffe://cluster.local/ns/shop/sa/frontend", "/api/orders/*", "GET"},
+		{"alice", "/admin/*", "POST"},
+	})
+	require.NoError(t, err)
+	s, err := NewServer(&ServerConfig{Enforcer: e, Ready: ready.Load, ExtAuthz: config})
+	require.NoError(t, err)
+
+	gs, _ := s.NewGRPCServer()
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	t.Cleanup(gs.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, conn.Close()) })
+	return authv3.NewAuthorizationClient(conn)
+}
+
+func checkRequest(principal, method, path string, headers map[string]string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+		Source: &authv3.AttributeContext_Peer{Principal: principal},
+		Request: &authv3.AttributeContext_Request{Http: &authv3.AttributeContext_HttpRequest{
+			Method:  method,
+			Path:    path,
+			Host:    "shop.example.com",
+			Headers: headers,
+		}},
+	}}
+}
+
+func Test_ExtAuthz(t *testing.T) {
+	var ready atomic.Bool
+	client := newExtAuthzClient(t, &ready, &ExtAuthzConfig{})
+	ctx := context.Background()
+	frontend := "spiffe://cluster.local/ns/shop/sa/frontend"
+
+	resp, err := client.Check(ctx, checkRequest(frontend, "GET", "/api/orders/1", nil))
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.Unavailable), resp.GetStatus().GetCode(), "fail closed before sync")
+	require.Equal(t, typev3.StatusCode_ServiceUnavailable, resp.GetDeniedResponse().GetStatus().GetCode())
+
+	ready.Store(true)
+	resp, err = client.Check(ctx, checkRequest(frontend, "GET", "/api/orders/1?expand=items", nil))
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.OK), resp.GetStatus().GetCode())
+	require.NotNil(t, resp.GetOkResponse())
+
+	resp, err = client.Check(ctx, checkRequest(frontend, "DELETE", "/api/orders/1", nil))
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.PermissionDenied), resp.GetStatus().GetCode())
+	require.Equal(t, typev3.StatusCode_Forbidden, resp.GetDeniedResponse().GetStatus().GetCode())
+
+	resp, err = client.Check(ctx, checkRequest("", "GET", "/api/orders/1", nil))
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.PermissionDenied), resp.GetStatus().GetCode())
+}
+
+func Test_ExtAuthzFailOpen(t *testing.T) {
+	var ready atomic.Bool
+	client := newExtAuthzClient(t, &ready, &ExtAuthzConfig{FailOpen: true})
+
+	resp, err := client.Check(context.Background(), checkRequest("", "DELETE", "/api/orders/1", nil))
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.OK), resp.GetStatus().GetCode(), "fail open before sync")
+
+	ready.Store(true)
+	resp, err = client.Check(context.Background(), checkRequest("", "DELETE", "/api/orders/1", nil))
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.PermissionDenied), resp.GetStatus().GetCode())
+}
+
+func Test_ExtAuthzHeaderMapping(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	client := newExtAuthzClient(t, &ready, &ExtAuthzConfig{
+		Request: []string{"header:X-User", ExtAuthzFieldPath, ExtAuthzFieldMethod},
+	})
+	resp, err := client.Check(context.Background(), checkRequest("", "POST", "/admin/users", map[string]string{"x-user": "alice"}))
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.OK), resp.GetStatus().GetCode())
+
+	resp, err = client.Check(context.Background(), checkRequest("", "POST", "/admin/users", map[string]string{"x-user": "bob"}))
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.PermissionDenied), resp.GetStatus().GetCode())
+}
+
+func Test_ExtAuthzMetadataMapping(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	client := newExtAuthzClient(t, &ready, &ExtAuthzConfig{
+		Request: []string{"metadata:envoy.filters.http.jwt_authn:jwt_payload.sub", ExtAuthzFieldPath, ExtAuthzFieldMethod},
+	})
+	claims, err := structpb.NewStruct(map[string]any{"jwt_payload": map[string]any{"sub": "alice"}})
+	require.NoError(t, err)
+	req := checkRequest("", "POST", "/admin/users", map[string]string{"x-user": "alice"})
+	req.Attributes.MetadataContext = &corev3.Metadata{FilterMetadata: map[string]*structpb.Struct{"envoy.filters.http.jwt_authn": claims}}
+	resp, err := client.Check(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.OK), resp.GetStatus().GetCode())
+
+	// the header of the client is not the subject
+	resp, err = client.Check(context.Background(), checkRequest("", "POST", "/admin/users", map[string]string{"x-user": "alice"}))
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.PermissionDenied), resp.GetStatus().GetCode())
+}
+
+func Test_metadataValue(t *testing.T) {
+	metadata, err := structpb.NewStruct(map[string]any{
+		"jwt_payload": map[string]any{"sub": "alice", "exp": float64(1700000000), "admin": true, "groups": []any{"a"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "alice", metadataValue(metadata, "jwt_payload.sub"))
+	require.Equal(t, "1700000000", metadataValue(metadata, "jwt_payload.exp"))
+	require.Equal(t, "true", metadataValue(metadata, "jwt_payload.admin"))
+	require.Empty(t, metadataValue(metadata, "jwt_payload.groups"))
+	require.Empty(t, metadataValue(metadata, "jwt_payload"))
+	require.Empty(t, metadataValue(metadata, "jwt_payload.sub.name"))
+	require.Empty(t, metadataValue(metadata, "missing.sub"))
+	require.Empty(t, metadataValue(nil, "jwt_payload.sub"))
+}
+
+func Test_ExtAuthzConfig(t *testing.T) {
+	c := &ExtAuthzConfig{Request: []string{ExtAuthzFieldHost, ExtAuthzFieldPrincipal, ExtAuthzFieldPath, ExtAuthzFieldMethod, "header:x-tenant"}}
+	require.NoError(t, c.validate())
+	req := checkRequest("frontend", "GET", "/api/orders#top", map[string]string{"x-tenant": "shop"})
+	require.Equal(t, []string{"shop.example.com", "frontend", "/api/orders", "GET", "shop"}, c.values(req))
+	require.Equal(t, []string{"", "", ""}, (&ExtAuthzConfig{}).values(&authv3.CheckRequest{}))
+
+	require.ErrorContains(t, (&ExtAuthzConfig{Request: []string{"header:"}}).validate(), "has no header name")
+	require.NoError(t, (&ExtAuthzConfig{Request: []string{"metadata:envoy.filters.http.jwt_authn:jwt_payload.sub"}}).validate())
+	require.ErrorContains(t, (&ExtAuthzConfig{Request: []string{"metadata:jwt_payload.sub"}}).validate(), "is not metadata:<filter>:<path>")
+	require.ErrorContains(t, (&ExtAuthzConfig{Request: []string{"user"}}).validate(), `unknown ext_authz field "user"`)
+}
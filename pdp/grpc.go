@@ -0,0 +1,111 @@
+package pdp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	pdpv1 "github.com/grepplabs/casbin-kube/pdp/v1"
+	"github.com/grepplabs/loggo/zlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// grpcService adapts the Server to the generated gRPC service.
+type grpcService struct {
+	pdpv1.UnimplementedPolicyDecisionServiceServer
+	server *Server
+}
+
+func (g *grpcService) Enforce(_ context.Context, req *pdpv1.EnforceRequest) (*pdpv1.EnforceResponse, error) {
+	allowed, err := g.server.enforce(req.GetValues())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &pdpv1.EnforceResponse{Allowed: allowed}, nil
+}
+
+func (g *grpcService) BatchEnforce(_ context.Context, req *pdpv1.BatchEnforceRequest) (*pdpv1.BatchEnforceResponse, error) {
+	requests := make([][]string, 0, len(req.GetRequests()))
+	for _, r := range req.GetRequests() {
+		requests = append(requests, r.GetValues())
+	}
+	allowed, err := g.server.batchEnforce(requests)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &pdpv1.BatchEnforceResponse{Allowed: allowed}, nil
+}
+
+func (g *grpcService) Explain(_ context.Context, req *pdpv1.EnforceRequest) (*pdpv1.ExplainResponse, error) {
+	allowed, explain, err := g.server.explain(req.GetValues())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &pdpv1.ExplainResponse{Allowed: allowed, Explain: explain}, nil
+}
+
+func grpcError(err error) error {
+	var reqErr requestError
+	switch {
+	case errors.Is(err, ErrNotReady):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.As(err, &reqErr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// NewGRPCServer returns a gRPC server with the policy decision service, the health service and the request logging.
+// With ServerConfig.ExtAuthz, the Envoy ext_authz Authorization service is registered as well.
+// The health status is SERVING once the enforcer is in sync, it is updated by WatchHealth.
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) (*grpc.Server, *health.Server) {
+	opts = append([]grpc.ServerOption{grpc.ChainUnaryInterceptor(logUnary)}, opts...)
+	gs := grpc.NewServer(opts...)
+	pdpv1.RegisterPolicyDecisionServiceServer(gs, &grpcService{server: s})
+	if s.extAuthz != nil {
+		authv3.RegisterAuthorizationServer(gs, &extAuthzService{server: s, config: s.extAuthz})
+	}
+	hs := health.NewServer()
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	hs.SetServingStatus(pdpv1.PolicyDecisionService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(gs, hs)
+	return gs, hs
+}
+
+// WatchHealth sets the health status from Ready every interval until the context is done.
+func (s *Server) WatchHealth(ctx context.Context, hs *health.Server, interval time.Duration) {
+	update := func() {
+		st := healthpb.HealthCheckResponse_NOT_SERVING
+		if s.Ready() {
+			st = healthpb.HealthCheckResponse_SERVING
+		}
+		hs.SetServingStatus("", st)
+		hs.SetServingStatus(pdpv1.PolicyDecisionService_ServiceDesc.ServiceName, st)
+	}
+	update()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			hs.Shutdown()
+			return
+		case <-ticker.C:
+			update()
+		}
+	}
+}
+
+// logUnary logs each unary call with its code and duration at the requestLogLevel.
+func logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	zlog.Logger.V(requestLogLevel).Info("grpc request", "method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start))
+	return resp, err
+}
@@ -0,0 +1,155 @@
+package pdp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/grepplabs/loggo/zlog"
+)
+
+// maxBodyBytes limits the size of a request body.
+const maxBodyBytes = 1 << 20
+
+type EnforceRequest struct {
+	// Request values in the order of the request definition, e.g. ["alice", "data1", "read"].
+	Request []string `json:"request"`
+}
+
+type EnforceResponse struct {
+	Allowed bool `json:"allowed"`
+}
+
+type BatchEnforceRequest struct {
+	Requests [][]string `json:"requests"`
+}
+
+type BatchEnforceResponse struct {
+	Allowed []bool `json:"allowed"`
+}
+
+type ExplainResponse struct {
+	Allowed bool `json:"allowed"`
+	// Policy rule which decided the request, empty if no rule matched.
+	Explain []string `json:"explain"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler returns the HTTP/JSON API:
+//
+//	POST /v1/enforce        EnforceRequest -> EnforceResponse
+//	POST /v1/enforce/batch  BatchEnforceRequest -> BatchEnforceResponse
+//	POST /v1/explain        EnforceRequest -> ExplainResponse
+//	GET  /readyz            200 once the enforcer is in sync, 503 before
+//
+// With ServerConfig.SubjectAccessReview, the Kubernetes authorization webhook is served as well:
+//
+//	POST /v1/subjectaccessreview  authorization.k8s.io/v1 SubjectAccessReview
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/enforce", func(w http.ResponseWriter, r *http.Request) {
+		var req EnforceRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		allowed, err := s.enforce(req.Request)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, EnforceResponse{Allowed: allowed})
+	})
+	mux.HandleFunc("POST /v1/enforce/batch", func(w http.ResponseWriter, r *http.Request) {
+		var req BatchEnforceRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		allowed, err := s.batchEnforce(req.Requests)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, BatchEnforceResponse{Allowed: allowed})
+	})
+	mux.HandleFunc("POST /v1/explain", func(w http.ResponseWriter, r *http.Request) {
+		var req EnforceRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		allowed, explain, err := s.explain(req.Request)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if explain == nil {
+			explain = []string{}
+		}
+		writeJSON(w, http.StatusOK, ExplainResponse{Allowed: allowed, Explain: explain})
+	})
+	if s.sar != nil {
+		mux.HandleFunc("POST /v1/subjectaccessreview", s.handleSubjectAccessReview)
+	}
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !s.Ready() {
+			writeError(w, ErrNotReady)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return logRequests(mux)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request body: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var reqErr requestError
+	switch {
+	case errors.Is(err, ErrNotReady):
+		status = http.StatusServiceUnavailable
+	case errors.As(err, &reqErr):
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		zlog.Debugf("write response err: %v", err)
+	}
+}
+
+// statusRecorder captures the status code for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs each request with its status and duration at the requestLogLevel.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		zlog.Logger.V(requestLogLevel).Info("http request", "method", r.Method, "path", r.URL.Path, "status", rec.status, "duration", time.Since(start))
+	})
+}
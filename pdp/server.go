@@ -0,0 +1,152 @@
+// Package pdp serves the decisions of a casbin enforcer over HTTP/JSON and gRPC.
+package pdp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/casbin/casbin/v3"
+)
+
+// ErrNotReady is returned while the enforcer is not in sync with the cluster.
+var ErrNotReady = errors.New("policy decision point is not ready")
+
+type ServerConfig struct {
+	// Enforcer deciding the requests, e.g. a SyncedEnforcer kept in sync by an Informer.
+	Enforcer casbin.IEnforcer
+	// Ready reports whether the enforcer is in sync, e.g. ManagedInformer.HasSynced. The requests are rejected until it is true.
+	// The server is always ready if nil.
+	Ready func() bool
+	// MaxBatchSize limits the number of requests of a batch, defaults to DefaultMaxBatchSize.
+	MaxBatchSize int
+	// SubjectAccessReview serves the Kubernetes authorization webhook on POST /v1/subjectaccessreview if set.
+	SubjectAccessReview *SubjectAccessReviewConfig
+	// ExtAuthz serves the Envoy ext_authz v3 Authorization service on the gRPC server if set.
+	ExtAuthz *ExtAuthzConfig
+}
+
+const DefaultMaxBatchSize = 1000
+
+// Server is a policy decision point. The decisions are the same over HTTP and gRPC.
+type Server struct {
+	enforcer     casbin.IEnforcer
+	ready        func() bool
+	maxBatchSize int
+	sar          *SubjectAccessReviewConfig
+	extAuthz     *ExtAuthzConfig
+}
+
+func NewServer(config *ServerConfig) (*Server, error) {
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.Enforcer == nil {
+		return nil, errors.New("enforcer cannot be nil")
+	}
+	maxBatchSize := config.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = DefaultMaxBatchSize
+	}
+	if config.SubjectAccessReview != nil {
+		if err := config.SubjectAccessReview.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if config.ExtAuthz != nil {
+		if err := config.ExtAuthz.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &Server{
+		enforcer:     config.Enforcer,
+		ready:        config.Ready,
+		maxBatchSize: maxBatchSize,
+		sar:          config.SubjectAccessReview,
+		extAuthz:     config.ExtAuthz,
+	}, nil
+}
+
+// Ready returns true if the enforcer is in sync.
+func (s *Server) Ready() bool {
+	return s.ready == nil || s.ready()
+}
+
+// requestLogLevel is the verbosity of the request logs, the requests are logged with debug logging only.
+const requestLogLevel = 1
+
+// requestError is a malformed request, as opposed to an enforcer error.
+type requestError struct {
+	err error
+}
+
+func (e requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e requestError) Unwrap() error {
+	return e.err
+}
+
+func (s *Server) enforce(values []string) (bool, error) {
+	if err := s.check(values); err != nil {
+		return false, err
+	}
+	return s.enforcer.Enforce(toInterfaces(values)...)
+}
+
+func (s *Server) batchEnforce(requests [][]string) ([]bool, error) {
+	if !s.Ready() {
+		return nil, ErrNotReady
+	}
+	if len(requests) > s.maxBatchSize {
+		return nil, requestError{fmt.Errorf("batch of %d requests exceeds the limit of %d", len(requests), s.maxBatchSize)}
+	}
+	size := s.requestSize()
+	batch := make([][]interface{}, 0, len(requests))
+	for i, values := range requests {
+		if len(values) != size {
+			return nil, requestError{fmt.Errorf("request %d has %d values, the request definition expects %d", i, len(values), size)}
+		}
+		batch = append(batch, toInterfaces(values))
+	}
+	return s.enforcer.BatchEnforce(batch)
+}
+
+func (s *Server) explain(values []string) (bool, []string, error) {
+	if err := s.check(values); err != nil {
+		return false, nil, err
+	}
+	return s.enforcer.EnforceEx(toInterfaces(values)...)
+}
+
+func (s *Server) check(values []string) error {
+	if !s.Ready() {
+		return ErrNotReady
+	}
+	if size := s.requestSize(); len(values) != size {
+		return requestError{fmt.Errorf("request has %d values, the request definition expects %d", len(values), size)}
+	}
+	return nil
+}
+
+// requestSize returns the number of values of the request definition r of the model, the values are passed as strings,
+// so their number is the only property checked before Enforce. Every error returned by the enforcer is an internal error.
+func (s *Server) requestSize() int {
+	if se, ok := s.enforcer.(*casbin.SyncedEnforcer); ok {
+		lock := se.GetLock()
+		lock.RLock()
+		defer lock.RUnlock()
+	}
+	if ast, ok := s.enforcer.GetModel()["r"]["r"]; ok {
+		return len(ast.Tokens)
+	}
+	return 0
+}
+
+func toInterfaces(values []string) []interface{} {
+	rvals := make([]interface{}, len(values))
+	for i, v := range values {
+		rvals[i] = v
+	}
+	return rvals
+}
@@ -0,0 +1,217 @@
+package pdp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	pdpv1 "github.com/grepplabs/casbin-kube/pdp/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestServer(t *testing.T, ready *atomic.Bool) *Server {
+	t.Helper()
+	e, err := casbin.NewSyncedEnforcer("../examples/rbac_model.conf")
+	require.NoError(t, err)
+	_, err = e.AddPolicies([][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "write"}})
+	require.NoError(t, err)
+	_, err = e.AddGroupingPolicy("bob", "data2_admin")
+	require.NoError(t, err)
+	s, err := NewServer(&ServerConfig{Enforcer: e, Ready: ready.Load, MaxBatchSize: 2})
+	require.NoError(t, err)
+	return s
+}
+
+func Test_NewServer(t *testing.T) {
+	_, err := NewServer(nil)
+	require.EqualError(t, err, "config cannot be nil")
+	_, err = NewServer(&ServerConfig{})
+	require.EqualError(t, err, "enforcer cannot be nil")
+
+	e, err := casbin.NewEnforcer("../examples/rbac_model.conf")
+	require.NoError(t, err)
+	s, err := NewServer(&ServerConfig{Enforcer: e})
+	require.NoError(t, err)
+	require.True(t, s.Ready())
+	require.Equal(t, DefaultMaxBatchSize, s.maxBatchSize)
+}
+
+func post(t *testing.T, h http.Handler, path string, body string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func Test_HTTPHandler(t *testing.T) {
+	var ready atomic.Bool
+	h := newTestServer(t, &ready).Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	code, resp := post(t, h, "/v1/enforce", `{"request":["alice","data1","read"]}`)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, ErrNotReady.Error(), resp["error"])
+
+	ready.Store(true)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	code, resp = post(t, h, "/v1/enforce", `{"request":["alice","data1","read"]}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]any{"allowed": true}, resp)
+	code, resp = post(t, h, "/v1/enforce", `{"request":["alice","data2","write"]}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]any{"allowed": false}, resp)
+
+	code, resp = post(t, h, "/v1/enforce/batch", `{"requests":[["bob","data2","write"],["bob","data1","read"]]}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]any{"allowed": []any{true, false}}, resp)
+
+	code, resp = post(t, h, "/v1/explain", `{"request":["bob","data2","write"]}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]any{"allowed": true, "explain": []any{"data2_admin", "data2", "write"}}, resp)
+	code, resp = post(t, h, "/v1/explain", `{"request":["bob","data1","write"]}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]any{"allowed": false, "explain": []any{}}, resp)
+}
+
+func Test_HTTPHandlerBadRequest(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	h := newTestServer(t, &ready).Handler()
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "invalid json", path: "/v1/enforce", body: `{"request":`},
+		{name: "unknown field", path: "/v1/enforce", body: `{"req":["alice"]}`},
+		{name: "no values", path: "/v1/enforce", body: `{"request":[]}`},
+		{name: "empty batch request", path: "/v1/enforce/batch", body: `{"requests":[["alice","data1","read"],[]]}`},
+		{name: "batch too large", path: "/v1/enforce/batch", body: `{"requests":[["a"],["b"],["c"]]}`},
+		{name: "explain no values", path: "/v1/explain", body: `{}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, resp := post(t, h, tc.path, tc.body)
+			require.Equal(t, http.StatusBadRequest, code)
+			require.NotEmpty(t, resp["error"])
+		})
+	}
+
+	// a request not matching the request definition is rejected before the enforcer
+	for _, path := range []string{"/v1/enforce", "/v1/explain"} {
+		code, resp := post(t, h, path, `{"request":["alice"]}`)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, resp["error"], "the request definition expects 3")
+	}
+	code, resp := post(t, h, "/v1/enforce/batch", `{"requests":[["alice","data1","read"],["alice"]]}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Contains(t, resp["error"], "request 1 has 1 values")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/enforce", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func Test_GRPCServer(t *testing.T) {
+	var ready atomic.Bool
+	s := newTestServer(t, &ready)
+	gs, hs := s.NewGRPCServer()
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	t.Cleanup(gs.Stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.WatchHealth(ctx, hs, 10*time.Millisecond)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, conn.Close()) })
+	client := pdpv1.NewPolicyDecisionServiceClient(conn)
+	health := healthpb.NewHealthClient(conn)
+
+	hr, err := health.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, hr.GetStatus())
+	_, err = client.Enforce(ctx, &pdpv1.EnforceRequest{Values: []string{"alice", "data1", "read"}})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	ready.Store(true)
+	require.Eventually(t, func() bool {
+		hr, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: pdpv1.PolicyDecisionService_ServiceDesc.ServiceName})
+		return err == nil && hr.GetStatus() == healthpb.HealthCheckResponse_SERVING
+	}, 5*time.Second, 10*time.Millisecond, "serving")
+
+	er, err := client.Enforce(ctx, &pdpv1.EnforceRequest{Values: []string{"alice", "data1", "read"}})
+	require.NoError(t, err)
+	require.True(t, er.GetAllowed())
+
+	br, err := client.BatchEnforce(ctx, &pdpv1.BatchEnforceRequest{Requests: []*pdpv1.EnforceRequest{
+		{Values: []string{"bob", "data2", "write"}},
+		{Values: []string{"alice", "data2", "write"}},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false}, br.GetAllowed())
+
+	xr, err := client.Explain(ctx, &pdpv1.EnforceRequest{Values: []string{"bob", "data2", "write"}})
+	require.NoError(t, err)
+	require.True(t, xr.GetAllowed())
+	require.Equal(t, []string{"data2_admin", "data2", "write"}, xr.GetExplain())
+
+	_, err = client.Enforce(ctx, &pdpv1.EnforceRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.BatchEnforce(ctx, &pdpv1.BatchEnforceRequest{Requests: make([]*pdpv1.EnforceRequest, 3)})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.Enforce(ctx, &pdpv1.EnforceRequest{Values: []string{"alice"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_EnforcerError(t *testing.T) {
+	m, err := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+[policy_definition]
+p = sub, obj, act
+[policy_effect]
+e = some(where (p.eft == allow))
+[matchers]
+m = keyMatch(r.obj)
+`)
+	require.NoError(t, err)
+	e, err := casbin.NewEnforcer(m)
+	require.NoError(t, err)
+	s, err := NewServer(&ServerConfig{Enforcer: e})
+	require.NoError(t, err)
+
+	// the errors of the enforcer for a well-formed request are internal errors
+	code, resp := post(t, s.Handler(), "/v1/enforce", `{"request":["alice","data1","read"]}`)
+	require.Equal(t, http.StatusInternalServerError, code)
+	require.NotEmpty(t, resp["error"])
+}
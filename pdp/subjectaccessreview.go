@@ -0,0 +1,169 @@
+package pdp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/grepplabs/loggo/zlog"
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+// The fields of a SubjectAccessReview which can be mapped onto the request definition.
+const (
+	// SARFieldSubject is the user and, if the user is denied, each of the user's groups. See SubjectAccessReviewConfig.Groups.
+	SARFieldSubject = "subject"
+	// SARFieldUser is the user.
+	SARFieldUser = "user"
+	// SARFieldNamespace is the namespace of the resource, empty for cluster-scoped resources and the non-resource requests.
+	SARFieldNamespace = "namespace"
+	// SARFieldVerb is the verb, e.g. get, list or create.
+	SARFieldVerb = "verb"
+	// SARFieldAPIGroup is the API group of the resource, empty for the core group.
+	SARFieldAPIGroup = "apiGroup"
+	// SARFieldResource is the resource with the subresource, e.g. pods or pods/log, or the path of a non-resource request, e.g. /healthz.
+	SARFieldResource = "resource"
+	// SARFieldName is the name of the resource.
+	SARFieldName = "name"
+)
+
+var sarFields = []string{SARFieldSubject, SARFieldUser, SARFieldNamespace, SARFieldVerb, SARFieldAPIGroup, SARFieldResource, SARFieldName}
+
+// DefaultSARRequest matches the request definition "r = sub, dom, obj, act" with the namespace as the domain,
+// so a permission granted in a namespace does not apply to the other namespaces.
+var DefaultSARRequest = []string{SARFieldSubject, SARFieldNamespace, SARFieldResource, SARFieldVerb}
+
+type SubjectAccessReviewConfig struct {
+	// Request lists the SARField* of each value of the request definition in order, defaults to DefaultSARRequest.
+	// E.g. "subject", "resource", "verb" for "r = sub, obj, act", a mapping without the namespace grants the namespaced permissions in all namespaces.
+	Request []string
+	// UserPrefix is prepended to the user, e.g. "user:". Use the same prefix as RBACConfig.UserPrefix.
+	UserPrefix string
+	// GroupPrefix is prepended to the groups, e.g. "group:". Use the same prefix as RBACConfig.GroupPrefix.
+	GroupPrefix string
+	// Groups enforces a request for each group of the user as the subject if the user is denied.
+	Groups bool
+	// Deny denies the requests which are not allowed. By default, the webhook has no opinion and the next authorizer decides.
+	Deny bool
+}
+
+func (c *SubjectAccessReviewConfig) validate() error {
+	for _, f := range c.Request {
+		if !slices.Contains(sarFields, f) {
+			return fmt.Errorf("unknown subject access review field %q, expected one of %s", f, strings.Join(sarFields, ", "))
+		}
+	}
+	return nil
+}
+
+func (c *SubjectAccessReviewConfig) request() []string {
+	if len(c.Request) == 0 {
+		return DefaultSARRequest
+	}
+	return c.Request
+}
+
+// subjects returns the subjects enforced in order.
+func (c *SubjectAccessReviewConfig) subjects(spec *authorizationv1.SubjectAccessReviewSpec) []string {
+	subjects := []string{c.UserPrefix + spec.User}
+	if c.Groups && slices.Contains(c.request(), SARFieldSubject) {
+		for _, g := range spec.Groups {
+			subjects = append(subjects, c.GroupPrefix+g)
+		}
+	}
+	return subjects
+}
+
+// values maps the review onto the values of the request definition.
+func (c *SubjectAccessReviewConfig) values(spec *authorizationv1.SubjectAccessReviewSpec, subject string) []string {
+	var namespace, verb, apiGroup, resource, name string
+	switch {
+	case spec.ResourceAttributes != nil:
+		ra := spec.ResourceAttributes
+		namespace, verb, apiGroup, name = ra.Namespace, ra.Verb, ra.Group, ra.Name
+		resource = ra.Resource
+		if ra.Subresource != "" {
+			resource += "/" + ra.Subresource
+		}
+	case spec.NonResourceAttributes != nil:
+		verb, resource = spec.NonResourceAttributes.Verb, spec.NonResourceAttributes.Path
+	}
+	request := c.request()
+	values := make([]string, len(request))
+	for i, f := range request {
+		switch f {
+		case SARFieldSubject:
+			values[i] = subject
+		case SARFieldUser:
+			values[i] = c.UserPrefix + spec.User
+		case SARFieldNamespace:
+			values[i] = namespace
+		case SARFieldVerb:
+			values[i] = verb
+		case SARFieldAPIGroup:
+			values[i] = apiGroup
+		case SARFieldResource:
+			values[i] = resource
+		case SARFieldName:
+			values[i] = name
+		}
+	}
+	return values
+}
+
+// reviewSubjectAccess decides the review, the user and the groups are enforced until one is allowed.
+func (s *Server) reviewSubjectAccess(spec *authorizationv1.SubjectAccessReviewSpec) authorizationv1.SubjectAccessReviewStatus {
+	if !s.Ready() {
+		return authorizationv1.SubjectAccessReviewStatus{EvaluationError: ErrNotReady.Error()}
+	}
+	if spec.ResourceAttributes == nil && spec.NonResourceAttributes == nil {
+		return authorizationv1.SubjectAccessReviewStatus{EvaluationError: "review has neither resource nor non-resource attributes"}
+	}
+	c := s.sar
+	if size := s.requestSize(); size != len(c.request()) {
+		return authorizationv1.SubjectAccessReviewStatus{
+			EvaluationError: fmt.Sprintf("the request definition expects %d values, the review is mapped onto %d", size, len(c.request())),
+		}
+	}
+	for _, subject := range c.subjects(spec) {
+		allowed, explain, err := s.enforcer.EnforceEx(toInterfaces(c.values(spec, subject))...)
+		if err != nil {
+			return authorizationv1.SubjectAccessReviewStatus{EvaluationError: err.Error()}
+		}
+		if allowed {
+			return authorizationv1.SubjectAccessReviewStatus{
+				Allowed: true,
+				Reason:  fmt.Sprintf("allowed by casbin policy [%s] for %s", strings.Join(explain, ", "), subject),
+			}
+		}
+	}
+	if c.Deny {
+		return authorizationv1.SubjectAccessReviewStatus{Denied: true, Reason: "denied by casbin policy"}
+	}
+	return authorizationv1.SubjectAccessReviewStatus{Reason: "no casbin policy allows the request"}
+}
+
+// handleSubjectAccessReview implements the authorization.k8s.io/v1 webhook contract.
+// The errors of the evaluation are reported in the status, which the API server treats as no opinion.
+func (s *Server) handleSubjectAccessReview(w http.ResponseWriter, r *http.Request) {
+	review := &authorizationv1.SubjectAccessReview{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(review); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request body: " + err.Error()})
+		return
+	}
+	gv := authorizationv1.SchemeGroupVersion.String()
+	if review.APIVersion != gv || review.Kind != "SubjectAccessReview" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("expected %s SubjectAccessReview, got %s %s", gv, review.APIVersion, review.Kind)})
+		return
+	}
+	review.Status = s.reviewSubjectAccess(&review.Spec)
+	if review.Status.EvaluationError != "" {
+		zlog.Warnf("subject access review of %s err: %s", review.Spec.User, review.Status.EvaluationError)
+	}
+	writeJSON(w, http.StatusOK, &authorizationv1.SubjectAccessReview{
+		TypeMeta: review.TypeMeta,
+		Status:   review.Status,
+	})
+}
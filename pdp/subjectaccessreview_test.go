@@ -0,0 +1,206 @@
+package pdp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const domainModel = `
+[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, dom, obj, act
+
+[role_definition]
+g = _, _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub, r.dom) && r.dom == p.dom && r.obj == p.obj && r.act == p.act
+`
+
+func newSARServer(t *testing.T, ready *atomic.Bool, config *SubjectAccessReviewConfig) http.Handler {
+	t.Helper()
+	m, err := model.NewModelFromString(domainModel)
+	require.NoError(t, err)
+	e, err := casbin.NewSyncedEnforcer(m)
+	require.NoError(t, err)
+	_, err = e.AddPolicies([][]string{
+		{"pod-reader", "team-a", "pods", "get"},
+		{"pod-reader", "team-a", "pods/log", "get"},
+		{"group:admins", "team-a", "deployments", "delete"},
+	})
+	require.NoError(t, err)
+	_, err = e.AddGroupingPolicy("user:alice", "pod-reader", "team-a")
+	require.NoError(t, err)
+	s, err := NewServer(&ServerConfig{Enforcer: e, Ready: ready.Load, SubjectAccessReview: config})
+	require.NoError(t, err)
+	return s.Handler()
+}
+
+func review(t *testing.T, h http.Handler, spec authorizationv1.SubjectAccessReviewSpec) authorizationv1.SubjectAccessReviewStatus {
+	t.Helper()
+	body, err := json.Marshal(&authorizationv1.SubjectAccessReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "authorization.k8s.io/v1", Kind: "SubjectAccessReview"},
+		Spec:     spec,
+	})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/subjectaccessreview", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp authorizationv1.SubjectAccessReview
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "authorization.k8s.io/v1", resp.APIVersion)
+	require.Equal(t, "SubjectAccessReview", resp.Kind)
+	return resp.Status
+}
+
+func resourceSpec(user string, groups []string, namespace, verb, resource, subresource string) authorizationv1.SubjectAccessReviewSpec {
+	return authorizationv1.SubjectAccessReviewSpec{
+		User:   user,
+		Groups: groups,
+		ResourceAttributes: &authorizationv1.ResourceAttributes{
+			Namespace:   namespace,
+			Verb:        verb,
+			Resource:    resource,
+			Subresource: subresource,
+		},
+	}
+}
+
+func Test_SubjectAccessReview(t *testing.T) {
+	var ready atomic.Bool
+	h := newSARServer(t, &ready, &SubjectAccessReviewConfig{
+		Request:     []string{SARFieldSubject, SARFieldNamespace, SARFieldResource, SARFieldVerb},
+		UserPrefix:  "user:",
+		GroupPrefix: "group:",
+		Groups:      true,
+	})
+
+	status := review(t, h, resourceSpec("alice", nil, "team-a", "get", "pods", ""))
+	require.False(t, status.Allowed)
+	require.Equal(t, ErrNotReady.Error(), status.EvaluationError)
+
+	ready.Store(true)
+	status = review(t, h, resourceSpec("alice", nil, "team-a", "get", "pods", ""))
+	require.True(t, status.Allowed)
+	require.Equal(t, "allowed by casbin policy [pod-reader, team-a, pods, get] for user:alice", status.Reason)
+
+	status = review(t, h, resourceSpec("alice", nil, "team-a", "get", "pods", "log"))
+	require.True(t, status.Allowed)
+
+	status = review(t, h, resourceSpec("alice", nil, "team-b", "get", "pods", ""))
+	require.False(t, status.Allowed)
+	require.False(t, status.Denied, "no opinion by default")
+	require.Empty(t, status.EvaluationError)
+
+	// bob is allowed through the admins group
+	status = review(t, h, resourceSpec("bob", []string{"system:authenticated", "admins"}, "team-a", "delete", "deployments", ""))
+	require.True(t, status.Allowed)
+	require.Contains(t, status.Reason, "for group:admins")
+	status = review(t, h, resourceSpec("bob", []string{"system:authenticated"}, "team-a", "delete", "deployments", ""))
+	require.False(t, status.Allowed)
+
+	status = review(t, h, authorizationv1.SubjectAccessReviewSpec{User: "alice"})
+	require.False(t, status.Allowed)
+	require.NotEmpty(t, status.EvaluationError)
+}
+
+func Test_SubjectAccessReviewGroupsDisabled(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	h := newSARServer(t, &ready, &SubjectAccessReviewConfig{
+		Request:     []string{SARFieldSubject, SARFieldNamespace, SARFieldResource, SARFieldVerb},
+		UserPrefix:  "user:",
+		GroupPrefix: "group:",
+		Deny:        true,
+	})
+	status := review(t, h, resourceSpec("bob", []string{"admins"}, "team-a", "delete", "deployments", ""))
+	require.False(t, status.Allowed)
+	require.True(t, status.Denied)
+}
+
+func Test_SubjectAccessReviewDefaultRequest(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	h := newSARServer(t, &ready, &SubjectAccessReviewConfig{UserPrefix: "user:"})
+
+	// the namespace is the domain
+	status := review(t, h, resourceSpec("alice", nil, "team-a", "get", "pods", ""))
+	require.True(t, status.Allowed, status.Reason)
+	status = review(t, h, resourceSpec("alice", nil, "team-b", "get", "pods", ""))
+	require.False(t, status.Allowed)
+	require.Empty(t, status.EvaluationError)
+
+	// a mapping not matching the request definition is an evaluation error
+	h = newSARServer(t, &ready, &SubjectAccessReviewConfig{Request: []string{SARFieldSubject, SARFieldResource, SARFieldVerb}})
+	status = review(t, h, resourceSpec("alice", nil, "team-a", "get", "pods", ""))
+	require.False(t, status.Allowed)
+	require.Equal(t, "the request definition expects 4 values, the review is mapped onto 3", status.EvaluationError)
+}
+
+func Test_SubjectAccessReviewValues(t *testing.T) {
+	c := &SubjectAccessReviewConfig{}
+	spec := &authorizationv1.SubjectAccessReviewSpec{
+		User: "alice",
+		NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+			Path: "/healthz",
+			Verb: "get",
+		},
+	}
+	require.Equal(t, []string{"alice", "", "/healthz", "get"}, c.values(spec, "alice"))
+
+	c = &SubjectAccessReviewConfig{
+		Request:    []string{SARFieldUser, SARFieldNamespace, SARFieldAPIGroup, SARFieldResource, SARFieldName, SARFieldVerb},
+		UserPrefix: "user:",
+	}
+	spec = &authorizationv1.SubjectAccessReviewSpec{
+		User: "alice",
+		ResourceAttributes: &authorizationv1.ResourceAttributes{
+			Namespace: "team-a",
+			Verb:      "update",
+			Group:     "apps",
+			Resource:  "deployments",
+			Name:      "web",
+		},
+	}
+	require.Equal(t, []string{"user:alice", "team-a", "apps", "deployments", "web", "update"}, c.values(spec, "ignored"))
+	require.Equal(t, []string{"user:alice"}, c.subjects(spec))
+
+	_, err := NewServer(&ServerConfig{
+		Enforcer:            &casbin.Enforcer{},
+		SubjectAccessReview: &SubjectAccessReviewConfig{Request: []string{"subject", "resource", "action"}},
+	})
+	require.ErrorContains(t, err, `unknown subject access review field "action"`)
+}
+
+func Test_SubjectAccessReviewBadRequest(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	h := newSARServer(t, &ready, &SubjectAccessReviewConfig{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/subjectaccessreview",
+		bytes.NewBufferString(`{"apiVersion":"authorization.k8s.io/v1beta1","kind":"SubjectAccessReview"}`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// not served without the configuration
+	var r atomic.Bool
+	s := newTestServer(t, &r)
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/subjectaccessreview", bytes.NewBufferString(`{}`)))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
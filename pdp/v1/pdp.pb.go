@@ -0,0 +1,330 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: pdp/v1/pdp.proto
+
+package pdpv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EnforceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []string               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnforceRequest) Reset() {
+	*x = EnforceRequest{}
+	mi := &file_pdp_v1_pdp_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnforceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnforceRequest) ProtoMessage() {}
+
+func (x *EnforceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdp_v1_pdp_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnforceRequest.ProtoReflect.Descriptor instead.
+func (*EnforceRequest) Descriptor() ([]byte, []int) {
+	return file_pdp_v1_pdp_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EnforceRequest) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type EnforceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnforceResponse) Reset() {
+	*x = EnforceResponse{}
+	mi := &file_pdp_v1_pdp_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnforceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnforceResponse) ProtoMessage() {}
+
+func (x *EnforceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pdp_v1_pdp_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnforceResponse.ProtoReflect.Descriptor instead.
+func (*EnforceResponse) Descriptor() ([]byte, []int) {
+	return file_pdp_v1_pdp_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EnforceResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+type BatchEnforceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requests      []*EnforceRequest      `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchEnforceRequest) Reset() {
+	*x = BatchEnforceRequest{}
+	mi := &file_pdp_v1_pdp_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchEnforceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchEnforceRequest) ProtoMessage() {}
+
+func (x *BatchEnforceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdp_v1_pdp_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchEnforceRequest.ProtoReflect.Descriptor instead.
+func (*BatchEnforceRequest) Descriptor() ([]byte, []int) {
+	return file_pdp_v1_pdp_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BatchEnforceRequest) GetRequests() []*EnforceRequest {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+type BatchEnforceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       []bool                 `protobuf:"varint,1,rep,packed,name=allowed,proto3" json:"allowed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchEnforceResponse) Reset() {
+	*x = BatchEnforceResponse{}
+	mi := &file_pdp_v1_pdp_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchEnforceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchEnforceResponse) ProtoMessage() {}
+
+func (x *BatchEnforceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pdp_v1_pdp_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchEnforceResponse.ProtoReflect.Descriptor instead.
+func (*BatchEnforceResponse) Descriptor() ([]byte, []int) {
+	return file_pdp_v1_pdp_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchEnforceResponse) GetAllowed() []bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return nil
+}
+
+type ExplainResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Explain       []string               `protobuf:"bytes,2,rep,name=explain,proto3" json:"explain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExplainResponse) Reset() {
+	*x = ExplainResponse{}
+	mi := &file_pdp_v1_pdp_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExplainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExplainResponse) ProtoMessage() {}
+
+func (x *ExplainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pdp_v1_pdp_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExplainResponse.ProtoReflect.Descriptor instead.
+func (*ExplainResponse) Descriptor() ([]byte, []int) {
+	return file_pdp_v1_pdp_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ExplainResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *ExplainResponse) GetExplain() []string {
+	if x != nil {
+		return x.Explain
+	}
+	return nil
+}
+
+var File_pdp_v1_pdp_proto protoreflect.FileDescriptor
+
+const file_pdp_v1_pdp_proto_rawDesc = "" +
+	"\n" +
+	"\x10pdp/v1/pdp.proto\x12\x11casbinkube.pdp.v1\"(\n" +
+	"\x0eEnforceRequest\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values\"+\n" +
+	"\x0fEnforceResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\"T\n" +
+	"\x13BatchEnforceRequest\x12=\n" +
+	"\brequests\x18\x01 \x03(\v2!.casbinkube.pdp.v1.EnforceRequestR\brequests\"0\n" +
+	"\x14BatchEnforceResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x03(\bR\aallowed\"E\n" +
+	"\x0fExplainResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x18\n" +
+	"\aexplain\x18\x02 \x03(\tR\aexplain2\x9c\x02\n" +
+	"\x15PolicyDecisionService\x12P\n" +
+	"\aEnforce\x12!.casbinkube.pdp.v1.EnforceRequest\x1a\".casbinkube.pdp.v1.EnforceResponse\x12_\n" +
+	"\fBatchEnforce\x12&.casbinkube.pdp.v1.BatchEnforceRequest\x1a'.casbinkube.pdp.v1.BatchEnforceResponse\x12P\n" +
+	"\aExplain\x12!.casbinkube.pdp.v1.EnforceRequest\x1a\".casbinkube.pdp.v1.ExplainResponseB/Z-github.com/grepplabs/casbin-kube/pdp/v1;pdpv1b\x06proto3"
+
+var (
+	file_pdp_v1_pdp_proto_rawDescOnce sync.Once
+	file_pdp_v1_pdp_proto_rawDescData []byte
+)
+
+func file_pdp_v1_pdp_proto_rawDescGZIP() []byte {
+	file_pdp_v1_pdp_proto_rawDescOnce.Do(func() {
+		file_pdp_v1_pdp_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pdp_v1_pdp_proto_rawDesc), len(file_pdp_v1_pdp_proto_rawDesc)))
+	})
+	return file_pdp_v1_pdp_proto_rawDescData
+}
+
+var file_pdp_v1_pdp_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_pdp_v1_pdp_proto_goTypes = []any{
+	(*EnforceRequest)(nil),       // 0: casbinkube.pdp.v1.EnforceRequest
+	(*EnforceResponse)(nil),      // 1: casbinkube.pdp.v1.EnforceResponse
+	(*BatchEnforceRequest)(nil),  // 2: casbinkube.pdp.v1.BatchEnforceRequest
+	(*BatchEnforceResponse)(nil), // 3: casbinkube.pdp.v1.BatchEnforceResponse
+	(*ExplainResponse)(nil),      // 4: casbinkube.pdp.v1.ExplainResponse
+}
+var file_pdp_v1_pdp_proto_depIdxs = []int32{
+	0, // 0: casbinkube.pdp.v1.BatchEnforceRequest.requests:type_name -> casbinkube.pdp.v1.EnforceRequest
+	0, // 1: casbinkube.pdp.v1.PolicyDecisionService.Enforce:input_type -> casbinkube.pdp.v1.EnforceRequest
+	2, // 2: casbinkube.pdp.v1.PolicyDecisionService.BatchEnforce:input_type -> casbinkube.pdp.v1.BatchEnforceRequest
+	0, // 3: casbinkube.pdp.v1.PolicyDecisionService.Explain:input_type -> casbinkube.pdp.v1.EnforceRequest
+	1, // 4: casbinkube.pdp.v1.PolicyDecisionService.Enforce:output_type -> casbinkube.pdp.v1.EnforceResponse
+	3, // 5: casbinkube.pdp.v1.PolicyDecisionService.BatchEnforce:output_type -> casbinkube.pdp.v1.BatchEnforceResponse
+	4, // 6: casbinkube.pdp.v1.PolicyDecisionService.Explain:output_type -> casbinkube.pdp.v1.ExplainResponse
+	4, // [4:7] is the sub-list for method output_type
+	1, // [1:4] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_pdp_v1_pdp_proto_init() }
+func file_pdp_v1_pdp_proto_init() {
+	if File_pdp_v1_pdp_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pdp_v1_pdp_proto_rawDesc), len(file_pdp_v1_pdp_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pdp_v1_pdp_proto_goTypes,
+		DependencyIndexes: file_pdp_v1_pdp_proto_depIdxs,
+		MessageInfos:      file_pdp_v1_pdp_proto_msgTypes,
+	}.Build()
+	File_pdp_v1_pdp_proto = out.File
+	file_pdp_v1_pdp_proto_goTypes = nil
+	file_pdp_v1_pdp_proto_depIdxs = nil
+}
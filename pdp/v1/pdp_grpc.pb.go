@@ -0,0 +1,197 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: pdp/v1/pdp.proto
+
+package pdpv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PolicyDecisionService_Enforce_FullMethodName      = "/casbinkube.pdp.v1.PolicyDecisionService/Enforce"
+	PolicyDecisionService_BatchEnforce_FullMethodName = "/casbinkube.pdp.v1.PolicyDecisionService/BatchEnforce"
+	PolicyDecisionService_Explain_FullMethodName      = "/casbinkube.pdp.v1.PolicyDecisionService/Explain"
+)
+
+// PolicyDecisionServiceClient is the client API for PolicyDecisionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PolicyDecisionServiceClient interface {
+	Enforce(ctx context.Context, in *EnforceRequest, opts ...grpc.CallOption) (*EnforceResponse, error)
+	BatchEnforce(ctx context.Context, in *BatchEnforceRequest, opts ...grpc.CallOption) (*BatchEnforceResponse, error)
+	Explain(ctx context.Context, in *EnforceRequest, opts ...grpc.CallOption) (*ExplainResponse, error)
+}
+
+type policyDecisionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPolicyDecisionServiceClient(cc grpc.ClientConnInterface) PolicyDecisionServiceClient {
+	return &policyDecisionServiceClient{cc}
+}
+
+func (c *policyDecisionServiceClient) Enforce(ctx context.Context, in *EnforceRequest, opts ...grpc.CallOption) (*EnforceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EnforceResponse)
+	err := c.cc.Invoke(ctx, PolicyDecisionService_Enforce_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyDecisionServiceClient) BatchEnforce(ctx context.Context, in *BatchEnforceRequest, opts ...grpc.CallOption) (*BatchEnforceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchEnforceResponse)
+	err := c.cc.Invoke(ctx, PolicyDecisionService_BatchEnforce_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyDecisionServiceClient) Explain(ctx context.Context, in *EnforceRequest, opts ...grpc.CallOption) (*ExplainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExplainResponse)
+	err := c.cc.Invoke(ctx, PolicyDecisionService_Explain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PolicyDecisionServiceServer is the server API for PolicyDecisionService service.
+// All implementations must embed UnimplementedPolicyDecisionServiceServer
+// for forward compatibility.
+type PolicyDecisionServiceServer interface {
+	Enforce(context.Context, *EnforceRequest) (*EnforceResponse, error)
+	BatchEnforce(context.Context, *BatchEnforceRequest) (*BatchEnforceResponse, error)
+	Explain(context.Context, *EnforceRequest) (*ExplainResponse, error)
+	mustEmbedUnimplementedPolicyDecisionServiceServer()
+}
+
+// UnimplementedPolicyDecisionServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPolicyDecisionServiceServer struct{}
+
+func (UnimplementedPolicyDecisionServiceServer) Enforce(context.Context, *EnforceRequest) (*EnforceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Enforce not implemented")
+}
+func (UnimplementedPolicyDecisionServiceServer) BatchEnforce(context.Context, *BatchEnforceRequest) (*BatchEnforceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchEnforce not implemented")
+}
+func (UnimplementedPolicyDecisionServiceServer) Explain(context.Context, *EnforceRequest) (*ExplainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Explain not implemented")
+}
+func (UnimplementedPolicyDecisionServiceServer) mustEmbedUnimplementedPolicyDecisionServiceServer() {}
+func (UnimplementedPolicyDecisionServiceServer) testEmbeddedByValue()                               {}
+
+// UnsafePolicyDecisionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PolicyDecisionServiceServer will
+// result in compilation errors.
+type UnsafePolicyDecisionServiceServer interface {
+	mustEmbedUnimplementedPolicyDecisionServiceServer()
+}
+
+func RegisterPolicyDecisionServiceServer(s grpc.ServiceRegistrar, srv PolicyDecisionServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPolicyDecisionServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PolicyDecisionService_ServiceDesc, srv)
+}
+
+func _PolicyDecisionService_Enforce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnforceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyDecisionServiceServer).Enforce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyDecisionService_Enforce_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyDecisionServiceServer).Enforce(ctx, req.(*EnforceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyDecisionService_BatchEnforce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchEnforceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyDecisionServiceServer).BatchEnforce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyDecisionService_BatchEnforce_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyDecisionServiceServer).BatchEnforce(ctx, req.(*BatchEnforceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyDecisionService_Explain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnforceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyDecisionServiceServer).Explain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyDecisionService_Explain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyDecisionServiceServer).Explain(ctx, req.(*EnforceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PolicyDecisionService_ServiceDesc is the grpc.ServiceDesc for PolicyDecisionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PolicyDecisionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "casbinkube.pdp.v1.PolicyDecisionService",
+	HandlerType: (*PolicyDecisionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Enforce",
+			Handler:    _PolicyDecisionService_Enforce_Handler,
+		},
+		{
+			MethodName: "BatchEnforce",
+			Handler:    _PolicyDecisionService_BatchEnforce_Handler,
+		},
+		{
+			MethodName: "Explain",
+			Handler:    _PolicyDecisionService_Explain_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pdp/v1/pdp.proto",
+}
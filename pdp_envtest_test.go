@@ -0,0 +1,75 @@
+package casbinkube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/grepplabs/casbin-kube/pdp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+func TestEnvtestPolicyDecisionServer(t *testing.T) {
+	env := startEnvtest(t)
+
+	e, err := casbin.NewSyncedEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	mgr, err := ctrl.NewManager(env.Config, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	require.NoError(t, err)
+	informer, err := NewManagedInformer(mgr, &ManagedInformerConfig{
+		InformerConfig: InformerConfig{KubeConfig: KubeConfig{Namespace: "default"}},
+	}, e)
+	require.NoError(t, err)
+	server, err := pdp.NewServer(&pdp.ServerConfig{Enforcer: e, Ready: informer.HasSynced})
+	require.NoError(t, err)
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+
+	enforce := func(values ...string) (int, bool) {
+		body, err := json.Marshal(pdp.EnforceRequest{Request: values})
+		require.NoError(t, err)
+		resp, err := http.Post(ts.URL+"/v1/enforce", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		var er pdp.EnforceResponse
+		_ = json.NewDecoder(resp.Body).Decode(&er)
+		return resp.StatusCode, er.Allowed
+	}
+	code, _ := enforce("alice", "data1", "read")
+	require.Equal(t, http.StatusServiceUnavailable, code, "not ready before the informer synced")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		assert.NoError(t, mgr.Start(ctx))
+	}()
+	require.Eventually(t, server.Ready, 10*time.Second, 100*time.Millisecond, "server ready")
+
+	c, err := client.New(env.Config, client.Options{Scheme: scheme})
+	require.NoError(t, err)
+	r := rule("p", "alice", "data1", "read")
+	r.Namespace, r.Name = "default", "alice-read"
+	require.NoError(t, c.Create(ctx, r))
+	require.Eventually(t, func() bool {
+		code, allowed := enforce("alice", "data1", "read")
+		return code == http.StatusOK && allowed
+	}, 10*time.Second, 100*time.Millisecond, "alice allowed")
+
+	require.NoError(t, c.Delete(ctx, r))
+	require.Eventually(t, func() bool {
+		code, allowed := enforce("alice", "data1", "read")
+		return code == http.StatusOK && !allowed
+	}, 10*time.Second, 100*time.Millisecond, "alice denied")
+}
@@ -0,0 +1,203 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	DefaultPolicyChangeRequestControllerName = "casbin-policy-change-request"
+	DefaultRequiredApprovals                 = 1
+)
+
+type PolicyChangeRequestReconcilerConfig struct {
+	// Namespace of the reconciled requests, all namespaces if empty.
+	Namespace string
+	// Labels selecting the reconciled requests, the created Rules get the labels as well.
+	Labels map[string]string
+	// RequiredApprovals is the number of distinct approvers other than the requester, defaults to DefaultRequiredApprovals.
+	RequiredApprovals int
+	// Model validates the policy lines before they are applied if set, a request with an invalid line fails.
+	Model model.Model
+	// NamespaceAsDomain validates the policy lines with the namespace of the request injected as the domain, see KubeConfig.NamespaceAsDomain.
+	NamespaceAsDomain bool
+}
+
+// PolicyChangeRequestReconciler creates and deletes the Rules of a PolicyChangeRequest once it is approved.
+// The requester is set by the PolicyChangeRequestWebhook, requests without a requester are never applied.
+type PolicyChangeRequestReconciler struct {
+	client            client.Client
+	namespace         string
+	labels            map[string]string
+	requiredApprovals int
+	model             model.Model
+	namespaceAsDomain bool
+	clock             clock.PassiveClock
+}
+
+func NewPolicyChangeRequestReconciler(c client.Client, config *PolicyChangeRequestReconcilerConfig) (*PolicyChangeRequestReconciler, error) {
+	if c == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.RequiredApprovals < 0 {
+		return nil, errors.New("required approvals cannot be negative")
+	}
+	requiredApprovals := config.RequiredApprovals
+	if requiredApprovals == 0 {
+		requiredApprovals = DefaultRequiredApprovals
+	}
+	return &PolicyChangeRequestReconciler{
+		client:            c,
+		namespace:         config.Namespace,
+		labels:            config.Labels,
+		requiredApprovals: requiredApprovals,
+		model:             config.Model,
+		namespaceAsDomain: config.NamespaceAsDomain,
+		clock:             clock.RealClock{},
+	}, nil
+}
+
+// SetupWithManager watches the requests, the status changes are not filtered out as the approvals are part of the status.
+func (r *PolicyChangeRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.PolicyChangeRequest{}, builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return matchesRule(obj, r.namespace, r.labels)
+			}),
+		)).
+		Named(DefaultPolicyChangeRequestControllerName).
+		Complete(r)
+}
+
+func (r *PolicyChangeRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	pcr := &v1alpha1.PolicyChangeRequest{}
+	if err := r.client.Get(ctx, req.NamespacedName, pcr); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !pcr.DeletionTimestamp.IsZero() || changeRequestDone(pcr) {
+		return ctrl.Result{}, nil
+	}
+	base := pcr.DeepCopy()
+	status := &pcr.Status
+	if status.Approvals == nil {
+		// an empty list lets the approvers append with a JSON patch
+		status.Approvals = []v1alpha1.PolicyApproval{}
+	}
+	status.ObservedGeneration = pcr.Generation
+	status.ApprovedBy = changeRequestApprovers(pcr)
+	approved := metav1.Condition{
+		Type:               v1alpha1.ConditionTypeApproved,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pcr.Generation,
+		Reason:             v1alpha1.ReasonApproved,
+		Message:            fmt.Sprintf("Approved by %s", strings.Join(status.ApprovedBy, ", ")),
+	}
+	if pcr.Spec.Requester == "" || len(status.ApprovedBy) < r.requiredApprovals {
+		approved.Status = metav1.ConditionFalse
+		approved.Reason = v1alpha1.ReasonWaitingForApproval
+		approved.Message = fmt.Sprintf("%d of %d approvals", len(status.ApprovedBy), r.requiredApprovals)
+		if pcr.Spec.Requester == "" {
+			approved.Message = "Requester is not set, the request cannot be approved"
+		}
+		status.Phase = v1alpha1.PolicyChangeRequestPending
+		meta.SetStatusCondition(&status.Conditions, approved)
+		return ctrl.Result{}, r.patchStatus(ctx, pcr, base)
+	}
+	meta.SetStatusCondition(&status.Conditions, approved)
+	if err := r.validate(pcr); err != nil {
+		status.Phase = v1alpha1.PolicyChangeRequestFailed
+		status.Message = err.Error()
+		zlog.Warnf("policy change request %s/%s failed: %v", pcr.Namespace, pcr.Name, err)
+		return ctrl.Result{}, r.patchStatus(ctx, pcr, base)
+	}
+	// the writes are idempotent, a failed request is retried until all of them succeed
+	if err := r.apply(ctx, pcr); err != nil {
+		return ctrl.Result{}, err
+	}
+	now := metav1.NewTime(r.clock.Now())
+	status.Phase = v1alpha1.PolicyChangeRequestApplied
+	status.AppliedAt = &now
+	status.Message = fmt.Sprintf("Created %d and deleted %d Rule(s)", len(pcr.Spec.Add), len(pcr.Spec.Remove))
+	zlog.Infof("applied policy change request %s/%s of %s approved by %s", pcr.Namespace, pcr.Name, pcr.Spec.Requester, strings.Join(status.ApprovedBy, ", "))
+	return ctrl.Result{}, r.patchStatus(ctx, pcr, base)
+}
+
+func (r *PolicyChangeRequestReconciler) validate(pcr *v1alpha1.PolicyChangeRequest) error {
+	if r.model == nil {
+		return nil
+	}
+	for _, pl := range slices.Concat(pcr.Spec.Add, pcr.Spec.Remove) {
+		line := fromPolicyLine(pl)
+		if err := validateRuleLine(r.model, line, pcr.Namespace, r.namespaceAsDomain); err != nil {
+			return fmt.Errorf("policy line %q is invalid: %w", lineString(line), err)
+		}
+	}
+	return nil
+}
+
+// apply creates the added Rules and deletes the removed ones in the namespace of the request.
+func (r *PolicyChangeRequestReconciler) apply(ctx context.Context, pcr *v1alpha1.PolicyChangeRequest) error {
+	for _, pl := range pcr.Spec.Add {
+		rule := toRule(pcr.Namespace, fromPolicyLine(pl))
+		rule.Labels = mergeLabels(r.labels, map[string]string{v1alpha1.PolicyChangeRequestLabel: pcr.Name})
+		if err := client.IgnoreAlreadyExists(r.client.Create(ctx, &rule)); err != nil {
+			return fmt.Errorf("create rule %q err: %w", lineString(fromPolicyLine(pl)), err)
+		}
+	}
+	for _, pl := range pcr.Spec.Remove {
+		rule := toRule(pcr.Namespace, fromPolicyLine(pl))
+		err := r.client.Delete(ctx, &rule, client.GracePeriodSeconds(DefaultGracePeriodSeconds))
+		if err = client.IgnoreNotFound(err); err != nil {
+			return fmt.Errorf("delete rule %q err: %w", lineString(fromPolicyLine(pl)), err)
+		}
+	}
+	return nil
+}
+
+// patchStatus patches the status if it changed. The optimistic lock fails if approvals were added meanwhile.
+func (r *PolicyChangeRequestReconciler) patchStatus(ctx context.Context, pcr, base *v1alpha1.PolicyChangeRequest) error {
+	// the semantic equality does not distinguish the nil and the empty approvals
+	if base.Status.Approvals != nil && equality.Semantic.DeepEqual(base.Status, pcr.Status) {
+		return nil
+	}
+	err := r.client.Status().Patch(ctx, pcr, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	if err = client.IgnoreNotFound(err); err != nil {
+		return fmt.Errorf("patch policy change request status err: %w", err)
+	}
+	return nil
+}
+
+// changeRequestApprovers returns the distinct approvers in the order of the approvals, the requester is not counted.
+func changeRequestApprovers(pcr *v1alpha1.PolicyChangeRequest) []string {
+	approvers := []string{}
+	for _, approval := range pcr.Status.Approvals {
+		if approval.Approver == "" || approval.Approver == pcr.Spec.Requester || slices.Contains(approvers, approval.Approver) {
+			continue
+		}
+		approvers = append(approvers, approval.Approver)
+	}
+	return approvers
+}
+
+// changeRequestDone returns true if the request was applied or failed, the outcome is final.
+func changeRequestDone(pcr *v1alpha1.PolicyChangeRequest) bool {
+	return pcr.Status.Phase == v1alpha1.PolicyChangeRequestApplied || pcr.Status.Phase == v1alpha1.PolicyChangeRequestFailed
+}
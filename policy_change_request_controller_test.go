@@ -0,0 +1,109 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func changeRequest(name, requester string, add, remove []v1alpha1.PolicyLine, approvers ...string) *v1alpha1.PolicyChangeRequest {
+	pcr := &v1alpha1.PolicyChangeRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: DefaultNamespace},
+		Spec:       v1alpha1.PolicyChangeRequestSpec{Add: add, Remove: remove, Requester: requester},
+	}
+	for _, approver := range approvers {
+		pcr.Status.Approvals = append(pcr.Status.Approvals, v1alpha1.PolicyApproval{Approver: approver})
+	}
+	return pcr
+}
+
+func Test_PolicyChangeRequestReconciler(t *testing.T) {
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	require.NoError(t, err)
+
+	bob := []v1alpha1.PolicyLine{{PType: "p", V0: "bob", V1: "data2", V2: "write"}}
+	alice := []v1alpha1.PolicyLine{{PType: "p", V0: "alice", V1: "data1", V2: "read"}}
+	existing := toRule(DefaultNamespace, fromPolicyLine(alice[0]))
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(
+			&existing,
+			changeRequest("pending", "dave", bob, nil, "erin"),
+			changeRequest("self-approved", "dave", bob, nil, "dave", "dave", "erin"),
+			changeRequest("approved", "dave", bob, alice, "erin", "frank"),
+			changeRequest("invalid", "dave", []v1alpha1.PolicyLine{{PType: "p2", V0: "bob"}}, nil, "erin", "frank"),
+			changeRequest("no-requester", "", bob, nil, "erin", "frank"),
+		).
+		WithStatusSubresource(&v1alpha1.PolicyChangeRequest{}).
+		Build()
+	r, err := NewPolicyChangeRequestReconciler(c, &PolicyChangeRequestReconcilerConfig{
+		Labels:            map[string]string{"app": "a"},
+		RequiredApprovals: 2,
+		Model:             m,
+	})
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	r.clock = clocktesting.NewFakePassiveClock(now)
+
+	ctx := context.Background()
+	get := func(name string) *v1alpha1.PolicyChangeRequest {
+		t.Helper()
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Namespace: DefaultNamespace, Name: name}})
+		require.NoError(t, err)
+		pcr := &v1alpha1.PolicyChangeRequest{}
+		require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: DefaultNamespace, Name: name}, pcr))
+		return pcr
+	}
+
+	pcr := get("pending")
+	require.Equal(t, v1alpha1.PolicyChangeRequestPending, pcr.Status.Phase)
+	require.Equal(t, []string{"erin"}, pcr.Status.ApprovedBy)
+	cond := meta.FindStatusCondition(pcr.Status.Conditions, v1alpha1.ConditionTypeApproved)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, v1alpha1.ReasonWaitingForApproval, cond.Reason)
+	require.Equal(t, "1 of 2 approvals", cond.Message)
+
+	pcr = get("self-approved")
+	require.Equal(t, v1alpha1.PolicyChangeRequestPending, pcr.Status.Phase, "the requester and the duplicates are not counted")
+	require.Equal(t, []string{"erin"}, pcr.Status.ApprovedBy)
+
+	pcr = get("no-requester")
+	require.Equal(t, v1alpha1.PolicyChangeRequestPending, pcr.Status.Phase)
+
+	pcr = get("invalid")
+	require.Equal(t, v1alpha1.PolicyChangeRequestFailed, pcr.Status.Phase)
+	require.Contains(t, pcr.Status.Message, "missing required definition p2")
+
+	pcr = get("approved")
+	require.Equal(t, v1alpha1.PolicyChangeRequestApplied, pcr.Status.Phase)
+	require.Equal(t, []string{"erin", "frank"}, pcr.Status.ApprovedBy)
+	require.Equal(t, now, pcr.Status.AppliedAt.UTC())
+	cond = meta.FindStatusCondition(pcr.Status.Conditions, v1alpha1.ConditionTypeApproved)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionTrue, cond.Status)
+
+	created := &v1alpha1.Rule{}
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: DefaultNamespace, Name: keyFor(fromPolicyLine(bob[0]))}, created))
+	require.Equal(t, map[string]string{"app": "a", v1alpha1.PolicyChangeRequestLabel: "approved"}, created.Labels)
+	err = c.Get(ctx, client.ObjectKeyFromObject(&existing), &v1alpha1.Rule{})
+	require.True(t, apierrors.IsNotFound(err))
+
+	// applied requests are final
+	rv := pcr.ResourceVersion
+	pcr = get("approved")
+	require.Equal(t, rv, pcr.ResourceVersion)
+
+	_, err = NewPolicyChangeRequestReconciler(c, &PolicyChangeRequestReconcilerConfig{RequiredApprovals: -1})
+	require.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/mutate-casbin-grepplabs-com-v1alpha1-policychangerequest,mutating=true,failurePolicy=fail,sideEffects=None,groups=casbin.grepplabs.com,resources=policychangerequests,verbs=create,versions=v1alpha1,name=mpolicychangerequest-v1alpha1.casbin.grepplabs.com,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-casbin-grepplabs-com-v1alpha1-policychangerequest,mutating=false,failurePolicy=fail,sideEffects=None,groups=casbin.grepplabs.com,resources=policychangerequests;policychangerequests/status,verbs=create;update,versions=v1alpha1,name=vpolicychangerequest-v1alpha1.casbin.grepplabs.com,admissionReviewVersions=v1
+
+type PolicyChangeRequestWebhookConfig struct {
+	// Model validates the policy lines of the new requests if set.
+	Model model.Model
+	// NamespaceAsDomain validates the policy lines with the namespace of the request injected as the domain, see KubeConfig.NamespaceAsDomain.
+	NamespaceAsDomain bool
+	// Controller is the user of the PolicyChangeRequestReconciler, e.g. system:serviceaccount:casbin:casbin-kube-controller.
+	// Only the controller can change the phase, the conditions, the approvers and the outcome of a request.
+	Controller string
+}
+
+// PolicyChangeRequestWebhook records the requesting user of a PolicyChangeRequest and guards its status:
+// the approvals can only be appended, each by the approving user, and the requester cannot approve.
+// The rest of the status is set by the controller only.
+type PolicyChangeRequestWebhook struct {
+	model             model.Model
+	namespaceAsDomain bool
+	controller        string
+}
+
+var _ admission.Defaulter[*v1alpha1.PolicyChangeRequest] = (*PolicyChangeRequestWebhook)(nil)
+var _ admission.Validator[*v1alpha1.PolicyChangeRequest] = (*PolicyChangeRequestWebhook)(nil)
+
+func NewPolicyChangeRequestWebhook(config *PolicyChangeRequestWebhookConfig) (*PolicyChangeRequestWebhook, error) {
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.Controller == "" {
+		return nil, errors.New("controller cannot be empty")
+	}
+	return &PolicyChangeRequestWebhook{
+		model:             config.Model,
+		namespaceAsDomain: config.NamespaceAsDomain,
+		controller:        config.Controller,
+	}, nil
+}
+
+// SetupWebhookWithManager registers the mutating and the validating webhook.
+func (w *PolicyChangeRequestWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &v1alpha1.PolicyChangeRequest{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// Default sets the requester to the user creating the request.
+func (w *PolicyChangeRequestWebhook) Default(ctx context.Context, pcr *v1alpha1.PolicyChangeRequest) error {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	if pcr.CreationTimestamp.IsZero() {
+		pcr.Spec.Requester = req.UserInfo.Username
+	}
+	return nil
+}
+
+func (w *PolicyChangeRequestWebhook) ValidateCreate(ctx context.Context, pcr *v1alpha1.PolicyChangeRequest) (admission.Warnings, error) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	if pcr.Spec.Requester != req.UserInfo.Username {
+		errs = append(errs, field.Forbidden(specPath.Child("requester"), "must be the requesting user"))
+	}
+	if w.model != nil {
+		errs = append(errs, w.validateLines(pcr.Namespace, specPath.Child("add"), pcr.Spec.Add)...)
+		errs = append(errs, w.validateLines(pcr.Namespace, specPath.Child("remove"), pcr.Spec.Remove)...)
+	}
+	return nil, changeRequestInvalid(pcr, errs)
+}
+
+// ValidateUpdate allows the user to append own approvals, the existing approvals cannot be changed.
+// The rest of the status can be changed by the controller only.
+func (w *PolicyChangeRequestWebhook) ValidateUpdate(ctx context.Context, oldPCR, pcr *v1alpha1.PolicyChangeRequest) (admission.Warnings, error) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	var errs field.ErrorList
+	if req.UserInfo.Username != w.controller {
+		errs = append(errs, validateControllerStatus(&oldPCR.Status, &pcr.Status)...)
+	}
+	approvalsPath := field.NewPath("status", "approvals")
+	previous, approvals := oldPCR.Status.Approvals, pcr.Status.Approvals
+	if len(approvals) < len(previous) {
+		return nil, changeRequestInvalid(pcr, field.ErrorList{field.Forbidden(approvalsPath, "approvals cannot be removed")})
+	}
+	for i, approval := range approvals {
+		path := approvalsPath.Index(i)
+		switch {
+		case i < len(previous):
+			if approval != previous[i] {
+				errs = append(errs, field.Forbidden(path, "approvals cannot be changed"))
+			}
+		case changeRequestDone(oldPCR):
+			errs = append(errs, field.Forbidden(path, fmt.Sprintf("request is already %s", oldPCR.Status.Phase)))
+		case approval.Approver != req.UserInfo.Username:
+			errs = append(errs, field.Forbidden(path.Child("approver"), "must be the approving user"))
+		case approval.Approver == pcr.Spec.Requester:
+			errs = append(errs, field.Forbidden(path.Child("approver"), "requester cannot approve the own request"))
+		}
+	}
+	return nil, changeRequestInvalid(pcr, errs)
+}
+
+// validateControllerStatus rejects the changes of the status fields set by the controller.
+func validateControllerStatus(previous, status *v1alpha1.PolicyChangeRequestStatus) field.ErrorList {
+	var errs field.ErrorList
+	statusPath := field.NewPath("status")
+	for _, f := range []struct {
+		name     string
+		old, new any
+	}{
+		{name: "phase", old: previous.Phase, new: status.Phase},
+		{name: "conditions", old: previous.Conditions, new: status.Conditions},
+		{name: "approvedBy", old: previous.ApprovedBy, new: status.ApprovedBy},
+		{name: "appliedAt", old: previous.AppliedAt, new: status.AppliedAt},
+		{name: "message", old: previous.Message, new: status.Message},
+		{name: "observedGeneration", old: previous.ObservedGeneration, new: status.ObservedGeneration},
+	} {
+		if !equality.Semantic.DeepEqual(f.old, f.new) {
+			errs = append(errs, field.Forbidden(statusPath.Child(f.name), "can be changed by the controller only"))
+		}
+	}
+	return errs
+}
+
+func (w *PolicyChangeRequestWebhook) ValidateDelete(_ context.Context, _ *v1alpha1.PolicyChangeRequest) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (w *PolicyChangeRequestWebhook) validateLines(namespace string, path *field.Path, lines []v1alpha1.PolicyLine) field.ErrorList {
+	var errs field.ErrorList
+	for i, pl := range lines {
+		if err := validateRuleLine(w.model, fromPolicyLine(pl), namespace, w.namespaceAsDomain); err != nil {
+			errs = append(errs, field.Invalid(path.Index(i), pl, err.Error()))
+		}
+	}
+	return errs
+}
+
+func changeRequestInvalid(pcr *v1alpha1.PolicyChangeRequest, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(v1alpha1.GroupVersion.WithKind("PolicyChangeRequest").GroupKind(), pcr.Name, errs)
+}
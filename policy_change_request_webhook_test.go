@@ -0,0 +1,95 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func withAdmissionUser(username string) context.Context {
+	return admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: username}},
+	})
+}
+
+func Test_PolicyChangeRequestWebhook(t *testing.T) {
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	require.NoError(t, err)
+	_, err = NewPolicyChangeRequestWebhook(&PolicyChangeRequestWebhookConfig{Model: m})
+	require.ErrorContains(t, err, "controller cannot be empty")
+	controller := "system:serviceaccount:casbin:controller"
+	w, err := NewPolicyChangeRequestWebhook(&PolicyChangeRequestWebhookConfig{Model: m, Controller: controller})
+	require.NoError(t, err)
+
+	bob := []v1alpha1.PolicyLine{{PType: "p", V0: "bob", V1: "data2", V2: "write"}}
+	pcr := changeRequest("pcr", "mallory", bob, nil)
+	require.NoError(t, w.Default(withAdmissionUser("dave"), pcr))
+	require.Equal(t, "dave", pcr.Spec.Requester)
+
+	_, err = w.ValidateCreate(withAdmissionUser("dave"), pcr)
+	require.NoError(t, err)
+	_, err = w.ValidateCreate(withAdmissionUser("mallory"), pcr)
+	require.ErrorContains(t, err, "spec.requester")
+	_, err = w.ValidateCreate(withAdmissionUser("dave"), changeRequest("pcr", "dave", []v1alpha1.PolicyLine{{PType: "p2", V0: "bob"}}, nil))
+	require.ErrorContains(t, err, "spec.add[0]")
+	require.True(t, apierrors.IsInvalid(err))
+
+	_, err = w.ValidateCreate(context.Background(), pcr)
+	require.True(t, apierrors.IsInternalError(err), "admission request is required")
+
+	approved := changeRequest("pcr", "dave", bob, nil, "erin")
+	applied := approved.DeepCopy()
+	applied.Status.Phase = v1alpha1.PolicyChangeRequestApplied
+	applied.Status.ApprovedBy = []string{"erin"}
+	applied.Status.AppliedAt = &metav1.Time{Time: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)}
+	applied.Status.Conditions = []metav1.Condition{{Type: v1alpha1.ConditionTypeReady, Status: metav1.ConditionTrue}}
+	failed := approved.DeepCopy()
+	failed.Status.Phase = v1alpha1.PolicyChangeRequestFailed
+	counted := approved.DeepCopy()
+	counted.Status.ApprovedBy = []string{"erin", "frank"}
+	tests := []struct {
+		name    string
+		user    string
+		old     *v1alpha1.PolicyChangeRequest
+		new     *v1alpha1.PolicyChangeRequest
+		wantErr string
+	}{
+		{name: "first approval", user: "erin", old: pcr, new: approved},
+		{name: "second approval", user: "frank", old: approved, new: changeRequest("pcr", "dave", bob, nil, "erin", "frank")},
+		{name: "status update", user: controller, old: approved, new: approved},
+		{name: "applied by the controller", user: controller, old: approved, new: applied},
+		{name: "failed by an approver", user: "erin", old: approved, new: failed, wantErr: "status.phase: Forbidden: can be changed by the controller only"},
+		{name: "applied by an approver", user: "erin", old: approved, new: applied, wantErr: "status.appliedAt"},
+		{name: "conditions of an approver", user: "erin", old: approved, new: applied, wantErr: "status.conditions"},
+		{name: "approvers of an approver", user: "frank", old: approved, new: counted, wantErr: "status.approvedBy"},
+		{name: "approval of other user", user: "frank", old: pcr, new: approved, wantErr: "must be the approving user"},
+		{name: "self approval", user: "dave", old: pcr, new: changeRequest("pcr", "dave", bob, nil, "dave"), wantErr: "requester cannot approve"},
+		{name: "changed approval", user: "frank", old: approved, new: changeRequest("pcr", "dave", bob, nil, "frank"), wantErr: "approvals cannot be changed"},
+		{name: "removed approval", user: "erin", old: approved, new: pcr, wantErr: "approvals cannot be removed"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := w.ValidateUpdate(withAdmissionUser(tc.user), tc.old, tc.new)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.wantErr)
+			require.True(t, apierrors.IsInvalid(err))
+		})
+	}
+
+	appliedApproval := applied.DeepCopy()
+	appliedApproval.Status.Approvals = changeRequest("pcr", "dave", bob, nil, "erin", "frank").Status.Approvals
+	_, err = w.ValidateUpdate(withAdmissionUser("frank"), applied, appliedApproval)
+	require.ErrorContains(t, err, "request is already Applied")
+}
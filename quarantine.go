@@ -0,0 +1,149 @@
+package casbinkube
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v3/model"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ReasonInvalidRule = "InvalidRule"
+	ActionApplyRule   = "Apply"
+)
+
+// QuarantinedRule is a policy line which was skipped because the enforcer rejected it.
+type QuarantinedRule struct {
+	// Kind of the object providing the line, KindRule, KindRuleSet, KindRole, KindRoleBinding, KindClusterRoleBinding or KindClusterRule.
+	Kind      string
+	Name      string
+	Namespace string
+	Rule      CasbinRule
+	Reason    string
+	Time      time.Time
+}
+
+// key identifies a Rule by name and a line of a RuleSet, Role or RBAC binding by kind, name and content.
+func (r QuarantinedRule) key() string {
+	key := r.Namespace + "/" + r.Name
+	if multiLine(r.Kind) {
+		key = r.Kind + "/" + key + "/" + keyFor(r.Rule)
+	}
+	return key
+}
+
+type quarantine struct {
+	mu    sync.RWMutex
+	rules map[string]QuarantinedRule
+	// objects provide the quarantined lines, the lines are applied again when the model changes.
+	objects map[string]client.Object
+}
+
+// quarantinedLine is a quarantined line with the object providing it.
+type quarantinedLine struct {
+	obj  client.Object
+	line CasbinRule
+}
+
+func newQuarantinedRule(obj client.Object, line CasbinRule) QuarantinedRule {
+	return QuarantinedRule{
+		Kind:      objectKind(obj),
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		Rule:      line,
+	}
+}
+
+func (q *quarantine) add(obj client.Object, line CasbinRule, reason error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.rules == nil {
+		q.rules = make(map[string]QuarantinedRule)
+		q.objects = make(map[string]client.Object)
+	}
+	r := newQuarantinedRule(obj, line)
+	r.Reason = reason.Error()
+	r.Time = time.Now()
+	q.rules[r.key()] = r
+	q.objects[r.key()] = obj
+}
+
+// remove returns true if the line was quarantined.
+func (q *quarantine) remove(obj client.Object, line CasbinRule) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key := newQuarantinedRule(obj, line).key()
+	_, ok := q.rules[key]
+	delete(q.rules, key)
+	delete(q.objects, key)
+	return ok
+}
+
+// count returns the number of quarantined lines of the object.
+func (q *quarantine) count(obj client.Object) int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	kind := objectKind(obj)
+	n := 0
+	for _, r := range q.rules {
+		if r.Kind == kind && r.Namespace == obj.GetNamespace() && r.Name == obj.GetName() {
+			n++
+		}
+	}
+	return n
+}
+
+func (q *quarantine) contains(obj client.Object, line CasbinRule) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	_, ok := q.rules[newQuarantinedRule(obj, line).key()]
+	return ok
+}
+
+func (q *quarantine) reset() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rules = nil
+	q.objects = nil
+}
+
+// lines returns the quarantined lines with their objects.
+func (q *quarantine) lines() []quarantinedLine {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	out := make([]quarantinedLine, 0, len(q.rules))
+	for key, r := range q.rules {
+		out = append(out, quarantinedLine{obj: q.objects[key], line: r.Rule})
+	}
+	return out
+}
+
+// list returns the quarantined rules sorted by namespace and name.
+func (q *quarantine) list() []QuarantinedRule {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	out := make([]QuarantinedRule, 0, len(q.rules))
+	for _, r := range q.rules {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace == out[j].Namespace {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Namespace < out[j].Namespace
+	})
+	return out
+}
+
+// validatePolicyLine checks that the model defines the ptype and the number of fields matches its definition.
+func validatePolicyLine(m model.Model, line CasbinRule) error {
+	sec, ptype, rule := lineToPolicyParams(line)
+	if ptype == "" {
+		return errors.New("ptype cannot be empty")
+	}
+	_, err := m.HasPolicyEx(sec, ptype, rule)
+	return err
+}
@@ -0,0 +1,116 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func Test_validatePolicyLine(t *testing.T) {
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		line    CasbinRule
+		wantErr string
+	}{
+		{name: "valid p", line: CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"}},
+		{name: "valid g", line: CasbinRule{PType: "g", V0: "alice", V1: "admin"}},
+		{name: "unknown ptype", line: CasbinRule{PType: "p2", V0: "alice", V1: "data1", V2: "read"}, wantErr: "missing required definition p2"},
+		{name: "too many fields", line: CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read", V3: "x"}, wantErr: "invalid policy rule size"},
+		{name: "too few fields", line: CasbinRule{PType: "p", V0: "alice", V1: "data1"}, wantErr: "invalid policy rule size"},
+		{name: "empty ptype", line: CasbinRule{}, wantErr: "ptype cannot be empty"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validatePolicyLine(m, tc.line)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_InformerQuarantine(t *testing.T) {
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	recorder := events.NewFakeRecorder(10)
+	w, err := NewInformer(&InformerConfig{EventRecorder: recorder}, e)
+	require.NoError(t, err)
+	h := w.ruleEventHandler()
+
+	valid := namedRule("valid", "p", "alice", "data1", "read")
+	unknown := namedRule("unknown", "p2", "alice", "data1", "read")
+	tooMany := namedRule("too-many", "p", "bob", "data1", "read", "extra")
+
+	h.OnAdd(valid, true)
+	h.OnAdd(unknown, true)
+	h.OnAdd(tooMany, true)
+
+	ok, err := e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+	policies, err := e.GetPolicy()
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+
+	quarantined := w.QuarantinedRules()
+	require.Len(t, quarantined, 2)
+	require.Equal(t, "too-many", quarantined[0].Name)
+	require.Contains(t, quarantined[0].Reason, "invalid policy rule size")
+	require.Equal(t, "unknown", quarantined[1].Name)
+	require.Contains(t, quarantined[1].Reason, "missing required definition p2")
+	require.Len(t, recorder.Events, 2)
+	require.Contains(t, <-recorder.Events, "Warning InvalidRule")
+
+	// quarantined rule is not removed from the enforcer
+	h.OnDelete(tooMany)
+	require.Len(t, w.QuarantinedRules(), 1)
+	ok, err = e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+
+	// update of a quarantined rule to a valid one adds it
+	fixed := namedRule("unknown", "p", "carol", "data1", "read")
+	h.OnUpdate(unknown, fixed)
+	require.Empty(t, w.QuarantinedRules())
+	ok, err = e.Enforce("carol", "data1", "read")
+	requireTrue(t, ok, err)
+}
+
+func namedRule(name string, ptype string, vals ...string) *v1alpha1.Rule {
+	r := rule(ptype, vals...)
+	r.Name = name
+	r.Namespace = DefaultNamespace
+	r.UID = types.UID("uid-" + name)
+	return r
+}
+
+func Test_AdapterSavePolicyKeepsQuarantinedRules(t *testing.T) {
+	unknown := namedRule("unknown", "p2", "alice", "data1", "read")
+	a, c := newRevisionTestAdapter(t, RevisionConfig{}, interceptor.Funcs{}, unknown, namedRule("bob", "p", "bob", "data1", "read"))
+	a.tolerant = true
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+	require.Len(t, a.QuarantinedRules(), 1)
+
+	e.EnableAutoSave(false)
+	_, err = e.AddPolicy("carol", "data1", "read")
+	require.NoError(t, err)
+	require.NoError(t, e.SavePolicy())
+
+	ctx := context.Background()
+	rules := &v1alpha1.RuleList{}
+	require.NoError(t, c.List(ctx, rules))
+	require.Len(t, rules.Items, 3)
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(unknown), &v1alpha1.Rule{}), "the quarantined Rule is not deleted")
+}
@@ -0,0 +1,135 @@
+package casbinkube
+
+import (
+	"slices"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	KindRoleBinding        = "RoleBinding"
+	KindClusterRoleBinding = "ClusterRoleBinding"
+)
+
+// RBACConfig maps the subjects of the Kubernetes RoleBindings and ClusterRoleBindings to grouping lines ptype, subject, role[, domain].
+type RBACConfig struct {
+	// Enabled reads the RoleBindings and ClusterRoleBindings in addition to Rules. Bindings are never written by the adapter.
+	Enabled bool
+	// PType of the grouping lines, defaults to g.
+	PType string
+	// Labels select the imported bindings, all bindings if empty. KubeConfig.Labels do not apply to the bindings.
+	Labels map[string]string
+	// Namespaces of the imported RoleBindings, all namespaces if empty.
+	Namespaces []string
+	// NamespaceAsDomain adds the namespace of a RoleBinding as the domain of its lines, e.g. for rbac_with_domains models.
+	NamespaceAsDomain bool
+	// ClusterDomain is the domain of the ClusterRoleBinding lines in the NamespaceAsDomain mode, e.g. *. The lines have no domain if empty.
+	ClusterDomain string
+	// UserPrefix is prepended to the user names. A ServiceAccount is mapped to its user name system:serviceaccount:<namespace>:<name>.
+	UserPrefix string
+	// GroupPrefix is prepended to the group names, e.g. group: to tell the groups from the users.
+	GroupPrefix string
+	// RolePrefix is prepended to the names of the referenced Roles.
+	RolePrefix string
+	// ClusterRolePrefix is prepended to the names of the referenced ClusterRoles.
+	ClusterRolePrefix string
+}
+
+// namespaces returns the namespaces of the RoleBindings, metav1.NamespaceAll selects all namespaces.
+func (c RBACConfig) namespaces() []string {
+	if len(c.Namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return c.Namespaces
+}
+
+// matches reports whether the RoleBinding or ClusterRoleBinding is imported.
+func (c RBACConfig) matches(obj client.Object) bool {
+	if _, ok := obj.(*rbacv1.RoleBinding); ok && len(c.Namespaces) > 0 && !slices.Contains(c.Namespaces, obj.GetNamespace()) {
+		return false
+	}
+	return matchesRule(obj, "", c.Labels)
+}
+
+// roleBindingLines maps the subjects of the RoleBinding to grouping lines in order without duplicates.
+func roleBindingLines(rb *rbacv1.RoleBinding, c RBACConfig) []CasbinRule {
+	var domain string
+	if c.NamespaceAsDomain {
+		domain = rb.Namespace
+	}
+	return bindingLines(c, rb.Namespace, domain, rb.Subjects, rb.RoleRef)
+}
+
+// clusterRoleBindingLines maps the subjects of the ClusterRoleBinding to grouping lines in order without duplicates.
+func clusterRoleBindingLines(crb *rbacv1.ClusterRoleBinding, c RBACConfig) []CasbinRule {
+	var domain string
+	if c.NamespaceAsDomain {
+		domain = c.ClusterDomain
+	}
+	return bindingLines(c, "", domain, crb.Subjects, crb.RoleRef)
+}
+
+func bindingLines(c RBACConfig, namespace, domain string, subjects []rbacv1.Subject, roleRef rbacv1.RoleRef) []CasbinRule {
+	ptype := c.PType
+	if ptype == "" {
+		ptype = "g"
+	}
+	role := c.roleName(roleRef)
+	seen := make(map[string]struct{}, len(subjects))
+	lines := make([]CasbinRule, 0, len(subjects))
+	for _, s := range subjects {
+		subject := c.subjectName(s, namespace)
+		if subject == "" || role == "" {
+			continue
+		}
+		line := CasbinRule{PType: ptype, V0: subject, V1: role, V2: domain}
+		key := keyFor(line)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// subjectName returns the casbin subject of the binding subject, empty for unknown kinds.
+// A ServiceAccount without a namespace belongs to the namespace of the RoleBinding.
+func (c RBACConfig) subjectName(s rbacv1.Subject, namespace string) string {
+	if s.Name == "" {
+		return ""
+	}
+	switch s.Kind {
+	case rbacv1.UserKind:
+		return c.UserPrefix + s.Name
+	case rbacv1.GroupKind:
+		return c.GroupPrefix + s.Name
+	case rbacv1.ServiceAccountKind:
+		if s.Namespace != "" {
+			namespace = s.Namespace
+		}
+		if namespace == "" {
+			return ""
+		}
+		return c.UserPrefix + "system:serviceaccount:" + namespace + ":" + s.Name
+	default:
+		return ""
+	}
+}
+
+// roleName returns the casbin role of the referenced Role or ClusterRole, empty for unknown kinds.
+func (c RBACConfig) roleName(ref rbacv1.RoleRef) string {
+	if ref.Name == "" {
+		return ""
+	}
+	switch ref.Kind {
+	case "Role":
+		return c.RolePrefix + ref.Name
+	case "ClusterRole":
+		return c.ClusterRolePrefix + ref.Name
+	default:
+		return ""
+	}
+}
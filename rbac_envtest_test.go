@@ -0,0 +1,69 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+func TestEnvtestRBACImport(t *testing.T) {
+	env := startEnvtest(t)
+
+	e, err := casbin.NewSyncedEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	_, err = e.AddPolicy("edit", "data1", "write")
+	require.NoError(t, err)
+
+	mgr, err := ctrl.NewManager(env.Config, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	require.NoError(t, err)
+	informer, err := NewManagedInformer(mgr, &ManagedInformerConfig{
+		InformerConfig: InformerConfig{
+			KubeConfig: KubeConfig{RBAC: RBACConfig{Enabled: true, Namespaces: []string{"default"}, GroupPrefix: "group:"}},
+		},
+	}, e)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		assert.NoError(t, mgr.Start(ctx))
+	}()
+	require.Eventually(t, informer.HasSynced, 10*time.Second, 100*time.Millisecond, "informer synced")
+
+	c, err := client.New(env.Config, client.Options{Scheme: scheme})
+	require.NoError(t, err)
+	rb := roleBinding("default", "editors", roleRef("ClusterRole", "edit"), subject(rbacv1.UserKind, "alice"))
+	rb.UID = ""
+	require.NoError(t, c.Create(ctx, rb))
+	other := roleBinding("kube-system", "editors", roleRef("ClusterRole", "edit"), subject(rbacv1.UserKind, "carol"))
+	other.UID = ""
+	require.NoError(t, c.Create(ctx, other))
+	crb := clusterRoleBinding("casbin-editors", roleRef("ClusterRole", "edit"), subject(rbacv1.GroupKind, "developers"))
+	crb.UID = ""
+	require.NoError(t, c.Create(ctx, crb))
+
+	enforce := func(sub string) func() bool {
+		return func() bool {
+			ok, err := e.Enforce(sub, "data1", "write")
+			return err == nil && ok
+		}
+	}
+	require.Eventually(t, enforce("alice"), 10*time.Second, 100*time.Millisecond, "alice bound by the RoleBinding")
+	require.Eventually(t, enforce("group:developers"), 10*time.Second, 100*time.Millisecond, "developers bound by the ClusterRoleBinding")
+	ok, err := e.Enforce("carol", "data1", "write")
+	requireFalse(t, ok, err)
+
+	require.NoError(t, c.Delete(ctx, rb))
+	require.Eventually(t, func() bool { return !enforce("alice")() }, 10*time.Second, 100*time.Millisecond, "alice unbound")
+}
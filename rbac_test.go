@@ -0,0 +1,164 @@
+package casbinkube
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func roleBinding(namespace, name string, roleRef rbacv1.RoleRef, subjects ...rbacv1.Subject) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			UID:       types.UID("uid-" + namespace + "-" + name),
+		},
+		Subjects: subjects,
+		RoleRef:  roleRef,
+	}
+}
+
+func clusterRoleBinding(name string, roleRef rbacv1.RoleRef, subjects ...rbacv1.Subject) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			UID:  types.UID("uid-" + name),
+		},
+		Subjects: subjects,
+		RoleRef:  roleRef,
+	}
+}
+
+func roleRef(kind, name string) rbacv1.RoleRef {
+	return rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: kind, Name: name}
+}
+
+func subject(kind, name string) rbacv1.Subject {
+	return rbacv1.Subject{Kind: kind, Name: name}
+}
+
+func Test_RBACBindingLines(t *testing.T) {
+	rb := roleBinding("team-a", "editors", roleRef("ClusterRole", "edit"),
+		subject(rbacv1.UserKind, "alice"),
+		subject(rbacv1.GroupKind, "developers"),
+		rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "deployer"},
+		rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "ci", Namespace: "tools"},
+		subject(rbacv1.UserKind, "alice"),
+		subject("Unknown", "bob"),
+	)
+	require.Equal(t, []CasbinRule{
+		{PType: "g", V0: "alice", V1: "edit"},
+		{PType: "g", V0: "developers", V1: "edit"},
+		{PType: "g", V0: "system:serviceaccount:team-a:deployer", V1: "edit"},
+		{PType: "g", V0: "system:serviceaccount:tools:ci", V1: "edit"},
+	}, roleBindingLines(rb, RBACConfig{}))
+
+	c := RBACConfig{
+		PType:             "g2",
+		NamespaceAsDomain: true,
+		ClusterDomain:     "*",
+		UserPrefix:        "user:",
+		GroupPrefix:       "group:",
+		RolePrefix:        "role:",
+		ClusterRolePrefix: "clusterrole:",
+	}
+	rb = roleBinding("team-a", "viewers", roleRef("Role", "viewer"), subject(rbacv1.UserKind, "alice"), subject(rbacv1.GroupKind, "qa"))
+	require.Equal(t, []CasbinRule{
+		{PType: "g2", V0: "user:alice", V1: "role:viewer", V2: "team-a"},
+		{PType: "g2", V0: "group:qa", V1: "role:viewer", V2: "team-a"},
+	}, roleBindingLines(rb, c))
+
+	crb := clusterRoleBinding("admins", roleRef("ClusterRole", "admin"), rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "root"}, subject(rbacv1.GroupKind, "ops"))
+	require.Equal(t, []CasbinRule{{PType: "g2", V0: "group:ops", V1: "clusterrole:admin", V2: "*"}}, clusterRoleBindingLines(crb, c))
+	c.ClusterDomain = ""
+	require.Equal(t, []CasbinRule{{PType: "g2", V0: "group:ops", V1: "clusterrole:admin"}}, clusterRoleBindingLines(crb, c))
+}
+
+func Test_RBACMatches(t *testing.T) {
+	c := RBACConfig{Namespaces: []string{"team-a"}, Labels: map[string]string{"casbin.grepplabs.com/import": "true"}}
+	rb := roleBinding("team-a", "editors", roleRef("ClusterRole", "edit"))
+	require.False(t, c.matches(rb))
+	rb.Labels = map[string]string{"casbin.grepplabs.com/import": "true"}
+	require.True(t, c.matches(rb))
+	rb.Namespace = "team-b"
+	require.False(t, c.matches(rb))
+
+	crb := clusterRoleBinding("admins", roleRef("ClusterRole", "admin"))
+	crb.Labels = map[string]string{"casbin.grepplabs.com/import": "true"}
+	require.True(t, c.matches(crb))
+}
+
+func Test_InformerRBAC(t *testing.T) {
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	_, err = e.AddPolicy("edit", "data1", "write")
+	require.NoError(t, err)
+	ch := make(chan PolicyEvent, 10)
+	w, err := NewInformer(&InformerConfig{
+		KubeConfig:          KubeConfig{RBAC: RBACConfig{Enabled: true, Namespaces: []string{"team-a"}}},
+		PolicyEventHandlers: []PolicyEventHandler{PolicyEventChannel(ch)},
+	}, e)
+	require.NoError(t, err)
+	h := w.bindingEventHandler()
+
+	rb := roleBinding("team-a", "editors", roleRef("ClusterRole", "edit"), subject(rbacv1.UserKind, "alice"))
+	h.OnAdd(rb, true)
+	h.OnAdd(roleBinding("team-b", "editors", roleRef("ClusterRole", "edit"), subject(rbacv1.UserKind, "carol")), true)
+	requireEvents(t, ch, 1)
+	event := <-ch
+	require.Equal(t, KindRoleBinding, event.Kind)
+	require.Equal(t, "editors", event.Name)
+
+	ok, err := e.Enforce("alice", "data1", "write")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("carol", "data1", "write")
+	requireFalse(t, ok, err)
+
+	// bob is bound by a ClusterRoleBinding as well
+	crb := clusterRoleBinding("editors", roleRef("ClusterRole", "edit"), subject(rbacv1.UserKind, "bob"))
+	h.OnAdd(crb, false)
+	event = <-ch
+	require.Equal(t, KindClusterRoleBinding, event.Kind)
+
+	updated := rb.DeepCopy()
+	updated.Subjects = []rbacv1.Subject{subject(rbacv1.UserKind, "bob")}
+	h.OnUpdate(rb, updated)
+	event = <-ch
+	require.Equal(t, PolicyRemoved, event.Type)
+	require.Equal(t, "alice", event.Rule.V0)
+	require.Empty(t, ch)
+
+	ok, err = e.Enforce("alice", "data1", "write")
+	requireFalse(t, ok, err)
+
+	h.OnDelete(cache.DeletedFinalStateUnknown{Key: "team-a/editors", Obj: updated})
+	require.Empty(t, ch)
+	ok, err = e.Enforce("bob", "data1", "write")
+	requireTrue(t, ok, err)
+
+	h.OnDelete(crb)
+	ok, err = e.Enforce("bob", "data1", "write")
+	requireFalse(t, ok, err)
+}
+
+func Test_AdapterLoadRBAC(t *testing.T) {
+	a := newTestAdapter(t, KubeConfig{RBAC: RBACConfig{Enabled: true, Namespaces: []string{"team-a"}, GroupPrefix: "group:"}},
+		namedRule("admin", "p", "admin", "data2", "write"),
+		roleBinding("team-a", "editors", roleRef("ClusterRole", "edit"), subject(rbacv1.UserKind, "alice")),
+		roleBinding("team-b", "editors", roleRef("ClusterRole", "edit"), subject(rbacv1.UserKind, "carol")),
+		clusterRoleBinding("ops", roleRef("ClusterRole", "admin"), subject(rbacv1.GroupKind, "ops")),
+	)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+
+	policies, err := e.GetGroupingPolicy()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"alice", "edit"}, {"group:ops", "admin"}}, policies)
+	ok, err := e.Enforce("group:ops", "data2", "write")
+	requireTrue(t, ok, err)
+}
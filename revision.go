@@ -0,0 +1,520 @@
+package casbinkube
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DefaultRevisionHistory = "policy"
+	DefaultRevisionLimit   = 100
+
+	// The operations recorded in PolicyRevision.Spec.Operation.
+	OperationSavePolicy           = "SavePolicy"
+	OperationAddPolicy            = "AddPolicy"
+	OperationRemovePolicy         = "RemovePolicy"
+	OperationRemoveFilteredPolicy = "RemoveFilteredPolicy"
+	OperationRollback             = "Rollback"
+
+	// CurrentRevision selects the current Rules instead of a recorded revision in DiffRevisions.
+	CurrentRevision int64 = 0
+
+	// createRevisionAttempts bounds the retries of concurrent writers claiming the same revision number.
+	createRevisionAttempts = 5
+	// maxDecompressedRules bounds the decompressed size of the policy lines of a revision.
+	maxDecompressedRules = 256 << 20
+)
+
+var (
+	// ErrRevisionsDisabled is returned by the revision operations if RevisionConfig.Enabled is not set.
+	ErrRevisionsDisabled = errors.New("policy revisions are not enabled")
+	// ErrRevisionNotFound is returned for a revision which was never recorded or already pruned.
+	ErrRevisionNotFound = errors.New("policy revision not found")
+)
+
+// RevisionConfig configures the optional history of the Rules written by the adapter.
+// After SavePolicy, AddPolicy, RemovePolicy and RemoveFilteredPolicy all Rules are recorded as a PolicyRevision.
+type RevisionConfig struct {
+	// Enabled records the revisions. The adapter needs permissions to create, list and delete the PolicyRevisions.
+	Enabled bool
+	// History names the PolicyRevisions "<history>-<revision>" and labels them with v1alpha1.PolicyRevisionLabel,
+	// adapters writing different Rules to the same namespace need distinct histories. Defaults to DefaultRevisionHistory.
+	History string
+	// Author recorded in the revisions unless the context carries one, see WithRevisionAuthor.
+	// Defaults to the event component and the host name.
+	Author string
+	// Limit is the number of kept revisions, the oldest ones are deleted. Defaults to DefaultRevisionLimit.
+	Limit int
+	// Compress stores the Rules gzip compressed, for large policies close to the object size limit.
+	Compress bool
+}
+
+func (c RevisionConfig) history() string {
+	if c.History == "" {
+		return DefaultRevisionHistory
+	}
+	return c.History
+}
+
+func (c RevisionConfig) limit() int {
+	if c.Limit <= 0 {
+		return DefaultRevisionLimit
+	}
+	return c.Limit
+}
+
+// Revision is a recorded state of the policy.
+type Revision struct {
+	Revision  int64
+	Operation string
+	Author    string
+	Message   string
+	Time      time.Time
+	Rules     []CasbinRule
+	// Specs are the recorded Rules in the order of Rules, including e.g. expiresAt, schedule and priority.
+	Specs []v1alpha1.RuleSpec
+}
+
+// RevisionDiff lists the policy lines added and removed between two revisions.
+type RevisionDiff struct {
+	From    int64
+	To      int64
+	Added   []CasbinRule
+	Removed []CasbinRule
+}
+
+// Empty reports whether both revisions have the same policy lines.
+func (d *RevisionDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+type revisionAuthorKey struct{}
+
+// WithRevisionAuthor returns a context recording the author in the revisions of the adapter writes, e.g. the user of an admin API.
+func WithRevisionAuthor(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, revisionAuthorKey{}, author)
+}
+
+func (a *Adapter) revisionsEnabled() bool {
+	return a.store.revisionClient != nil
+}
+
+func (a *Adapter) revisionAuthor(ctx context.Context) string {
+	if author, ok := ctx.Value(revisionAuthorKey{}).(string); ok && author != "" {
+		return author
+	}
+	if a.revisions.Author != "" {
+		return a.revisions.Author
+	}
+	return a.events.identity
+}
+
+// recordRevision records the Rules after a successful write. The write is not undone if recording fails, the error is logged only.
+func (a *Adapter) recordRevision(ctx context.Context, operation string) {
+	if !a.revisionsEnabled() {
+		return
+	}
+	if _, err := a.createRevision(ctx, operation, "", false); err != nil {
+		zlog.Errorf("record policy revision after %s err: %v", operation, err)
+	}
+}
+
+// createRevision records the current Rules as the next revision. Unless forced, nothing is recorded if the lines did not change.
+func (a *Adapter) createRevision(ctx context.Context, operation string, message string, force bool) (int64, error) {
+	rules, err := a.store.GetStoredRules(ctx)
+	if err != nil {
+		return 0, err
+	}
+	specs := ruleSpecs(rules)
+	for attempt := 0; ; attempt++ {
+		revisions, err := a.store.GetAllRevisions(ctx)
+		if err != nil {
+			return 0, err
+		}
+		var next int64 = 1
+		if n := len(revisions); n > 0 {
+			last := &revisions[n-1]
+			if !force {
+				previous, err := decodeRevisionRules(&last.Spec)
+				if err != nil {
+					return 0, err
+				}
+				if equality.Semantic.DeepEqual(previous, specs) {
+					return last.Spec.Revision, nil
+				}
+			}
+			next = last.Spec.Revision + 1
+		}
+		revision, err := a.newPolicyRevision(ctx, next, operation, message, specs)
+		if err != nil {
+			return 0, err
+		}
+		// the revision number is claimed by the object name, a concurrent writer gets AlreadyExists and retries
+		err = a.store.revisionClient.Create(ctx, revision)
+		if apierrors.IsAlreadyExists(err) && attempt < createRevisionAttempts {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		a.pruneRevisions(ctx, append(revisions, *revision))
+		return next, nil
+	}
+}
+
+func (a *Adapter) newPolicyRevision(ctx context.Context, revision int64, operation string, message string, specs []v1alpha1.RuleSpec) (*v1alpha1.PolicyRevision, error) {
+	spec := v1alpha1.PolicyRevisionSpec{
+		Revision:  revision,
+		Operation: operation,
+		Author:    a.revisionAuthor(ctx),
+		Timestamp: metav1.NewTime(a.store.clock.Now()),
+		Message:   message,
+		RuleCount: int32(len(specs)), //nolint:gosec
+	}
+	revisionRules := make([]v1alpha1.RevisionRule, 0, len(specs))
+	for i := range specs {
+		revisionRules = append(revisionRules, toRevisionRule(&specs[i]))
+	}
+	if a.revisions.Compress {
+		compressed, err := compressRevisionRules(revisionRules)
+		if err != nil {
+			return nil, err
+		}
+		spec.CompressedRules = compressed
+	} else {
+		spec.Rules = revisionRules
+	}
+	return &v1alpha1.PolicyRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: revisionName(a.revisions.history(), revision),
+		},
+		Spec: spec,
+	}, nil
+}
+
+// pruneRevisions deletes the oldest revisions above the limit, the errors are logged only.
+func (a *Adapter) pruneRevisions(ctx context.Context, revisions []v1alpha1.PolicyRevision) {
+	excess := len(revisions) - a.revisions.limit()
+	for i := 0; i < excess; i++ {
+		if err := a.store.revisionClient.Delete(ctx, &revisions[i]); client.IgnoreNotFound(err) != nil {
+			zlog.Warnf("prune policy revision %s err: %v", revisions[i].Name, err)
+		}
+	}
+}
+
+// ListRevisions returns the recorded revisions, the oldest first.
+func (a *Adapter) ListRevisions(ctx context.Context) ([]Revision, error) {
+	if !a.revisionsEnabled() {
+		return nil, ErrRevisionsDisabled
+	}
+	revisions, err := a.store.GetAllRevisions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Revision, 0, len(revisions))
+	for i := range revisions {
+		r, err := fromPolicyRevision(&revisions[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *r)
+	}
+	return result, nil
+}
+
+// GetRevision returns the recorded revision, ErrRevisionNotFound if it does not exist.
+func (a *Adapter) GetRevision(ctx context.Context, revision int64) (*Revision, error) {
+	if !a.revisionsEnabled() {
+		return nil, ErrRevisionsDisabled
+	}
+	obj, err := a.store.revisionClient.Get(ctx, revisionName(a.revisions.history(), revision))
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: %d", ErrRevisionNotFound, revision)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromPolicyRevision(obj)
+}
+
+// DiffRevisions returns the policy lines added and removed from one revision to the other, CurrentRevision selects the current Rules.
+func (a *Adapter) DiffRevisions(ctx context.Context, from int64, to int64) (*RevisionDiff, error) {
+	if !a.revisionsEnabled() {
+		return nil, ErrRevisionsDisabled
+	}
+	fromLines, err := a.revisionLines(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toLines, err := a.revisionLines(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	diff := diffLines(fromLines, toLines)
+	diff.From, diff.To = from, to
+	return diff, nil
+}
+
+func (a *Adapter) revisionLines(ctx context.Context, revision int64) ([]CasbinRule, error) {
+	if revision == CurrentRevision {
+		rules, err := a.store.GetStoredRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return ruleLines(rules), nil
+	}
+	r, err := a.GetRevision(ctx, revision)
+	if err != nil {
+		return nil, err
+	}
+	return r.Rules, nil
+}
+
+// RollbackToRevision restores the policy lines of the revision and records the result as a new Rollback revision.
+// The missing Rules are created with their recorded spec and the extra Rules deleted, the unchanged Rules keep their current spec.
+// The rollback is not atomic: the Rules are written one by one and the informers can apply the intermediate states.
+// If a write fails, the applied changes are undone on a best-effort basis and the errors of the undo are returned with the cause.
+// The returned diff lists the changes from the current Rules to the revision.
+func (a *Adapter) RollbackToRevision(ctx context.Context, revision int64) (*RevisionDiff, error) {
+	if !a.revisionsEnabled() {
+		return nil, ErrRevisionsDisabled
+	}
+	if a.namespaceAsDomain {
+		return nil, errNamespaceAsDomain
+	}
+	target, err := a.GetRevision(ctx, revision)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := a.store.GetStoredRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diff := diffLines(ruleLines(rules), target.Rules)
+	diff.From, diff.To = CurrentRevision, revision
+
+	wanted := make(map[string]v1alpha1.RuleSpec, len(target.Specs))
+	for _, spec := range target.Specs {
+		wanted[keyFor(fromRuleSpec(&spec))] = spec
+	}
+	var deleted []v1alpha1.Rule
+	for i := range rules {
+		if _, ok := wanted[keyFor(fromRule(&rules[i]))]; !ok {
+			deleted = append(deleted, rules[i])
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool {
+		return lineString(fromRule(&deleted[i])) < lineString(fromRule(&deleted[j]))
+	})
+	var created []CasbinRule
+	for _, line := range diff.Added {
+		if err = a.store.CreateRule(ctx, wanted[keyFor(line)]); err != nil {
+			return nil, a.undoRollback(ctx, err, created, nil)
+		}
+		created = append(created, line)
+	}
+	for i := range deleted {
+		if err = client.IgnoreNotFound(a.store.k8sClient.Delete(ctx, &deleted[i])); err != nil {
+			return nil, a.undoRollback(ctx, err, created, deleted[:i])
+		}
+	}
+	if _, err = a.createRevision(ctx, OperationRollback, "rollback to revision "+strconv.FormatInt(revision, 10), true); err != nil {
+		return diff, fmt.Errorf("record policy revision after rollback err: %w", err)
+	}
+	return diff, nil
+}
+
+// undoRollback deletes the created Rules and recreates the deleted ones, the errors of the undo are joined to the cause.
+func (a *Adapter) undoRollback(ctx context.Context, cause error, created []CasbinRule, deleted []v1alpha1.Rule) error {
+	errs := []error{fmt.Errorf("rollback err: %w", cause)}
+	for _, line := range created {
+		if err := a.store.DeletePolicy(ctx, "", line); err != nil {
+			errs = append(errs, fmt.Errorf("undo create %q err: %w", lineString(line), err))
+		}
+	}
+	for i := range deleted {
+		rule := &v1alpha1.Rule{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        deleted[i].Name,
+				Namespace:   deleted[i].Namespace,
+				Labels:      deleted[i].Labels,
+				Annotations: deleted[i].Annotations,
+			},
+			Spec: deleted[i].Spec,
+		}
+		if err := client.IgnoreAlreadyExists(a.store.k8sClient.Create(ctx, rule)); err != nil {
+			errs = append(errs, fmt.Errorf("undo delete %q err: %w", lineString(fromRule(&deleted[i])), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func revisionName(history string, revision int64) string {
+	return history + "-" + strconv.FormatInt(revision, 10)
+}
+
+func fromPolicyRevision(obj *v1alpha1.PolicyRevision) (*Revision, error) {
+	specs, err := decodeRevisionRules(&obj.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("policy revision %s: %w", obj.Name, err)
+	}
+	return &Revision{
+		Revision:  obj.Spec.Revision,
+		Operation: obj.Spec.Operation,
+		Author:    obj.Spec.Author,
+		Message:   obj.Spec.Message,
+		Time:      obj.Spec.Timestamp.Time,
+		Rules:     specLines(specs),
+		Specs:     specs,
+	}, nil
+}
+
+// decodeRevisionRules returns the recorded Rules, the revisions recorded before the specs only have the policy lines.
+func decodeRevisionRules(spec *v1alpha1.PolicyRevisionSpec) ([]v1alpha1.RuleSpec, error) {
+	revisionRules := spec.Rules
+	if len(spec.CompressedRules) != 0 {
+		var err error
+		if revisionRules, err = decompressRevisionRules(spec.CompressedRules); err != nil {
+			return nil, err
+		}
+	}
+	specs := make([]v1alpha1.RuleSpec, 0, len(revisionRules))
+	for i := range revisionRules {
+		specs = append(specs, fromRevisionRule(&revisionRules[i]))
+	}
+	return specs, nil
+}
+
+func toRevisionRule(spec *v1alpha1.RuleSpec) v1alpha1.RevisionRule {
+	return v1alpha1.RevisionRule{
+		PolicyLine: toPolicyLine(fromRuleSpec(spec)),
+		NotBefore:  spec.NotBefore,
+		ExpiresAt:  spec.ExpiresAt,
+		Schedule:   spec.Schedule,
+		Disabled:   spec.Disabled,
+		Priority:   spec.Priority,
+	}
+}
+
+func fromRevisionRule(r *v1alpha1.RevisionRule) v1alpha1.RuleSpec {
+	line := fromPolicyLine(r.PolicyLine)
+	return v1alpha1.RuleSpec{
+		PType:     line.PType,
+		V0:        line.V0,
+		V1:        line.V1,
+		V2:        line.V2,
+		V3:        line.V3,
+		V4:        line.V4,
+		V5:        line.V5,
+		NotBefore: r.NotBefore,
+		ExpiresAt: r.ExpiresAt,
+		Schedule:  r.Schedule,
+		Disabled:  r.Disabled,
+		Priority:  r.Priority,
+	}
+}
+
+func compressRevisionRules(lines []v1alpha1.RevisionRule) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(lines); err != nil {
+		return nil, fmt.Errorf("compress rules: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("compress rules: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decompressRevisionRules(data []byte) ([]v1alpha1.RevisionRule, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompress rules: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+	var lines []v1alpha1.RevisionRule
+	if err = json.NewDecoder(io.LimitReader(zr, maxDecompressedRules)).Decode(&lines); err != nil {
+		return nil, fmt.Errorf("decompress rules: %w", err)
+	}
+	return lines, nil
+}
+
+// ruleLines returns the distinct policy lines of the Rules in a stable order.
+func ruleLines(rules []v1alpha1.Rule) []CasbinRule {
+	return specLines(ruleSpecs(rules))
+}
+
+// ruleSpecs returns the specs of the Rules with distinct policy lines in the order of the lines.
+func ruleSpecs(rules []v1alpha1.Rule) []v1alpha1.RuleSpec {
+	seen := make(map[string]struct{}, len(rules))
+	specs := make([]v1alpha1.RuleSpec, 0, len(rules))
+	for i := range rules {
+		key := keyFor(fromRule(&rules[i]))
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		specs = append(specs, rules[i].Spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return lineString(fromRuleSpec(&specs[i])) < lineString(fromRuleSpec(&specs[j]))
+	})
+	return specs
+}
+
+func specLines(specs []v1alpha1.RuleSpec) []CasbinRule {
+	lines := make([]CasbinRule, 0, len(specs))
+	for i := range specs {
+		lines = append(lines, fromRuleSpec(&specs[i]))
+	}
+	return lines
+}
+
+// diffLines returns the lines of to missing in from as added and the lines of from missing in to as removed.
+func diffLines(from []CasbinRule, to []CasbinRule) *RevisionDiff {
+	diff := &RevisionDiff{}
+	fromKeys := lineKeys(from)
+	toKeys := lineKeys(to)
+	for _, line := range to {
+		if _, ok := fromKeys[keyFor(line)]; !ok {
+			diff.Added = append(diff.Added, line)
+		}
+	}
+	for _, line := range from {
+		if _, ok := toKeys[keyFor(line)]; !ok {
+			diff.Removed = append(diff.Removed, line)
+		}
+	}
+	sortLines(diff.Added)
+	sortLines(diff.Removed)
+	return diff
+}
+
+func lineKeys(lines []CasbinRule) map[string]struct{} {
+	keys := make(map[string]struct{}, len(lines))
+	for _, line := range lines {
+		keys[keyFor(line)] = struct{}{}
+	}
+	return keys
+}
+
+func sortLines(lines []CasbinRule) {
+	sort.Slice(lines, func(i, j int) bool {
+		return lineString(lines[i]) < lineString(lines[j])
+	})
+}
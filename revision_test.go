@@ -0,0 +1,206 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newRevisionTestAdapter(t *testing.T, config RevisionConfig, funcs interceptor.Funcs, objs ...client.Object) (*Adapter, client.Client) {
+	t.Helper()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(funcs).Build()
+	a := &Adapter{
+		events:    newEventReporter(nil, nil, "", DefaultAdapterEventComponent),
+		revisions: config,
+		store: &k8sAdapter{
+			clock:      clocktesting.NewFakePassiveClock(time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC)),
+			namespaces: []string{DefaultNamespace},
+			k8sClient: &k8sClient[*v1alpha1.Rule, *v1alpha1.RuleList]{
+				New:       func() *v1alpha1.Rule { return &v1alpha1.Rule{} },
+				NewList:   func() *v1alpha1.RuleList { return &v1alpha1.RuleList{} },
+				Client:    c,
+				Namespace: DefaultNamespace,
+			},
+			revisionClient: &k8sClient[*v1alpha1.PolicyRevision, *v1alpha1.PolicyRevisionList]{
+				New:       func() *v1alpha1.PolicyRevision { return &v1alpha1.PolicyRevision{} },
+				NewList:   func() *v1alpha1.PolicyRevisionList { return &v1alpha1.PolicyRevisionList{} },
+				Client:    c,
+				Namespace: DefaultNamespace,
+				Labels:    map[string]string{v1alpha1.PolicyRevisionLabel: config.history()},
+			},
+		},
+	}
+	return a, c
+}
+
+func Test_AdapterRevisions(t *testing.T) {
+	a, _ := newRevisionTestAdapter(t, RevisionConfig{Enabled: true, Author: "ops"}, interceptor.Funcs{})
+	ctx := context.Background()
+
+	require.NoError(t, a.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}))
+	require.NoError(t, a.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}), "unchanged, not recorded")
+	require.NoError(t, a.AddPolicies("p", "p", [][]string{{"bob", "data2", "write"}, {"carol", "data1", "read"}}))
+	require.NoError(t, a.RemovePolicyCtx(WithRevisionAuthor(ctx, "jane"), "p", "p", []string{"alice", "data1", "read"}))
+
+	revisions, err := a.ListRevisions(ctx)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	require.Equal(t, int64(1), revisions[0].Revision)
+	require.Equal(t, OperationAddPolicy, revisions[1].Operation)
+	require.Len(t, revisions[1].Rules, 3)
+	require.Equal(t, "ops", revisions[1].Author)
+	require.Equal(t, OperationRemovePolicy, revisions[2].Operation)
+	require.Equal(t, "jane", revisions[2].Author)
+	require.Equal(t, time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC), revisions[2].Time.UTC())
+
+	diff, err := a.DiffRevisions(ctx, 1, 3)
+	require.NoError(t, err)
+	require.Equal(t, []CasbinRule{{PType: "p", V0: "bob", V1: "data2", V2: "write"}, {PType: "p", V0: "carol", V1: "data1", V2: "read"}}, diff.Added)
+	require.Equal(t, []CasbinRule{{PType: "p", V0: "alice", V1: "data1", V2: "read"}}, diff.Removed)
+
+	diff, err = a.DiffRevisions(ctx, 3, CurrentRevision)
+	require.NoError(t, err)
+	require.True(t, diff.Empty())
+
+	diff, err = a.RollbackToRevision(WithRevisionAuthor(ctx, "jane"), 1)
+	require.NoError(t, err)
+	require.Len(t, diff.Added, 1)
+	require.Len(t, diff.Removed, 2)
+
+	lines, err := a.store.GetAllPolicies(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []CasbinRule{{PType: "p", V0: "alice", V1: "data1", V2: "read"}}, lines)
+
+	r, err := a.GetRevision(ctx, 4)
+	require.NoError(t, err)
+	require.Equal(t, OperationRollback, r.Operation)
+	require.Equal(t, "rollback to revision 1", r.Message)
+	require.Equal(t, revisions[0].Rules, r.Rules)
+
+	_, err = a.GetRevision(ctx, 5)
+	require.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func Test_AdapterRevisionsCompressedAndPruned(t *testing.T) {
+	a, c := newRevisionTestAdapter(t, RevisionConfig{Enabled: true, History: "shop", Limit: 2, Compress: true}, interceptor.Funcs{},
+		namedRule(keyFor(CasbinRule{PType: "g", V0: "alice", V1: "admin"}), "g", "alice", "admin"))
+	ctx := context.Background()
+
+	require.NoError(t, a.AddPolicyCtx(ctx, "p", "p", []string{"admin", "data1", "read"}))
+	require.NoError(t, a.AddPolicyCtx(ctx, "p", "p", []string{"admin", "data1", "write"}))
+	require.NoError(t, a.RemovePolicyCtx(ctx, "p", "p", []string{"admin", "data1", "write"}))
+
+	var l v1alpha1.PolicyRevisionList
+	require.NoError(t, c.List(ctx, &l))
+	require.Len(t, l.Items, 2)
+	for _, item := range l.Items {
+		require.Empty(t, item.Spec.Rules)
+		require.NotEmpty(t, item.Spec.CompressedRules)
+		require.Equal(t, "shop", item.Labels[v1alpha1.PolicyRevisionLabel])
+	}
+
+	revisions, err := a.ListRevisions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), revisions[0].Revision)
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: DefaultNamespace, Name: "shop-3"}, &v1alpha1.PolicyRevision{}))
+	require.Equal(t, OperationRemovePolicy, revisions[1].Operation)
+	require.Contains(t, revisions[1].Author, DefaultAdapterEventComponent)
+	require.Equal(t, []CasbinRule{{PType: "g", V0: "alice", V1: "admin"}, {PType: "p", V0: "admin", V1: "data1", V2: "read"}}, revisions[1].Rules)
+
+	_, err = a.GetRevision(ctx, 1)
+	require.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func Test_AdapterRollbackUndo(t *testing.T) {
+	failCreate := keyFor(CasbinRule{PType: "p", V0: "carol", V1: "data1", V2: "read"})
+	failDelete := keyFor(CasbinRule{PType: "p", V0: "erin", V1: "data1", V2: "read"})
+	var fail bool
+	a, _ := newRevisionTestAdapter(t, RevisionConfig{Enabled: true}, interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if fail && obj.GetName() == failCreate {
+				return errors.New("create forbidden")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+		Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+			if fail && obj.GetName() == failDelete {
+				return errors.New("delete forbidden")
+			}
+			return c.Delete(ctx, obj, opts...)
+		},
+	})
+	ctx := context.Background()
+
+	require.NoError(t, a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data1", "read"}, {"carol", "data1", "read"}}))
+	require.NoError(t, a.RemovePolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data1", "read"}, {"carol", "data1", "read"}}))
+	before, err := a.store.GetAllPolicies(ctx)
+	require.NoError(t, err)
+	require.Empty(t, before)
+
+	fail = true
+	_, err = a.RollbackToRevision(ctx, 1)
+	require.ErrorContains(t, err, "create forbidden")
+	after, err := a.store.GetAllPolicies(ctx)
+	require.NoError(t, err)
+	require.Empty(t, after, "the created Rules are deleted again")
+
+	fail = false
+	require.NoError(t, a.AddPolicies("p", "p", [][]string{{"dave", "data1", "read"}, {"erin", "data1", "read"}}))
+	fail = true
+	failCreate = ""
+	_, err = a.RollbackToRevision(ctx, 2)
+	require.ErrorContains(t, err, "delete forbidden")
+	after, err = a.store.GetAllPolicies(ctx)
+	require.NoError(t, err)
+	require.Len(t, after, 2, "the deleted Rules are created again")
+
+	revisions, err := a.ListRevisions(ctx)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3, "failed rollbacks are not recorded")
+}
+
+func Test_AdapterRollbackRestoresSpec(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		notBefore := metav1.NewTime(time.Date(2026, 10, 13, 8, 0, 0, 0, time.UTC))
+		expiresAt := metav1.NewTime(time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC))
+		alice := toRule(DefaultNamespace, CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"})
+		alice.Spec.NotBefore = &notBefore
+		alice.Spec.ExpiresAt = &expiresAt
+		alice.Spec.Schedule = &v1alpha1.RuleSchedule{Cron: "0 22 * * Sat", Duration: &metav1.Duration{Duration: 4 * time.Hour}, TimeZone: "Europe/Berlin"}
+		alice.Spec.Disabled = true
+		alice.Spec.Priority = 5
+		a, c := newRevisionTestAdapter(t, RevisionConfig{Enabled: true, Compress: compress}, interceptor.Funcs{}, &alice)
+		ctx := context.Background()
+
+		require.NoError(t, a.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data1", "read"}))
+		require.NoError(t, a.RemovePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}))
+		_, err := a.RollbackToRevision(ctx, 1)
+		require.NoError(t, err)
+
+		restored := &v1alpha1.Rule{}
+		require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(&alice), restored))
+		require.True(t, equality.Semantic.DeepEqual(alice.Spec, restored.Spec), "the recorded spec is restored: %+v", restored.Spec)
+	}
+}
+
+func Test_AdapterRevisionsDisabled(t *testing.T) {
+	a := newTestAdapter(t, KubeConfig{})
+	_, err := a.ListRevisions(context.Background())
+	require.ErrorIs(t, err, ErrRevisionsDisabled)
+	_, err = a.RollbackToRevision(context.Background(), 1)
+	require.ErrorIs(t, err, ErrRevisionsDisabled)
+	require.NoError(t, a.AddPolicy("p", "p", []string{"alice", "data1", "read"}))
+
+	_, err = NewAdapter(&AdapterConfig{KubeConfig: KubeConfig{NamespaceAsDomain: true}, Revisions: RevisionConfig{Enabled: true}})
+	require.ErrorIs(t, err, errNamespaceAsDomain)
+}
@@ -0,0 +1,52 @@
+package casbinkube
+
+import (
+	"errors"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const KindRole = "Role"
+
+// errRoleDomain rejects the lines of a Role with a domain in the NamespaceAsDomain mode, the namespace is the domain of its lines.
+var errRoleDomain = errors.New("spec.domain of a Role must be empty in the namespace as domain mode")
+
+// validateRoleDomain returns errRoleDomain for a Role with a domain in the NamespaceAsDomain mode, nil for other objects.
+func validateRoleDomain(obj client.Object, namespaceAsDomain bool) error {
+	if r, ok := obj.(*v1alpha1.Role); ok && namespaceAsDomain && r.Spec.Domain != "" {
+		return errRoleDomain
+	}
+	return nil
+}
+
+// roleLines expands the Role into grouping lines in order without duplicates:
+// ptype, member, role[, domain] for each member and ptype, role, nested role[, domain] for each nested role.
+func roleLines(r *v1alpha1.Role) []CasbinRule {
+	ptype := r.Spec.PType
+	if ptype == "" {
+		ptype = "g"
+	}
+	role := r.Spec.Role
+	if role == "" {
+		role = r.Name
+	}
+	seen := make(map[string]struct{}, len(r.Spec.Members)+len(r.Spec.Roles))
+	lines := make([]CasbinRule, 0, len(r.Spec.Members)+len(r.Spec.Roles))
+	add := func(user, role string) {
+		line := CasbinRule{PType: ptype, V0: user, V1: role, V2: r.Spec.Domain}
+		key := keyFor(line)
+		if _, ok := seen[key]; ok || user == "" || role == "" {
+			return
+		}
+		seen[key] = struct{}{}
+		lines = append(lines, line)
+	}
+	for _, member := range r.Spec.Members {
+		add(member, role)
+	}
+	for _, nested := range r.Spec.Roles {
+		add(role, nested)
+	}
+	return lines
+}
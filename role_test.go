@@ -0,0 +1,103 @@
+package casbinkube
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func namedRole(name string, spec v1alpha1.RoleSpec) *v1alpha1.Role {
+	return &v1alpha1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: DefaultNamespace,
+			UID:       types.UID("uid-" + name),
+		},
+		Spec: spec,
+	}
+}
+
+func Test_RoleLines(t *testing.T) {
+	r := namedRole("admins", v1alpha1.RoleSpec{Members: []string{"alice", "bob", "alice", ""}, Roles: []string{"editor"}})
+	require.Equal(t, []CasbinRule{
+		{PType: "g", V0: "alice", V1: "admins"},
+		{PType: "g", V0: "bob", V1: "admins"},
+		{PType: "g", V0: "admins", V1: "editor"},
+	}, roleLines(r))
+
+	r = namedRole("admins", v1alpha1.RoleSpec{PType: "g2", Role: "admin", Domain: "tenant1", Members: []string{"alice"}})
+	require.Equal(t, []CasbinRule{{PType: "g2", V0: "alice", V1: "admin", V2: "tenant1"}}, roleLines(r))
+}
+
+func Test_InformerRole(t *testing.T) {
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	_, err = e.AddPolicy("data2_admin", "data2", "read")
+	require.NoError(t, err)
+	ch := make(chan PolicyEvent, 10)
+	w, err := NewInformer(&InformerConfig{
+		KubeConfig:          KubeConfig{Roles: true},
+		PolicyEventHandlers: []PolicyEventHandler{PolicyEventChannel(ch)},
+	}, e)
+	require.NoError(t, err)
+	h := w.roleEventHandler()
+
+	r := namedRole("data2-admin", v1alpha1.RoleSpec{Role: "data2_admin", Members: []string{"alice", "bob"}})
+	h.OnAdd(r, true)
+	requireEvents(t, ch, 2)
+	event := <-ch
+	require.Equal(t, KindRole, event.Kind)
+	require.Equal(t, "data2-admin", event.Name)
+	<-ch
+
+	ok, err := e.Enforce("alice", "data2", "read")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("carol", "data2", "read")
+	requireFalse(t, ok, err)
+
+	// bob is assigned the role by a Rule as well
+	w.ruleEventHandler().OnAdd(namedRule("bob", "g", "bob", "data2_admin"), false)
+	require.Empty(t, ch)
+
+	// only the membership changes are applied
+	updated := r.DeepCopy()
+	updated.Spec.Members = []string{"alice", "carol"}
+	h.OnUpdate(r, updated)
+	event = <-ch
+	require.Equal(t, PolicyAdded, event.Type)
+	require.Equal(t, "carol", event.Rule.V0)
+	require.Empty(t, ch)
+
+	ok, err = e.Enforce("bob", "data2", "read")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("carol", "data2", "read")
+	requireTrue(t, ok, err)
+
+	h.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/data2-admin", Obj: updated})
+	requireEvents(t, ch, 2)
+	policies, err := e.GetGroupingPolicy()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"bob", "data2_admin"}}, policies)
+	ok, err = e.Enforce("alice", "data2", "read")
+	requireFalse(t, ok, err)
+}
+
+func Test_AdapterLoadRoles(t *testing.T) {
+	a := newTestAdapter(t, KubeConfig{Roles: true},
+		namedRule("admin", "p", "data2_admin", "data2", "write"),
+		namedRole("data2-admin", v1alpha1.RoleSpec{Role: "data2_admin", Members: []string{"alice", "bob"}}),
+	)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+
+	policies, err := e.GetGroupingPolicy()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"alice", "data2_admin"}, {"bob", "data2_admin"}}, policies)
+	ok, err := e.Enforce("bob", "data2", "write")
+	requireTrue(t, ok, err)
+}
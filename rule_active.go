@@ -0,0 +1,77 @@
+package casbinkube
+
+import (
+	"time"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ruleSpec returns the spec of a Rule or ClusterRule.
+func ruleSpec(obj client.Object) (*v1alpha1.RuleSpec, bool) {
+	switch r := obj.(type) {
+	case *v1alpha1.Rule:
+		return &r.Spec, true
+	case *v1alpha1.ClusterRule:
+		return &r.Spec, true
+	default:
+		return nil, false
+	}
+}
+
+// ruleActive reports whether the rule is active at the time, i.e. not disabled, not before spec.notBefore, before spec.expiresAt
+// and in a window of spec.schedule. Objects without a rule spec, e.g. RuleSets, are always active.
+func ruleActive(obj client.Object, now time.Time) bool {
+	spec, ok := ruleSpec(obj)
+	if !ok {
+		return true
+	}
+	if spec.Disabled {
+		return false
+	}
+	if spec.NotBefore != nil && now.Before(spec.NotBefore.Time) {
+		return false
+	}
+	if spec.ExpiresAt != nil && !now.Before(spec.ExpiresAt.Time) {
+		return false
+	}
+	if spec.Schedule != nil {
+		active, _ := scheduleState(spec.Schedule, now)
+		return active
+	}
+	return true
+}
+
+// nextTransition returns the time after now at which the rule becomes active or expires or a window of its schedule
+// starts or ends, zero if there is none.
+// A disabled rule has no transition until it is enabled.
+func nextTransition(obj client.Object, now time.Time) time.Time {
+	spec, ok := ruleSpec(obj)
+	if !ok || spec.Disabled {
+		return time.Time{}
+	}
+	if spec.NotBefore != nil && now.Before(spec.NotBefore.Time) {
+		return spec.NotBefore.Time
+	}
+	if spec.ExpiresAt != nil && !now.Before(spec.ExpiresAt.Time) {
+		return time.Time{}
+	}
+	var next time.Time
+	if spec.Schedule != nil {
+		_, next = scheduleState(spec.Schedule, now)
+	}
+	if spec.ExpiresAt != nil && (next.IsZero() || spec.ExpiresAt.Time.Before(next)) {
+		next = spec.ExpiresAt.Time
+	}
+	return next
+}
+
+// scheduleState reports whether the time is in a window of the schedule and the time at which this changes.
+// A rule with an invalid schedule is never active, the schedule is validated by the RuleValidator.
+func scheduleState(schedule *v1alpha1.RuleSchedule, now time.Time) (bool, time.Time) {
+	s, err := scheduleCache.get(schedule)
+	if err != nil {
+		return false, time.Time{}
+	}
+	return s.evaluate(now)
+}
@@ -0,0 +1,218 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// asyncFakeClock runs the AfterFunc callbacks in their own goroutine like the real clock, the fake clock runs them while holding its lock.
+type asyncFakeClock struct {
+	*clocktesting.FakeClock
+}
+
+func (c asyncFakeClock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	return c.FakeClock.AfterFunc(d, func() { go f() })
+}
+
+func timeBoundRule(name string, notBefore, expiresAt time.Time, ptype string, vals ...string) *v1alpha1.Rule {
+	r := namedRule(name, ptype, vals...)
+	if !notBefore.IsZero() {
+		r.Spec.NotBefore = &metav1.Time{Time: notBefore}
+	}
+	if !expiresAt.IsZero() {
+		r.Spec.ExpiresAt = &metav1.Time{Time: expiresAt}
+	}
+	return r
+}
+
+func Test_InformerRuleActivity(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	fakeClock := clocktesting.NewFakeClock(now)
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	ch := make(chan PolicyEvent, 10)
+	w, err := NewInformer(&InformerConfig{
+		PolicyEventHandlers: []PolicyEventHandler{PolicyEventChannel(ch)},
+	}, e)
+	require.NoError(t, err)
+	w.clock = asyncFakeClock{fakeClock}
+	defer w.Close()
+	h := w.ruleEventHandler()
+
+	oncall := timeBoundRule("oncall", time.Time{}, now.Add(time.Hour), "p", "alice", "data1", "write")
+	incident := timeBoundRule("incident", now.Add(time.Hour), now.Add(2*time.Hour), "p", "bob", "data1", "write")
+	expired := timeBoundRule("expired", time.Time{}, now, "p", "carol", "data1", "write")
+	h.OnAdd(oncall, true)
+	h.OnAdd(incident, true)
+	h.OnAdd(expired, true)
+	requireEvents(t, ch, 1)
+	require.Equal(t, "oncall", (<-ch).Name)
+
+	ok, err := e.Enforce("alice", "data1", "write")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("bob", "data1", "write")
+	requireFalse(t, ok, err)
+	ok, err = e.Enforce("carol", "data1", "write")
+	requireFalse(t, ok, err)
+
+	// oncall expires and incident becomes active without a watch event
+	fakeClock.Step(time.Hour)
+	events := map[string]PolicyEventType{}
+	for range 2 {
+		select {
+		case event := <-ch:
+			events[event.Name] = event.Type
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for the policy events")
+		}
+	}
+	require.Equal(t, map[string]PolicyEventType{"oncall": PolicyRemoved, "incident": PolicyAdded}, events)
+	policies, err := e.GetPolicy()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"bob", "data1", "write"}}, policies)
+
+	// extending the expiry activates the rule again
+	extended := oncall.DeepCopy()
+	extended.Spec.ExpiresAt = &metav1.Time{Time: now.Add(3 * time.Hour)}
+	h.OnUpdate(oncall, extended)
+	event := <-ch
+	require.Equal(t, PolicyAdded, event.Type)
+	require.Equal(t, "oncall", event.Name)
+
+	// the metadata update of an active rule does not change the policy
+	labeled := extended.DeepCopy()
+	labeled.Labels = map[string]string{"team": "a"}
+	h.OnUpdate(extended, labeled)
+	require.Empty(t, ch)
+
+	// deleting an inactive rule does not change the policy
+	h.OnDelete(expired)
+	require.Empty(t, ch)
+
+	h.OnDelete(incident)
+	require.Equal(t, PolicyRemoved, (<-ch).Type)
+	w.ruleMu.Lock()
+	require.Len(t, w.timers, 1) // oncall
+	require.Empty(t, w.inactive)
+	w.ruleMu.Unlock()
+}
+
+func Test_InformerRuleDisabled(t *testing.T) {
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	ch := make(chan PolicyEvent, 10)
+	w, err := NewInformer(&InformerConfig{
+		PolicyEventHandlers: []PolicyEventHandler{PolicyEventChannel(ch)},
+	}, e)
+	require.NoError(t, err)
+	defer w.Close()
+	h := w.ruleEventHandler()
+
+	disabled := timeBoundRule("alice", time.Time{}, time.Now().Add(time.Hour), "p", "alice", "data1", "read")
+	disabled.Spec.Disabled = true
+	h.OnAdd(disabled, false)
+	require.Empty(t, ch)
+	w.ruleMu.Lock()
+	require.Empty(t, w.timers)
+	w.ruleMu.Unlock()
+
+	enabled := disabled.DeepCopy()
+	enabled.Spec.Disabled = false
+	h.OnUpdate(disabled, enabled)
+	require.Equal(t, PolicyAdded, (<-ch).Type)
+	ok, err := e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+
+	h.OnUpdate(enabled, disabled)
+	require.Equal(t, PolicyRemoved, (<-ch).Type)
+	ok, err = e.Enforce("alice", "data1", "read")
+	requireFalse(t, ok, err)
+
+	h.OnDelete(disabled)
+	require.Empty(t, ch)
+}
+
+func Test_AdapterLoadActiveRules(t *testing.T) {
+	now := time.Now()
+	a := newTestAdapter(t, KubeConfig{ClusterRules: true},
+		namedRule("alice", "p", "alice", "data1", "read"),
+		timeBoundRule("bob", time.Time{}, now.Add(time.Hour), "p", "bob", "data2", "write"),
+		timeBoundRule("carol", time.Time{}, now.Add(-time.Hour), "p", "carol", "data1", "read"),
+		timeBoundRule("dave", now.Add(time.Hour), time.Time{}, "p", "dave", "data1", "read"),
+		&v1alpha1.ClusterRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "admin"},
+			Spec:       v1alpha1.RuleSpec{PType: "p", V0: "admin", V1: "data1", V2: "write", ExpiresAt: &metav1.Time{Time: now.Add(-time.Hour)}},
+		},
+		&v1alpha1.Rule{
+			ObjectMeta: metav1.ObjectMeta{Name: "erin", Namespace: DefaultNamespace},
+			Spec:       v1alpha1.RuleSpec{PType: "p", V0: "erin", V1: "data1", V2: "read", Disabled: true},
+		},
+	)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+
+	policies, err := e.GetPolicy()
+	require.NoError(t, err)
+	require.ElementsMatch(t, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}, policies)
+
+	lines, err := a.store.GetAllPolicies(context.Background())
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+}
+
+func Test_AdapterSavePolicyKeepsTimeBoundRules(t *testing.T) {
+	now := time.Now()
+	a := newTestAdapter(t, KubeConfig{},
+		namedRule("alice", "p", "alice", "data1", "read"),
+		timeBoundRule("bob", time.Time{}, now.Add(time.Hour), "p", "bob", "data2", "write"),
+		timeBoundRule("carol", time.Time{}, now.Add(-time.Hour), "p", "carol", "data1", "read"),
+		timeBoundRule("dave", now.Add(time.Hour), time.Time{}, "p", "dave", "data1", "read"),
+	)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+	e.EnableAutoSave(false)
+	_, err = e.RemovePolicy("alice", "data1", "read")
+	require.NoError(t, err)
+	_, err = e.AddPolicy("frank", "data1", "read")
+	require.NoError(t, err)
+	require.NoError(t, e.SavePolicy())
+
+	ctx := context.Background()
+	rules, err := a.store.GetStoredRules(ctx)
+	require.NoError(t, err)
+	byName := make(map[string]v1alpha1.Rule, len(rules))
+	for _, rule := range rules {
+		byName[rule.Name] = rule
+	}
+	require.Len(t, byName, 4)
+	require.NotContains(t, byName, "alice")
+	require.NotNil(t, byName["bob"].Spec.ExpiresAt, "the expiry is kept")
+	require.Contains(t, byName, "carol", "the expired Rule is left to the ExpiredRuleReconciler")
+	require.NotNil(t, byName["dave"].Spec.NotBefore, "the pending Rule is not deleted")
+	require.Contains(t, byName, keyFor(CasbinRule{PType: "p", V0: "frank", V1: "data1", V2: "read"}))
+}
+
+func Test_AdapterSavePolicyKeepsDisabledRules(t *testing.T) {
+	disabled := namedRule("erin", "p", "erin", "data1", "read")
+	disabled.Spec.Disabled = true
+	a := newTestAdapter(t, KubeConfig{}, namedRule("alice", "p", "alice", "data1", "read"), disabled)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+	e.ClearPolicy()
+	require.NoError(t, e.SavePolicy())
+
+	rules, err := a.store.GetStoredRules(context.Background())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, "erin", rules[0].Name)
+	require.True(t, rules[0].Spec.Disabled)
+}
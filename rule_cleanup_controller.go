@@ -0,0 +1,110 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const DefaultExpiredRuleControllerName = "casbin-expired-rule-cleanup"
+
+type ExpiredRuleReconcilerConfig struct {
+	// Namespace of the reconciled Rules, all namespaces if empty.
+	Namespace string
+	// Labels selecting the reconciled Rules and ClusterRules.
+	Labels map[string]string
+	// ClusterRules deletes the expired ClusterRules as well.
+	ClusterRules bool
+	// Retention keeps an expired rule for the duration before it is deleted.
+	Retention time.Duration
+}
+
+// ExpiredRuleReconciler deletes the Rules and ClusterRules after spec.expiresAt.
+// The informer and the adapter do not enforce expired rules, the deletion only cleans them up.
+type ExpiredRuleReconciler struct {
+	client       client.Client
+	namespace    string
+	labels       map[string]string
+	clusterRules bool
+	retention    time.Duration
+	clock        clock.PassiveClock
+}
+
+func NewExpiredRuleReconciler(c client.Client, config *ExpiredRuleReconcilerConfig) (*ExpiredRuleReconciler, error) {
+	if c == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.Retention < 0 {
+		return nil, errors.New("retention cannot be negative")
+	}
+	return &ExpiredRuleReconciler{
+		client:       c,
+		namespace:    config.Namespace,
+		labels:       config.Labels,
+		clusterRules: config.ClusterRules,
+		retention:    config.Retention,
+		clock:        clock.RealClock{},
+	}, nil
+}
+
+func (r *ExpiredRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Rule{}, builder.WithPredicates(
+			predicate.GenerationChangedPredicate{},
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return matchesRule(obj, r.namespace, r.labels)
+			}),
+		)).
+		Named(DefaultExpiredRuleControllerName).
+		Complete(r)
+	if err != nil || !r.clusterRules {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterRule{}, builder.WithPredicates(
+			predicate.GenerationChangedPredicate{},
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return matchesRule(obj, "", r.labels)
+			}),
+		)).
+		Named(DefaultExpiredRuleControllerName + "-cluster").
+		Complete(r)
+}
+
+// Reconcile handles a Rule, or a ClusterRule if the request has no namespace.
+func (r *ExpiredRuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var obj client.Object = &v1alpha1.Rule{}
+	if req.Namespace == "" {
+		obj = &v1alpha1.ClusterRule{}
+	}
+	if err := r.client.Get(ctx, req.NamespacedName, obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	spec, _ := ruleSpec(obj)
+	if !obj.GetDeletionTimestamp().IsZero() || spec.ExpiresAt == nil {
+		return ctrl.Result{}, nil
+	}
+	if wait := spec.ExpiresAt.Add(r.retention).Sub(r.clock.Now()); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+	// the preconditions protect a rule whose expiry was extended meanwhile
+	uid, resourceVersion := obj.GetUID(), obj.GetResourceVersion()
+	err := r.client.Delete(ctx, obj, client.Preconditions{UID: &uid, ResourceVersion: &resourceVersion})
+	if err = client.IgnoreNotFound(err); err != nil {
+		return ctrl.Result{}, fmt.Errorf("delete expired %s err: %w", objectKind(obj), err)
+	}
+	zlog.Infof("deleted expired %s %s", objectKind(obj), req.String())
+	return ctrl.Result{}, nil
+}
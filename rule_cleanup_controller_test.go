@@ -0,0 +1,63 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_ExpiredRuleReconciler(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	expired := timeBoundRule("expired", time.Time{}, now.Add(-2*time.Minute), "p", "alice", "data1", "read")
+	retained := timeBoundRule("retained", time.Time{}, now.Add(-30*time.Second), "p", "bob", "data1", "read")
+	active := timeBoundRule("active", time.Time{}, now.Add(time.Hour), "p", "carol", "data1", "read")
+	permanent := namedRule("permanent", "p", "dave", "data1", "read")
+	clusterExpired := &v1alpha1.ClusterRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-expired"},
+		Spec:       v1alpha1.RuleSpec{PType: "p", V0: "admin", V1: "data1", V2: "write", ExpiresAt: &metav1.Time{Time: now.Add(-time.Hour)}},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(expired, retained, active, permanent, clusterExpired).
+		Build()
+	r, err := NewExpiredRuleReconciler(c, &ExpiredRuleReconcilerConfig{ClusterRules: true, Retention: time.Minute})
+	require.NoError(t, err)
+	r.clock = clocktesting.NewFakePassiveClock(now)
+
+	ctx := context.Background()
+	tests := []struct {
+		key          client.ObjectKey
+		requeueAfter time.Duration
+		deleted      client.Object
+	}{
+		{key: client.ObjectKeyFromObject(expired), deleted: &v1alpha1.Rule{}},
+		{key: client.ObjectKeyFromObject(retained), requeueAfter: 30 * time.Second},
+		{key: client.ObjectKeyFromObject(active), requeueAfter: time.Hour + time.Minute},
+		{key: client.ObjectKeyFromObject(permanent)},
+		{key: client.ObjectKeyFromObject(clusterExpired), deleted: &v1alpha1.ClusterRule{}},
+		{key: client.ObjectKey{Namespace: DefaultNamespace, Name: "missing"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.key.String(), func(t *testing.T) {
+			res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: tc.key})
+			require.NoError(t, err)
+			require.Equal(t, tc.requeueAfter, res.RequeueAfter)
+			if tc.deleted != nil {
+				require.True(t, apierrors.IsNotFound(c.Get(ctx, tc.key, tc.deleted)))
+			}
+		})
+	}
+	rules := &v1alpha1.RuleList{}
+	require.NoError(t, c.List(ctx, rules))
+	require.Len(t, rules.Items, 3)
+}
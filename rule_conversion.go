@@ -0,0 +1,139 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/casbin-kube/api/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+)
+
+type RuleConverterConfig struct {
+	// Model provides the field names of the policy types, e.g. sub, obj, act for p = sub, obj, act.
+	Model model.Model
+	// FieldNames sets or overrides the field names by ptype, e.g. {"g": {"user", "role"}}. The model does not name the fields of grouping rules.
+	FieldNames map[string][]string
+}
+
+// RuleConverter converts Rules between v1alpha1 and v1beta1. It resolves the named fields of v1beta1 Rules to values
+// and names the values of v1alpha1 Rules by the field names of the model.
+type RuleConverter struct {
+	fieldNames map[string][]string
+}
+
+// NewRuleConverter creates the converter.
+func NewRuleConverter(config *RuleConverterConfig) (*RuleConverter, error) {
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.Model == nil {
+		return nil, errors.New("model cannot be nil")
+	}
+	fieldNames := make(map[string][]string)
+	if ast, ok := config.Model["p"]; ok {
+		for ptype, assertion := range ast {
+			names := make([]string, 0, len(assertion.Tokens))
+			for _, token := range assertion.Tokens {
+				names = append(names, strings.TrimPrefix(token, ptype+"_"))
+			}
+			fieldNames[ptype] = names
+		}
+	}
+	for ptype, names := range config.FieldNames {
+		if len(names) > 6 {
+			return nil, fmt.Errorf("ptype %s has %d field names, at most 6 are supported", ptype, len(names))
+		}
+		fieldNames[ptype] = slices.Clone(names)
+	}
+	return &RuleConverter{
+		fieldNames: fieldNames,
+	}, nil
+}
+
+// SetupWebhookWithManager registers the conversion webhook at /convert. The manager scheme must contain both API versions.
+func (c *RuleConverter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	for _, obj := range []runtime.Object{&v1alpha1.Rule{}, &v1beta1.Rule{}} {
+		if _, _, err := mgr.GetScheme().ObjectKinds(obj); err != nil {
+			return fmt.Errorf("manager scheme err: %w", err)
+		}
+	}
+	return ctrl.NewWebhookManagedBy(mgr, &v1alpha1.Rule{}).
+		WithConverter(conversion.NewHubSpokeConverter(&v1alpha1.Rule{},
+			conversion.NewSpokeConverter(&v1beta1.Rule{}, c.convertHubToSpoke, c.convertSpokeToHub))).
+		Complete()
+}
+
+// convertHubToSpoke names the values by the field names of the model unless the stored Rule keeps its own fields.
+func (c *RuleConverter) convertHubToSpoke(_ context.Context, src *v1alpha1.Rule, dst *v1beta1.Rule) error {
+	if err := dst.ConvertFrom(src); err != nil {
+		return err
+	}
+	if len(dst.Spec.Fields) == 0 {
+		dst.Spec.Fields = c.defaultFields(dst.Spec.PType, dst.Spec.Values)
+	}
+	return nil
+}
+
+// convertSpokeToHub resolves the named fields to values. The fields equal to the ones named by the model are not kept
+// in the fields annotation, convertHubToSpoke derives them again and the stored Rule does not change on a round trip.
+func (c *RuleConverter) convertSpokeToHub(_ context.Context, src *v1beta1.Rule, dst *v1alpha1.Rule) error {
+	if len(src.Spec.Fields) != 0 {
+		values, err := c.resolveFields(src.Spec.PType, src.Spec.Values, src.Spec.Fields)
+		if err != nil {
+			return fmt.Errorf("rule %s/%s: %w", src.Namespace, src.Name, err)
+		}
+		src = src.DeepCopy()
+		src.Spec.Values = values
+		if maps.Equal(src.Spec.Fields, c.defaultFields(src.Spec.PType, values)) {
+			src.Spec.Fields = nil
+		}
+	}
+	return src.ConvertTo(dst)
+}
+
+// defaultFields names the non-empty values by the field names of the ptype, nil if no value is named.
+func (c *RuleConverter) defaultFields(ptype string, values []string) map[string]string {
+	names := c.fieldNames[ptype]
+	var fields map[string]string
+	for i, value := range values {
+		if i >= len(names) || value == "" {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[names[i]] = value
+	}
+	return fields
+}
+
+// resolveFields sets the values at the positions of the named fields. A named field must not contradict a value.
+func (c *RuleConverter) resolveFields(ptype string, values []string, fields map[string]string) ([]string, error) {
+	names, ok := c.fieldNames[ptype]
+	if !ok {
+		return nil, fmt.Errorf("ptype %s has no field names", ptype)
+	}
+	values = slices.Clone(values)
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		index := slices.Index(names, name)
+		if index < 0 {
+			return nil, fmt.Errorf("unknown field %q of ptype %s, expected one of %s", name, ptype, strings.Join(names, ", "))
+		}
+		for len(values) <= index {
+			values = append(values, "")
+		}
+		if values[index] != "" && values[index] != fields[name] {
+			return nil, fmt.Errorf("field %q conflicts with value %d %q", name, index, values[index])
+		}
+		values[index] = fields[name]
+	}
+	return values, nil
+}
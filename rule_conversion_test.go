@@ -0,0 +1,188 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/casbin-kube/api/v1beta1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_RuleConverter(t *testing.T) {
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	require.NoError(t, err)
+	c, err := NewRuleConverter(&RuleConverterConfig{
+		Model:      m,
+		FieldNames: map[string][]string{"g": {"user", "role"}},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// v1alpha1 -> v1beta1 names the values
+	alpha := namedRule("alice", "p", "alice", "data1", "read")
+	beta := &v1beta1.Rule{}
+	require.NoError(t, c.convertHubToSpoke(ctx, alpha, beta))
+	require.Equal(t, v1beta1.RuleSpec{
+		PType:  "p",
+		Values: []string{"alice", "data1", "read"},
+		Fields: map[string]string{"sub": "alice", "obj": "data1", "act": "read"},
+	}, beta.Spec)
+	require.Equal(t, alpha.UID, beta.UID)
+
+	g := &v1beta1.Rule{}
+	require.NoError(t, c.convertHubToSpoke(ctx, namedRule("g", "g", "alice", "admin"), g))
+	require.Equal(t, map[string]string{"user": "alice", "role": "admin"}, g.Spec.Fields)
+
+	// v1beta1 -> v1alpha1 resolves the named fields and keeps the description
+	beta = &v1beta1.Rule{
+		ObjectMeta: metav1.ObjectMeta{Name: "bob", Namespace: DefaultNamespace},
+		Spec: v1beta1.RuleSpec{
+			PType:       "p",
+			Fields:      map[string]string{"sub": "bob", "act": "write", "obj": "data2"},
+			Description: "bob writes data2",
+		},
+	}
+	alpha = &v1alpha1.Rule{}
+	require.NoError(t, c.convertSpokeToHub(ctx, beta, alpha))
+	require.Equal(t, v1alpha1.RuleSpec{PType: "p", V0: "bob", V1: "data2", V2: "write"}, alpha.Spec)
+	require.Equal(t, "bob writes data2", alpha.Annotations[v1beta1.DescriptionAnnotation])
+	require.Empty(t, beta.Spec.Values)
+
+	// round trip
+	roundTrip := &v1beta1.Rule{}
+	require.NoError(t, c.convertHubToSpoke(ctx, alpha, roundTrip))
+	beta.Spec.Values = []string{"bob", "data2", "write"}
+	require.Equal(t, beta, roundTrip)
+
+	tests := []struct {
+		name    string
+		spec    v1beta1.RuleSpec
+		wantErr string
+	}{
+		{name: "values and fields", spec: v1beta1.RuleSpec{PType: "p", Values: []string{"bob"}, Fields: map[string]string{"obj": "data2"}}},
+		{name: "unknown field", spec: v1beta1.RuleSpec{PType: "p", Fields: map[string]string{"subject": "bob"}}, wantErr: `unknown field "subject" of ptype p`},
+		{name: "conflict", spec: v1beta1.RuleSpec{PType: "p", Values: []string{"bob"}, Fields: map[string]string{"sub": "alice"}}, wantErr: `field "sub" conflicts with value 0 "bob"`},
+		{name: "unknown ptype", spec: v1beta1.RuleSpec{PType: "p2", Fields: map[string]string{"sub": "bob"}}, wantErr: "ptype p2 has no field names"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.convertSpokeToHub(ctx, &v1beta1.Rule{Spec: tc.spec}, &v1alpha1.Rule{})
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_RuleConvertible(t *testing.T) {
+	beta := &v1beta1.Rule{
+		ObjectMeta: metav1.ObjectMeta{Name: "r", Namespace: DefaultNamespace, Annotations: map[string]string{"a": "b"}},
+		Spec: v1beta1.RuleSpec{
+			PType:       "g",
+			Values:      []string{"alice", "admin"},
+			Fields:      map[string]string{"user": "alice"},
+			Description: "alice is an admin",
+		},
+		Status: v1beta1.RuleStatus{ObservedGeneration: 2, Conditions: []metav1.Condition{{Type: v1beta1.ConditionTypeReady, Status: metav1.ConditionTrue}}},
+	}
+	alpha := &v1alpha1.Rule{}
+	require.NoError(t, beta.ConvertTo(alpha))
+	require.Equal(t, v1alpha1.RuleSpec{PType: "g", V0: "alice", V1: "admin"}, alpha.Spec)
+	require.Equal(t, map[string]string{
+		"a":                           "b",
+		v1beta1.DescriptionAnnotation: "alice is an admin",
+		v1beta1.FieldsAnnotation:      `{"user":"alice"}`,
+	}, alpha.Annotations)
+	require.Equal(t, int64(2), alpha.Status.ObservedGeneration)
+
+	roundTrip := &v1beta1.Rule{}
+	require.NoError(t, roundTrip.ConvertFrom(alpha))
+	require.Equal(t, beta, roundTrip)
+
+	require.ErrorContains(t, (&v1beta1.Rule{Spec: v1beta1.RuleSpec{PType: "p", Values: make([]string, 7)}}).ConvertTo(alpha), "at most 6")
+}
+
+func newTestRuleConverter(t testing.TB) *RuleConverter {
+	t.Helper()
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	require.NoError(t, err)
+	c, err := NewRuleConverter(&RuleConverterConfig{
+		Model:      m,
+		FieldNames: map[string][]string{"g": {"user", "role"}},
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func Test_RuleConverterRoundTrip(t *testing.T) {
+	c := newTestRuleConverter(t)
+	ctx := context.Background()
+
+	described := namedRule("described", "p", "alice", "data1", "read")
+	described.Annotations = map[string]string{v1beta1.DescriptionAnnotation: "alice reads data1"}
+	partial := namedRule("partial", "p", "alice", "data1", "read")
+	partial.Annotations = map[string]string{v1beta1.FieldsAnnotation: `{"sub":"alice"}`}
+
+	for _, hub := range []*v1alpha1.Rule{
+		namedRule("alice", "p", "alice", "data1", "read"),
+		namedRule("admin", "g", "alice", "admin"),
+		namedRule("unnamed", "g2", "alice", "admin"),
+		namedRule("gap", "p", "alice", "", "read"),
+		described,
+		partial,
+	} {
+		t.Run(hub.Name, func(t *testing.T) {
+			spoke := &v1beta1.Rule{}
+			require.NoError(t, c.convertHubToSpoke(ctx, hub, spoke))
+			roundTrip := &v1alpha1.Rule{}
+			require.NoError(t, c.convertSpokeToHub(ctx, spoke, roundTrip))
+			require.Equal(t, hub, roundTrip, "the stored Rule does not change")
+		})
+	}
+}
+
+func FuzzRuleConverterRoundTrip(f *testing.F) {
+	f.Add("p", "alice", "data1", "read", "", "")
+	f.Add("p", "", "data1", "", "sub", "alice")
+	f.Add("p", "alice", "", "", "act", "read")
+	f.Add("g", "alice", "admin", "", "role", "admin")
+	f.Add("g2", "alice", "admin", "", "", "")
+	c := newTestRuleConverter(f)
+	ctx := context.Background()
+
+	f.Fuzz(func(t *testing.T, ptype, v0, v1, v2, field, value string) {
+		for _, s := range []string{ptype, v0, v1, v2, field, value} {
+			if !utf8.ValidString(s) {
+				t.Skip("the Kubernetes objects are valid UTF-8")
+			}
+		}
+		spoke := &v1beta1.Rule{
+			ObjectMeta: metav1.ObjectMeta{Name: "r", Namespace: DefaultNamespace},
+			Spec:       v1beta1.RuleSpec{PType: ptype, Values: []string{v0, v1, v2}},
+		}
+		if field != "" {
+			spoke.Spec.Fields = map[string]string{field: value}
+		}
+		hub := &v1alpha1.Rule{}
+		if err := c.convertSpokeToHub(ctx, spoke, hub); err != nil {
+			return
+		}
+		// the stored Rule is stable: hub -> spoke -> hub is the identity
+		spoke = &v1beta1.Rule{}
+		require.NoError(t, c.convertHubToSpoke(ctx, hub, spoke))
+		roundTrip := &v1alpha1.Rule{}
+		require.NoError(t, c.convertSpokeToHub(ctx, spoke, roundTrip))
+		require.Equal(t, hub, roundTrip)
+
+		// and so is the served Rule: spoke -> hub -> spoke is the identity once converted
+		spokeRoundTrip := &v1beta1.Rule{}
+		require.NoError(t, c.convertHubToSpoke(ctx, roundTrip, spokeRoundTrip))
+		require.Equal(t, spoke, spokeRoundTrip)
+	})
+}
@@ -0,0 +1,203 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const DefaultRuleGarbageCollectorName = "casbin-rule-gc"
+
+type RuleGarbageCollectorConfig struct {
+	// Namespace of the collected Rules, all namespaces if empty.
+	Namespace string
+	// Labels selecting the collected Rules.
+	Labels map[string]string
+	// Orphaned deletes the Rules linked by the v1alpha1.ModelLabel label to a Model which does not exist.
+	Orphaned bool
+	// OrphanedGracePeriod keeps an orphaned Rule for the duration after its creation, e.g. while its Model is being applied.
+	OrphanedGracePeriod time.Duration
+	// InvalidRetention deletes the Rules whose Ready condition is False for longer than the duration, 0 keeps the invalid Rules.
+	// The Ready condition is set by the RuleStatusReconciler.
+	InvalidRetention time.Duration
+	// Model revalidates the invalid Rules before they are deleted if set, a Ready condition set for another model or mode is ignored.
+	Model model.Model
+	// NamespaceAsDomain validates the Rules with their namespace injected as the domain, see KubeConfig.NamespaceAsDomain.
+	NamespaceAsDomain bool
+}
+
+// RuleGarbageCollector deletes the orphaned Rules and the Rules which stay invalid for the model.
+type RuleGarbageCollector struct {
+	client              client.Client
+	namespace           string
+	labels              map[string]string
+	orphaned            bool
+	orphanedGracePeriod time.Duration
+	invalidRetention    time.Duration
+	model               model.Model
+	namespaceAsDomain   bool
+	clock               clock.PassiveClock
+}
+
+func NewRuleGarbageCollector(c client.Client, config *RuleGarbageCollectorConfig) (*RuleGarbageCollector, error) {
+	if c == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.OrphanedGracePeriod < 0 {
+		return nil, errors.New("orphaned grace period cannot be negative")
+	}
+	if config.InvalidRetention < 0 {
+		return nil, errors.New("invalid retention cannot be negative")
+	}
+	return &RuleGarbageCollector{
+		client:              c,
+		namespace:           config.Namespace,
+		labels:              config.Labels,
+		orphaned:            config.Orphaned,
+		orphanedGracePeriod: config.OrphanedGracePeriod,
+		invalidRetention:    config.InvalidRetention,
+		model:               config.Model,
+		namespaceAsDomain:   config.NamespaceAsDomain,
+		clock:               clock.RealClock{},
+	}, nil
+}
+
+// SetupWithManager watches the Rules and, for the orphaned Rules, the Models.
+// The status changes are not filtered out as the Ready condition of a Rule is part of its status.
+func (r *RuleGarbageCollector) SetupWithManager(mgr ctrl.Manager) error {
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Rule{}, builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return matchesRule(obj, r.namespace, r.labels)
+			}),
+		)).
+		Named(DefaultRuleGarbageCollectorName)
+	if r.orphaned {
+		b = b.Watches(&v1alpha1.Model{}, handler.EnqueueRequestsFromMapFunc(r.modelRules), builder.WithPredicates(
+			predicate.Funcs{
+				CreateFunc: func(_ event.CreateEvent) bool { return false },
+				UpdateFunc: func(_ event.UpdateEvent) bool { return false },
+			},
+		))
+	}
+	return b.Complete(r)
+}
+
+// modelRules returns the Rules linked to the deleted Model.
+func (r *RuleGarbageCollector) modelRules(ctx context.Context, obj client.Object) []reconcile.Request {
+	if r.namespace != "" && obj.GetNamespace() != r.namespace {
+		return nil
+	}
+	l := &v1alpha1.RuleList{}
+	err := r.client.List(ctx, l, client.InNamespace(obj.GetNamespace()),
+		client.MatchingLabels(mergeLabels(r.labels, map[string]string{v1alpha1.ModelLabel: obj.GetName()})))
+	if err != nil {
+		zlog.Errorf("list rules of model %s/%s err: %v", obj.GetNamespace(), obj.GetName(), err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(l.Items))
+	for i := range l.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&l.Items[i])})
+	}
+	return requests
+}
+
+func (r *RuleGarbageCollector) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	rule := &v1alpha1.Rule{}
+	if err := r.client.Get(ctx, req.NamespacedName, rule); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !rule.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	now := r.clock.Now()
+	var requeueAfter time.Duration
+	if r.orphaned {
+		wait, orphaned, err := r.orphanedWait(ctx, rule, now)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if orphaned {
+			if wait == 0 {
+				return ctrl.Result{}, r.delete(ctx, rule, "orphaned")
+			}
+			requeueAfter = wait
+		}
+	}
+	if r.invalidRetention > 0 {
+		if wait, ok := r.invalidWait(rule, now); ok {
+			if wait == 0 {
+				return ctrl.Result{}, r.delete(ctx, rule, "invalid")
+			}
+			requeueAfter = minRequeue(requeueAfter, wait)
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// orphanedWait returns the time until the orphaned Rule is collected and true if the linked Model does not exist.
+func (r *RuleGarbageCollector) orphanedWait(ctx context.Context, rule *v1alpha1.Rule, now time.Time) (time.Duration, bool, error) {
+	name := rule.Labels[v1alpha1.ModelLabel]
+	if name == "" {
+		return 0, false, nil
+	}
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: rule.Namespace, Name: name}, &v1alpha1.Model{})
+	if err == nil {
+		return 0, false, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return 0, false, fmt.Errorf("get model err: %w", err)
+	}
+	return max(rule.CreationTimestamp.Add(r.orphanedGracePeriod).Sub(now), 0), true, nil
+}
+
+// invalidWait returns the time until the invalid Rule is collected and true if the Rule is invalid for its generation.
+func (r *RuleGarbageCollector) invalidWait(rule *v1alpha1.Rule, now time.Time) (time.Duration, bool) {
+	cond := meta.FindStatusCondition(rule.Status.Conditions, v1alpha1.ConditionTypeReady)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.ObservedGeneration != rule.Generation {
+		return 0, false
+	}
+	if r.model != nil && ruleReadyCondition(r.model, rule, r.namespaceAsDomain).Status != metav1.ConditionFalse {
+		// the condition was set for another model or mode, the RuleStatusReconciler updates it
+		return 0, false
+	}
+	return max(cond.LastTransitionTime.Add(r.invalidRetention).Sub(now), 0), true
+}
+
+func (r *RuleGarbageCollector) delete(ctx context.Context, rule *v1alpha1.Rule, reason string) error {
+	// the preconditions protect a rule which was fixed meanwhile
+	uid, resourceVersion := rule.UID, rule.ResourceVersion
+	err := r.client.Delete(ctx, rule, client.Preconditions{UID: &uid, ResourceVersion: &resourceVersion})
+	if err = client.IgnoreNotFound(err); err != nil {
+		return fmt.Errorf("delete %s rule err: %w", reason, err)
+	}
+	zlog.Infof("deleted %s rule %s/%s", reason, rule.Namespace, rule.Name)
+	return nil
+}
+
+// minRequeue returns the earlier positive requeue duration.
+func minRequeue(a, b time.Duration) time.Duration {
+	if a <= 0 {
+		return b
+	}
+	return min(a, b)
+}
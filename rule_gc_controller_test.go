@@ -0,0 +1,89 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_RuleGarbageCollector(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	linked := func(name, modelName string, created time.Time) *v1alpha1.Rule {
+		r := namedRule(name, "p", name, "data1", "read")
+		r.Labels = map[string]string{v1alpha1.ModelLabel: modelName}
+		r.CreationTimestamp = metav1.Time{Time: created}
+		return r
+	}
+	invalid := func(name string, since time.Time) *v1alpha1.Rule {
+		r := namedRule(name, "p", name, "data1")
+		r.Generation = 2
+		r.Status.Conditions = []metav1.Condition{{
+			Type:               v1alpha1.ConditionTypeReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: 2,
+			Reason:             v1alpha1.ReasonInvalid,
+			LastTransitionTime: metav1.Time{Time: since},
+		}}
+		return r
+	}
+	rbac := &v1alpha1.Model{ObjectMeta: metav1.ObjectMeta{Name: "rbac", Namespace: DefaultNamespace}}
+
+	orphaned := linked("orphaned", "deleted", now.Add(-time.Hour))
+	young := linked("young", "deleted", now.Add(-10*time.Second))
+	owned := linked("owned", "rbac", now.Add(-time.Hour))
+	invalidOld := invalid("invalid-old", now.Add(-2*time.Hour))
+	invalidNew := invalid("invalid-new", now.Add(-30*time.Minute))
+	invalidStale := invalid("invalid-stale", now.Add(-2*time.Hour))
+	invalidStale.Generation = 3 // the rule was changed after the validation
+	valid := namedRule("valid", "p", "alice", "data1", "read")
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(rbac, orphaned, young, owned, invalidOld, invalidNew, invalidStale, valid).
+		Build()
+	r, err := NewRuleGarbageCollector(c, &RuleGarbageCollectorConfig{Orphaned: true, OrphanedGracePeriod: time.Minute, InvalidRetention: time.Hour})
+	require.NoError(t, err)
+	r.clock = clocktesting.NewFakePassiveClock(now)
+
+	ctx := context.Background()
+	tests := []struct {
+		rule         *v1alpha1.Rule
+		requeueAfter time.Duration
+		deleted      bool
+	}{
+		{rule: orphaned, deleted: true},
+		{rule: young, requeueAfter: 50 * time.Second},
+		{rule: owned},
+		{rule: invalidOld, deleted: true},
+		{rule: invalidNew, requeueAfter: 30 * time.Minute},
+		{rule: invalidStale},
+		{rule: valid},
+	}
+	for _, tc := range tests {
+		t.Run(tc.rule.Name, func(t *testing.T) {
+			key := client.ObjectKeyFromObject(tc.rule)
+			res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			require.NoError(t, err)
+			require.Equal(t, tc.requeueAfter, res.RequeueAfter)
+			err = c.Get(ctx, key, &v1alpha1.Rule{})
+			if tc.deleted {
+				require.True(t, apierrors.IsNotFound(err))
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+	require.Len(t, r.modelRules(ctx, &v1alpha1.Model{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: DefaultNamespace}}), 1)
+
+	_, err = NewRuleGarbageCollector(c, &RuleGarbageCollectorConfig{InvalidRetention: -time.Second})
+	require.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package casbinkube
+
+import (
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// lessRule orders the Rules or ClusterRules by spec.priority, then by the creation timestamp and the resource version.
+func lessRule(a, b client.Object) bool {
+	if pa, pb := rulePriority(a), rulePriority(b); pa != pb {
+		return pa < pb
+	}
+	ta, tb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !ta.Equal(&tb) {
+		return ta.Before(&tb)
+	}
+	if c := compareResourceVersion(a.GetResourceVersion(), b.GetResourceVersion()); c != 0 {
+		return c < 0
+	}
+	return lessName(a, b)
+}
+
+// lessName orders the objects by namespace and name.
+func lessName(a, b client.Object) bool {
+	if a.GetNamespace() != b.GetNamespace() {
+		return a.GetNamespace() < b.GetNamespace()
+	}
+	return a.GetName() < b.GetName()
+}
+
+func rulePriority(obj client.Object) int32 {
+	if spec, ok := ruleSpec(obj); ok {
+		return spec.Priority
+	}
+	return 0
+}
+
+// compareResourceVersion compares the resource versions as numbers, the API server uses decimal etcd revisions.
+func compareResourceVersion(a, b string) int {
+	switch {
+	case len(a) != len(b):
+		return len(a) - len(b)
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// lineOrder is the position of a policy line in the load order of the Adapter.
+type lineOrder struct {
+	// group loads the ClusterRules before or after the Rules, the RuleSets, the Roles and the RBAC bindings.
+	group    int
+	priority int32
+}
+
+func (o lineOrder) less(other lineOrder) bool {
+	if o.group != other.group {
+		return o.group < other.group
+	}
+	return o.priority < other.priority
+}
+
+// orderOf returns the load order of the lines of a Rule, RuleSet, Role, RBAC binding or ClusterRule.
+func orderOf(obj client.Object, precedence ClusterRulePrecedence) lineOrder {
+	switch r := obj.(type) {
+	case *v1alpha1.Rule:
+		return lineOrder{priority: r.Spec.Priority}
+	case *v1alpha1.RuleSet, *v1alpha1.Role, *rbacv1.RoleBinding, *rbacv1.ClusterRoleBinding:
+		return lineOrder{group: 1}
+	case *v1alpha1.ClusterRule:
+		if precedence == ClusterRulesLast {
+			return lineOrder{group: 2, priority: r.Spec.Priority}
+		}
+		return lineOrder{group: -1, priority: r.Spec.Priority}
+	default:
+		return lineOrder{}
+	}
+}
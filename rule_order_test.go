@@ -0,0 +1,139 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const priorityEffectModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[policy_effect]
+e = priority(p.eft) || deny
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`
+
+func priorityRule(name string, priority int32, vals ...string) *v1alpha1.Rule {
+	r := namedRule(name, "p", vals...)
+	r.Spec.Priority = priority
+	return r
+}
+
+func newPriorityEnforcer(t *testing.T) *casbin.SyncedEnforcer {
+	t.Helper()
+	m, err := model.NewModelFromString(priorityEffectModel)
+	require.NoError(t, err)
+	e, err := casbin.NewSyncedEnforcer(m)
+	require.NoError(t, err)
+	return e
+}
+
+func Test_LessRule(t *testing.T) {
+	now := metav1.Now()
+	older := metav1.NewTime(now.Add(-1))
+	a := namedRule("a", "p", "alice", "data1", "read")
+	b := namedRule("b", "p", "bob", "data1", "read")
+	a.CreationTimestamp, b.CreationTimestamp = now, now
+	a.ResourceVersion, b.ResourceVersion = "10", "9"
+	require.True(t, lessRule(b, a))
+
+	a.CreationTimestamp = older
+	require.True(t, lessRule(a, b))
+
+	b.Spec.Priority = -1
+	require.True(t, lessRule(b, a))
+}
+
+func Test_AdapterRulePriority(t *testing.T) {
+	a := newTestAdapter(t, KubeConfig{},
+		priorityRule("allow", 10, "alice", "data1", "read", "allow"),
+		priorityRule("deny", 1, "alice", "data1", "read", "deny"),
+		namedRule("bob", "p", "bob", "data1", "read", "allow"),
+	)
+	e := newPriorityEnforcer(t)
+	e.SetAdapter(a)
+	require.NoError(t, e.LoadPolicy())
+
+	policies, err := e.GetPolicy()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"bob", "data1", "read", "allow"},
+		{"alice", "data1", "read", "deny"},
+		{"alice", "data1", "read", "allow"},
+	}, policies)
+	ok, err := e.Enforce("alice", "data1", "read")
+	requireFalse(t, ok, err)
+
+	// SavePolicy keeps the existing Rules with their priority
+	require.NoError(t, e.SavePolicy())
+	require.NoError(t, e.LoadPolicy())
+	reloaded, err := e.GetPolicy()
+	require.NoError(t, err)
+	require.Equal(t, policies, reloaded)
+	rules, err := a.store.GetStoredRules(context.Background())
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	for _, rule := range rules {
+		require.Equal(t, map[string]int32{"allow": 10, "deny": 1, "bob": 0}[rule.Name], rule.Spec.Priority)
+	}
+}
+
+func Test_InformerRulePriority(t *testing.T) {
+	e := newPriorityEnforcer(t)
+	w, err := NewInformer(&InformerConfig{KubeConfig: KubeConfig{ClusterRulePrecedence: ClusterRulesLast}}, e)
+	require.NoError(t, err)
+	h := w.ruleEventHandler()
+	requirePolicy := func(expected ...[]string) {
+		t.Helper()
+		policies, err := e.GetPolicy()
+		require.NoError(t, err)
+		require.Equal(t, expected, policies)
+	}
+	allow := []string{"alice", "data1", "read", "allow"}
+	deny := []string{"alice", "data1", "read", "deny"}
+	bob := []string{"bob", "data1", "read", "allow"}
+	admin := []string{"admin", "data1", "read", "allow"}
+
+	allowRule := priorityRule("allow", 10, allow...)
+	denyRule := priorityRule("deny", 1, deny...)
+	h.OnAdd(allowRule, false)
+	h.OnAdd(&v1alpha1.ClusterRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "admin"},
+		Spec:       v1alpha1.RuleSpec{PType: "p", V0: "admin", V1: "data1", V2: "read", V3: "allow", Priority: -5},
+	}, false)
+	h.OnAdd(denyRule, false)
+	h.OnAdd(priorityRule("bob", 1, bob...), false)
+	requirePolicy(deny, bob, allow, admin)
+	ok, err := e.Enforce("alice", "data1", "read")
+	requireFalse(t, ok, err)
+
+	// the order is kept although casbin moves the last line into the gap of the removed one
+	h.OnDelete(denyRule)
+	requirePolicy(bob, allow, admin)
+	ok, err = e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+
+	// the lines with the same priority are kept in the order they were added
+	h.OnAdd(denyRule, false)
+	requirePolicy(bob, deny, allow, admin)
+
+	// the priority update moves the line
+	updated := allowRule.DeepCopy()
+	updated.Spec.Priority = 0
+	h.OnUpdate(allowRule, updated)
+	requirePolicy(allow, bob, deny, admin)
+	ok, err = e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+}
@@ -0,0 +1,381 @@
+package casbinkube
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+)
+
+const (
+	// maxScheduleWindows bounds the windows evaluated for one transition, e.g. for overlapping windows covering a long time.
+	maxScheduleWindows = 1000
+	// maxCronSearch bounds the search of the next cron time, e.g. for "0 0 30 2 *" which never matches.
+	maxCronSearch = 5 * 366 * 24 * time.Hour
+	// scheduleCacheSize bounds the parsed schedules kept by scheduleCache, the cache is cleared when it is full.
+	scheduleCacheSize = 1024
+)
+
+var weekdays = map[string]time.Weekday{
+	"Sun": time.Sunday, "Mon": time.Monday, "Tue": time.Tuesday, "Wed": time.Wednesday, "Thu": time.Thursday, "Fri": time.Friday, "Sat": time.Saturday,
+}
+
+// ruleSchedule is the parsed RuleSchedule.
+type ruleSchedule struct {
+	loc      *time.Location
+	cron     *cronSchedule
+	duration time.Duration
+	windows  []weeklyWindow
+	// maxLen is the longest window, windows covering a time start at most maxLen before it
+	maxLen time.Duration
+}
+
+type weeklyWindow struct {
+	days  [7]bool
+	start int // minutes of the day
+	end   int
+}
+
+func parseRuleSchedule(s *v1alpha1.RuleSchedule) (*ruleSchedule, error) {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", s.TimeZone, err)
+	}
+	rs := &ruleSchedule{loc: loc}
+	switch {
+	case s.Cron != "" && len(s.Windows) != 0:
+		return nil, errors.New("cron and windows are mutually exclusive")
+	case s.Cron != "":
+		if s.Duration == nil || s.Duration.Duration <= 0 {
+			return nil, errors.New("duration must be positive")
+		}
+		if rs.cron, err = parseCron(s.Cron); err != nil {
+			return nil, fmt.Errorf("invalid cron %q: %w", s.Cron, err)
+		}
+		rs.duration = s.Duration.Duration
+		rs.maxLen = rs.duration
+	case len(s.Windows) != 0:
+		for i, w := range s.Windows {
+			ww, err := parseWeeklyWindow(w)
+			if err != nil {
+				return nil, fmt.Errorf("invalid window %d: %w", i, err)
+			}
+			rs.windows = append(rs.windows, ww)
+		}
+		// a window ending on the next day lasts at most 24h, plus a DST hour
+		rs.maxLen = 25 * time.Hour
+	default:
+		return nil, errors.New("either cron or windows must be set")
+	}
+	return rs, nil
+}
+
+// scheduleCache keeps the parsed schedules by their spec, the schedules are evaluated on every informer event and timer.
+var scheduleCache = &ruleScheduleCache{}
+
+type ruleScheduleCache struct {
+	mu      sync.Mutex
+	entries map[string]parsedSchedule
+}
+
+type parsedSchedule struct {
+	schedule *ruleSchedule
+	err      error
+}
+
+// get returns the parsed schedule, the schedule is parsed once per spec and time zone. A ruleSchedule is not modified after parsing.
+func (c *ruleScheduleCache) get(s *v1alpha1.RuleSchedule) (*ruleSchedule, error) {
+	key := scheduleKey(s)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.entries[key]; ok {
+		return p.schedule, p.err
+	}
+	if c.entries == nil || len(c.entries) >= scheduleCacheSize {
+		c.entries = make(map[string]parsedSchedule)
+	}
+	rs, err := parseRuleSchedule(s)
+	c.entries[key] = parsedSchedule{schedule: rs, err: err}
+	return rs, err
+}
+
+func scheduleKey(s *v1alpha1.RuleSchedule) string {
+	var b strings.Builder
+	b.WriteString(s.TimeZone)
+	b.WriteString("|")
+	b.WriteString(s.Cron)
+	b.WriteString("|")
+	if s.Duration != nil {
+		b.WriteString(s.Duration.Duration.String())
+	}
+	for _, w := range s.Windows {
+		b.WriteString("|")
+		b.WriteString(strings.Join(w.Days, ","))
+		b.WriteString(" ")
+		b.WriteString(w.Start)
+		b.WriteString("-")
+		b.WriteString(w.End)
+	}
+	return b.String()
+}
+
+func parseWeeklyWindow(w v1alpha1.TimeWindow) (weeklyWindow, error) {
+	var ww weeklyWindow
+	var err error
+	if ww.start, err = parseClock(w.Start); err != nil {
+		return ww, err
+	}
+	if ww.end, err = parseClock(w.End); err != nil {
+		return ww, err
+	}
+	if len(w.Days) == 0 {
+		ww.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, day := range w.Days {
+		wd, ok := weekdays[day]
+		if !ok {
+			return ww, fmt.Errorf("unknown weekday %q", day)
+		}
+		ww.days[wd] = true
+	}
+	return ww, nil
+}
+
+// parseClock returns the minutes of the day of HH:MM.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// evaluate reports whether the time is in a window and returns the time at which this changes, zero if never.
+// Adjacent and overlapping windows are joined.
+func (s *ruleSchedule) evaluate(now time.Time) (bool, time.Time) {
+	// end of the windows covering now, zero if now is not covered
+	var covered time.Time
+	t := now.Add(-s.maxLen)
+	for range maxScheduleWindows {
+		start, end, ok := s.next(t)
+		if !ok {
+			break
+		}
+		t = start
+		if start.After(now) {
+			if covered.IsZero() {
+				return false, start
+			}
+			if start.After(covered) {
+				return true, covered
+			}
+		}
+		if end.After(now) && end.After(covered) {
+			covered = end
+		}
+	}
+	// the windows are re-evaluated at the end of the last one
+	return !covered.IsZero(), covered
+}
+
+// next returns the first window starting after the time, the longest one if several start at the same time.
+func (s *ruleSchedule) next(t time.Time) (time.Time, time.Time, bool) {
+	if s.cron != nil {
+		start, ok := s.cron.next(t.In(s.loc))
+		return start, start.Add(s.duration), ok
+	}
+	local := t.In(s.loc)
+	var start, end time.Time
+	// the window starting on the day before can start after t in the DST fall back hour
+	for day := -1; day <= 7; day++ {
+		date := time.Date(local.Year(), local.Month(), local.Day()+day, 0, 0, 0, 0, s.loc)
+		for _, w := range s.windows {
+			if !w.days[date.Weekday()] {
+				continue
+			}
+			ws := time.Date(date.Year(), date.Month(), date.Day(), 0, w.start, 0, 0, s.loc)
+			if !ws.After(t) {
+				continue
+			}
+			endDay := date.Day()
+			if w.end <= w.start {
+				endDay++
+			}
+			we := time.Date(date.Year(), date.Month(), endDay, 0, w.end, 0, 0, s.loc)
+			if start.IsZero() || ws.Before(start) || (ws.Equal(start) && we.After(end)) {
+				start, end = ws, we
+			}
+		}
+	}
+	return start, end, !start.IsZero()
+}
+
+// cronSchedule is a standard 5-field cron expression: minute hour day-of-month month day-of-week.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// day-of-month and day-of-week restricted both match either of them, like cron does
+	domStar, dowStar bool
+	// a fixed hour skipped by the DST spring forward runs after the change, a repeated one runs once, like cron does
+	hourStar bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	cronMonths   = []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronWeekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+func parseCron(expr string) (*cronSchedule, error) {
+	if d, ok := cronDescriptors[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = d
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	c := &cronSchedule{
+		hourStar: strings.HasPrefix(fields[1], "*"),
+		domStar:  strings.HasPrefix(fields[2], "*"),
+		dowStar:  strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7, cronWeekdays); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	// 7 is Sunday as well
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// parseCronField returns the bit set of a comma separated list of *, values and ranges with an optional /step.
+func parseCronField(field string, minValue, maxValue int, names []string) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(field, ",") {
+		expr, stepExpr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepExpr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepExpr)
+			}
+		}
+		low, high := minValue, maxValue
+		if expr != "*" {
+			lowExpr, highExpr, isRange := strings.Cut(expr, "-")
+			var err error
+			if low, err = parseCronValue(lowExpr, minValue, maxValue, names); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = parseCronValue(highExpr, minValue, maxValue, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				high = maxValue
+			}
+			if high < low {
+				return 0, fmt.Errorf("invalid range %q", expr)
+			}
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, minValue, maxValue int, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < minValue || v > maxValue {
+		return 0, fmt.Errorf("invalid value %q, expected %d-%d", s, minValue, maxValue)
+	}
+	return v, nil
+}
+
+// next returns the first time after t matching the expression in the location of t.
+func (c *cronSchedule) next(t time.Time) (time.Time, bool) {
+	loc := t.Location()
+	limit := t.Add(maxCronSearch)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			// the hours are stepped in absolute time, the wall clock skips or repeats an hour at the DST changes
+			next := t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+			if c.skippedHour(t, next) {
+				return next, true
+			}
+			t = next
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			next := t.Add(time.Minute)
+			if c.skippedHour(t, next) {
+				return next, true
+			}
+			t = next
+		case !c.hourStar && t.Add(-time.Hour).Hour() == t.Hour():
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// skippedHour reports whether the wall clock skips a fixed hour of the expression between the times, e.g. 02:00 at the DST spring forward.
+func (c *cronSchedule) skippedHour(from, to time.Time) bool {
+	if c.hourStar || to.Day() != from.Day() {
+		return false
+	}
+	for h := from.Hour() + 1; h < to.Hour(); h++ {
+		if c.hour&(1<<uint(h)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
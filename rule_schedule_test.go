@@ -0,0 +1,264 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func Test_parseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		from    time.Time
+		want    time.Time
+		wantErr string
+	}{
+		{expr: "0 22 * * Sat", from: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC), want: time.Date(2026, 10, 17, 22, 0, 0, 0, time.UTC)},
+		{expr: "*/15 9-17 * * 1-5", from: time.Date(2026, 10, 16, 17, 50, 0, 0, time.UTC), want: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{expr: "30 2 1,15 * *", from: time.Date(2026, 10, 1, 2, 30, 0, 0, time.UTC), want: time.Date(2026, 10, 15, 2, 30, 0, 0, time.UTC)},
+		{expr: "0 0 13 * 5", from: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), want: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * Feb 7", from: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), want: time.Date(2027, 2, 7, 0, 0, 0, 0, time.UTC)},
+		{expr: "@monthly", from: time.Date(2026, 12, 14, 0, 0, 0, 0, time.UTC), want: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 30 2 *", from: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 22 * *", wantErr: "expected 5 fields"},
+		{expr: "60 * * * *", wantErr: "minute: invalid value"},
+		{expr: "0 * * * 1-Foo", wantErr: "day of week: invalid value"},
+		{expr: "*/0 * * * *", wantErr: "invalid step"},
+		{expr: "0 17-9 * * *", wantErr: "invalid range"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			c, err := parseCron(tc.expr)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			next, ok := c.next(tc.from)
+			require.Equal(t, !tc.want.IsZero(), ok)
+			require.Equal(t, tc.want, next)
+		})
+	}
+}
+
+func Test_parseCronDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	// 2026-03-29 02:00 CET springs forward to 03:00 CEST, 2026-10-25 03:00 CEST falls back to 02:00 CET
+	at := func(hour, minute int, month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC).In(berlin)
+	}
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{name: "skipped hour runs after the spring forward", expr: "30 2 * * *", from: at(12, 0, time.March, 28), want: at(1, 0, time.March, 29)},
+		{name: "skipped hour in a list", expr: "30 1,2 * * *", from: at(0, 30, time.March, 29), want: at(1, 0, time.March, 29)},
+		{name: "hourly over the spring forward", expr: "0 * * * *", from: at(0, 30, time.March, 29), want: at(1, 0, time.March, 29)},
+		{name: "hour after the spring forward", expr: "30 3 * * *", from: at(0, 30, time.March, 29), want: at(1, 30, time.March, 29)},
+		{name: "first occurrence of the repeated hour", expr: "30 2 * * *", from: at(22, 0, time.October, 24), want: at(0, 30, time.October, 25)},
+		{name: "fixed hour is not repeated", expr: "30 2 * * *", from: at(0, 30, time.October, 25), want: at(1, 30, time.October, 26)},
+		{name: "hourly before the fall back", expr: "0 * * * *", from: at(23, 30, time.October, 24), want: at(0, 0, time.October, 25)},
+		{name: "hourly in the repeated hour", expr: "0 * * * *", from: at(0, 0, time.October, 25), want: at(1, 0, time.October, 25)},
+		{name: "hourly after the repeated hour", expr: "0 * * * *", from: at(1, 0, time.October, 25), want: at(2, 0, time.October, 25)},
+		{name: "stepped hours in the repeated hour", expr: "15 */2 * * *", from: at(0, 15, time.October, 25), want: at(1, 15, time.October, 25)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := parseCron(tc.expr)
+			require.NoError(t, err)
+			next, ok := c.next(tc.from)
+			require.True(t, ok)
+			require.True(t, tc.want.Equal(next), "next %v, want %v", next, tc.want)
+		})
+	}
+}
+
+func Test_parseCronDayMatching(t *testing.T) {
+	from := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC) // Wednesday
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{name: "day of month only", expr: "0 0 1 * *", want: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "day of week only", expr: "0 0 * * Sun", want: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{name: "either day of month", expr: "0 0 15 * Mon", want: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "or day of week", expr: "0 0 1 * Mon", want: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{name: "Sunday as 7", expr: "0 0 31 * 7", want: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{name: "stepped day of month and day of week", expr: "0 0 */10 * Mon", want: time.Date(2026, 12, 21, 0, 0, 0, 0, time.UTC)},
+		{name: "day of month and stepped day of week", expr: "0 0 13 * */3", want: time.Date(2026, 12, 13, 0, 0, 0, 0, time.UTC)},
+		{name: "last day of short months", expr: "0 0 31 * *", want: time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 0 29 2 *", want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := parseCron(tc.expr)
+			require.NoError(t, err)
+			next, ok := c.next(from)
+			require.True(t, ok)
+			require.Equal(t, tc.want, next)
+		})
+	}
+}
+
+func Test_scheduleCache(t *testing.T) {
+	schedule := &v1alpha1.RuleSchedule{TimeZone: "Europe/Berlin", Cron: "0 22 * * Sat", Duration: &metav1.Duration{Duration: 4 * time.Hour}}
+	s1, err := scheduleCache.get(schedule)
+	require.NoError(t, err)
+	s2, err := scheduleCache.get(schedule.DeepCopy())
+	require.NoError(t, err)
+	require.Same(t, s1, s2, "the schedule is parsed once")
+
+	other := schedule.DeepCopy()
+	other.TimeZone = "UTC"
+	s3, err := scheduleCache.get(other)
+	require.NoError(t, err)
+	require.NotSame(t, s1, s3)
+
+	_, err = scheduleCache.get(&v1alpha1.RuleSchedule{TimeZone: "Mars/Olympus", Cron: "0 22 * * Sat", Duration: schedule.Duration})
+	require.ErrorContains(t, err, "invalid time zone")
+}
+
+func Test_ruleScheduleEvaluate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	businessHours := &v1alpha1.RuleSchedule{
+		TimeZone: "Europe/Berlin",
+		Windows:  []v1alpha1.TimeWindow{{Days: []string{"Mon", "Tue", "Wed", "Thu", "Fri"}, Start: "09:00", End: "17:00"}},
+	}
+	overnight := &v1alpha1.RuleSchedule{
+		Windows: []v1alpha1.TimeWindow{
+			{Days: []string{"Sat"}, Start: "22:00", End: "02:00"},
+			{Days: []string{"Sun"}, Start: "02:00", End: "04:00"},
+		},
+	}
+	maintenance := &v1alpha1.RuleSchedule{Cron: "0 22 * * Sat", Duration: &metav1.Duration{Duration: 4 * time.Hour}}
+
+	tests := []struct {
+		name       string
+		schedule   *v1alpha1.RuleSchedule
+		now        time.Time
+		wantActive bool
+		wantNext   time.Time
+	}{
+		{name: "before business hours", schedule: businessHours, now: time.Date(2026, 10, 16, 8, 0, 0, 0, berlin), wantNext: time.Date(2026, 10, 16, 9, 0, 0, 0, berlin)},
+		{name: "in business hours", schedule: businessHours, now: time.Date(2026, 10, 16, 9, 0, 0, 0, berlin), wantActive: true, wantNext: time.Date(2026, 10, 16, 17, 0, 0, 0, berlin)},
+		{name: "weekend", schedule: businessHours, now: time.Date(2026, 10, 16, 17, 0, 0, 0, berlin), wantNext: time.Date(2026, 10, 19, 9, 0, 0, 0, berlin)},
+		{name: "after the DST change", schedule: businessHours, now: time.Date(2026, 10, 23, 18, 0, 0, 0, berlin), wantNext: time.Date(2026, 10, 26, 9, 0, 0, 0, berlin)},
+		{name: "adjacent windows are joined", schedule: overnight, now: time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC), wantActive: true, wantNext: time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC)},
+		{name: "window of the previous day", schedule: overnight, now: time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC), wantActive: true, wantNext: time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC)},
+		{name: "cron window", schedule: maintenance, now: time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC), wantActive: true, wantNext: time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC)},
+		{name: "next cron window", schedule: maintenance, now: time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC), wantNext: time.Date(2026, 10, 24, 22, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := parseRuleSchedule(tc.schedule)
+			require.NoError(t, err)
+			active, next := s.evaluate(tc.now)
+			require.Equal(t, tc.wantActive, active)
+			require.True(t, tc.wantNext.Equal(next), "next %v, want %v", next, tc.wantNext)
+		})
+	}
+
+	for _, invalid := range []*v1alpha1.RuleSchedule{
+		{TimeZone: "Mars/Olympus", Windows: businessHours.Windows},
+		{Cron: "0 22 * * Sat"},
+		{Windows: []v1alpha1.TimeWindow{{Days: []string{"Caturday"}, Start: "09:00", End: "17:00"}}},
+		{Windows: []v1alpha1.TimeWindow{{Start: "9am", End: "17:00"}}},
+		{},
+	} {
+		_, err := parseRuleSchedule(invalid)
+		require.Error(t, err)
+		active, next := scheduleState(invalid, time.Now())
+		require.False(t, active, "an invalid schedule is never active")
+		require.True(t, next.IsZero())
+	}
+}
+
+func Test_InformerScheduledRule(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC) // Friday
+	fakeClock := clocktesting.NewFakeClock(now)
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	ch := make(chan PolicyEvent, 10)
+	w, err := NewInformer(&InformerConfig{
+		PolicyEventHandlers: []PolicyEventHandler{PolicyEventChannel(ch)},
+		Clock:               asyncFakeClock{fakeClock},
+	}, e)
+	require.NoError(t, err)
+	defer w.Close()
+	h := w.ruleEventHandler()
+
+	support := namedRule("support", "p", "alice", "data1", "write")
+	support.Spec.Schedule = &v1alpha1.RuleSchedule{
+		Windows: []v1alpha1.TimeWindow{{Days: []string{"Fri"}, Start: "09:00", End: "17:00"}},
+	}
+	support.Spec.ExpiresAt = &metav1.Time{Time: now.Add(7*24*time.Hour + 3*time.Hour)}
+	h.OnAdd(support, true)
+	require.Empty(t, ch)
+
+	step := func(d time.Duration, want PolicyEventType) {
+		t.Helper()
+		fakeClock.Step(d)
+		select {
+		case event := <-ch:
+			require.Equal(t, want, event.Type)
+			require.Equal(t, "support", event.Name)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for the policy event")
+		}
+	}
+	step(time.Hour, PolicyAdded)
+	ok, err := e.Enforce("alice", "data1", "write")
+	requireTrue(t, ok, err)
+
+	step(8*time.Hour, PolicyRemoved)
+	ok, err = e.Enforce("alice", "data1", "write")
+	requireFalse(t, ok, err)
+
+	// the next window is cut by the expiry
+	step(7*24*time.Hour-8*time.Hour, PolicyAdded)
+	step(2*time.Hour, PolicyRemoved)
+	w.ruleMu.Lock()
+	require.Empty(t, w.timers, "expired")
+	w.ruleMu.Unlock()
+}
+
+func Test_AdapterSavePolicyKeepsScheduledRules(t *testing.T) {
+	businessHours := &v1alpha1.RuleSchedule{
+		Windows: []v1alpha1.TimeWindow{{Days: []string{"Mon", "Tue", "Wed", "Thu", "Fri"}, Start: "09:00", End: "17:00"}},
+	}
+	weekend := &v1alpha1.RuleSchedule{
+		Windows: []v1alpha1.TimeWindow{{Days: []string{"Sat", "Sun"}, Start: "00:00", End: "00:00"}},
+	}
+	support := namedRule("support", "p", "alice", "data1", "write")
+	support.Spec.Schedule = businessHours
+	oncall := namedRule("oncall", "p", "bob", "data1", "write")
+	oncall.Spec.Schedule = weekend
+	a := newTestAdapter(t, KubeConfig{}, support, oncall)
+	a.store.clock = clocktesting.NewFakePassiveClock(time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)) // Friday
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+	ok, err := e.Enforce("alice", "data1", "write")
+	requireTrue(t, ok, err)
+	require.NoError(t, e.SavePolicy())
+
+	rules, err := a.store.GetStoredRules(context.Background())
+	require.NoError(t, err)
+	require.Len(t, rules, 2, "the Rule outside of its window is not deleted")
+	for _, rule := range rules {
+		require.NotNil(t, rule.Spec.Schedule, "rule %s keeps its schedule", rule.Name)
+	}
+}
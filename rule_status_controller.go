@@ -0,0 +1,123 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const DefaultRuleStatusControllerName = "casbin-rule-status"
+
+type RuleStatusReconcilerConfig struct {
+	// Model used to validate the Rules.
+	Model model.Model
+	// Namespace of the reconciled Rules, all namespaces if empty.
+	Namespace string
+	// Labels selecting the reconciled Rules.
+	Labels map[string]string
+	// NamespaceAsDomain validates the Rules with their namespace injected as the domain, see KubeConfig.NamespaceAsDomain.
+	NamespaceAsDomain bool
+}
+
+// RuleStatusReconciler sets the Ready condition of the Rules validated against the model.
+type RuleStatusReconciler struct {
+	client            client.Client
+	model             model.Model
+	namespace         string
+	labels            map[string]string
+	namespaceAsDomain bool
+}
+
+func NewRuleStatusReconciler(c client.Client, config *RuleStatusReconcilerConfig) (*RuleStatusReconciler, error) {
+	if c == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.Model == nil {
+		return nil, errors.New("model cannot be nil")
+	}
+	return &RuleStatusReconciler{
+		client:            c,
+		model:             config.Model,
+		namespace:         config.Namespace,
+		labels:            config.Labels,
+		namespaceAsDomain: config.NamespaceAsDomain,
+	}, nil
+}
+
+func (r *RuleStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Rule{}, builder.WithPredicates(
+			predicate.GenerationChangedPredicate{},
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return matchesRule(obj, r.namespace, r.labels)
+			}),
+		)).
+		Named(DefaultRuleStatusControllerName).
+		Complete(r)
+}
+
+func (r *RuleStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	rule := &v1alpha1.Rule{}
+	if err := r.client.Get(ctx, req.NamespacedName, rule); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !rule.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	base := rule.DeepCopy()
+	changed := meta.SetStatusCondition(&rule.Status.Conditions, ruleReadyCondition(r.model, rule, r.namespaceAsDomain))
+	if rule.Status.ObservedGeneration != rule.Generation {
+		rule.Status.ObservedGeneration = rule.Generation
+		changed = true
+	}
+	if !changed {
+		return ctrl.Result{}, nil
+	}
+	if err := client.IgnoreNotFound(r.client.Status().Patch(ctx, rule, client.MergeFrom(base))); err != nil {
+		return ctrl.Result{}, fmt.Errorf("patch rule status err: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+func ruleReadyCondition(m model.Model, rule *v1alpha1.Rule, namespaceAsDomain bool) metav1.Condition {
+	cond := metav1.Condition{
+		Type:               v1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: rule.Generation,
+		Reason:             v1alpha1.ReasonAccepted,
+		Message:            "Rule is valid for the model",
+	}
+	if err := validateRuleLine(m, fromRule(rule), rule.Namespace, namespaceAsDomain); err != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = v1alpha1.ReasonInvalid
+		cond.Message = err.Error()
+	} else if rule.Spec.Schedule != nil {
+		if _, err := parseRuleSchedule(rule.Spec.Schedule); err != nil {
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = v1alpha1.ReasonInvalid
+			cond.Message = "invalid schedule: " + err.Error()
+		}
+	}
+	return cond
+}
+
+// matchesRule returns true if the object is in the namespace (any if empty) and matches the labels.
+func matchesRule(obj client.Object, namespace string, lbls map[string]string) bool {
+	if namespace != "" && obj.GetNamespace() != namespace {
+		return false
+	}
+	return labels.SelectorFromSet(lbls).Matches(labels.Set(obj.GetLabels()))
+}
@@ -0,0 +1,72 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_RuleStatusReconciler(t *testing.T) {
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	require.NoError(t, err)
+
+	valid := namedRule("valid", "p", "alice", "data1", "read")
+	valid.Generation = 2
+	invalid := namedRule("invalid", "p2", "alice", "data1", "read")
+	invalid.Generation = 1
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(valid, invalid).
+		WithStatusSubresource(&v1alpha1.Rule{}).
+		Build()
+	r, err := NewRuleStatusReconciler(c, &RuleStatusReconcilerConfig{Model: m})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, name := range []string{"valid", "invalid", "missing"} {
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Namespace: DefaultNamespace, Name: name}})
+		require.NoError(t, err)
+	}
+
+	got := &v1alpha1.Rule{}
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(valid), got))
+	require.Equal(t, int64(2), got.Status.ObservedGeneration)
+	cond := meta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeReady)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionTrue, cond.Status)
+	require.Equal(t, v1alpha1.ReasonAccepted, cond.Reason)
+	require.Equal(t, int64(2), cond.ObservedGeneration)
+
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(invalid), got))
+	require.Equal(t, int64(1), got.Status.ObservedGeneration)
+	cond = meta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeReady)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, v1alpha1.ReasonInvalid, cond.Reason)
+	require.Contains(t, cond.Message, "missing required definition p2")
+
+	// no change, no update
+	rv := got.ResourceVersion
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(invalid)})
+	require.NoError(t, err)
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(invalid), got))
+	require.Equal(t, rv, got.ResourceVersion)
+}
+
+func Test_matchesRule(t *testing.T) {
+	r := namedRule("r", "p", "alice")
+	r.Labels = map[string]string{"app": "a"}
+
+	require.True(t, matchesRule(r, "", nil))
+	require.True(t, matchesRule(r, DefaultNamespace, map[string]string{"app": "a"}))
+	require.False(t, matchesRule(r, "other", nil))
+	require.False(t, matchesRule(r, "", map[string]string{"app": "b"}))
+}
@@ -0,0 +1,139 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-casbin-grepplabs-com-v1alpha1-rule,mutating=false,failurePolicy=fail,sideEffects=None,groups=casbin.grepplabs.com,resources=rules,verbs=create;update,versions=v1alpha1,name=vrule-v1alpha1.casbin.grepplabs.com,admissionReviewVersions=v1
+
+type RuleValidatorConfig struct {
+	// Model used to validate ptype and the number of fields.
+	Model model.Model
+	// FieldPatterns restricts the values by ptype and field index, e.g. {"p": {2: "^(read|write)$"}}.
+	FieldPatterns map[string]map[int]string
+	// RequireExistingRoles rejects grouping rules (g, g2, ...) whose role (v1) is not the subject (v0) of another Rule in the namespace.
+	RequireExistingRoles bool
+	// NamespaceAsDomain validates the Rules with their namespace injected as the domain, see KubeConfig.NamespaceAsDomain.
+	NamespaceAsDomain bool
+}
+
+// RuleValidator is a validating admission webhook for Rules.
+type RuleValidator struct {
+	reader               client.Reader
+	model                model.Model
+	fieldPatterns        map[string]map[int]*regexp.Regexp
+	requireExistingRoles bool
+	namespaceAsDomain    bool
+}
+
+var _ admission.Validator[*v1alpha1.Rule] = (*RuleValidator)(nil)
+
+// NewRuleValidator creates the validator. The reader is used to look up the roles and must support the spec.v0 field selector.
+func NewRuleValidator(reader client.Reader, config *RuleValidatorConfig) (*RuleValidator, error) {
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.Model == nil {
+		return nil, errors.New("model cannot be nil")
+	}
+	if config.RequireExistingRoles && reader == nil {
+		return nil, errors.New("reader cannot be nil if existing roles are required")
+	}
+	patterns := make(map[string]map[int]*regexp.Regexp, len(config.FieldPatterns))
+	for ptype, fields := range config.FieldPatterns {
+		patterns[ptype] = make(map[int]*regexp.Regexp, len(fields))
+		for index, pattern := range fields {
+			if index < 0 || index > 5 {
+				return nil, fmt.Errorf("field index %d of ptype %s is out of range", index, ptype)
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("field pattern v%d of ptype %s: %w", index, ptype, err)
+			}
+			patterns[ptype][index] = re
+		}
+	}
+	return &RuleValidator{
+		reader:               reader,
+		model:                config.Model,
+		fieldPatterns:        patterns,
+		requireExistingRoles: config.RequireExistingRoles,
+		namespaceAsDomain:    config.NamespaceAsDomain,
+	}, nil
+}
+
+// SetupWebhookWithManager registers the webhook. The roles are looked up without the cache.
+func (v *RuleValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if v.reader == nil {
+		v.reader = mgr.GetAPIReader()
+	}
+	return ctrl.NewWebhookManagedBy(mgr, &v1alpha1.Rule{}).
+		WithValidator(v).
+		Complete()
+}
+
+func (v *RuleValidator) ValidateCreate(ctx context.Context, rule *v1alpha1.Rule) (admission.Warnings, error) {
+	return nil, v.validate(ctx, rule)
+}
+
+func (v *RuleValidator) ValidateUpdate(ctx context.Context, _, rule *v1alpha1.Rule) (admission.Warnings, error) {
+	return nil, v.validate(ctx, rule)
+}
+
+func (v *RuleValidator) ValidateDelete(_ context.Context, _ *v1alpha1.Rule) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *RuleValidator) validate(ctx context.Context, rule *v1alpha1.Rule) error {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	line := fromRule(rule)
+	if err := validateRuleLine(v.model, line, rule.Namespace, v.namespaceAsDomain); err != nil {
+		errs = append(errs, field.Invalid(specPath, line, err.Error()))
+	}
+	values := []string{line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
+	for index, re := range v.fieldPatterns[line.PType] {
+		if !re.MatchString(values[index]) {
+			errs = append(errs, field.Invalid(specPath.Child(fmt.Sprintf("v%d", index)), values[index], "must match "+re.String()))
+		}
+	}
+	if rule.Spec.Schedule != nil {
+		if _, err := parseRuleSchedule(rule.Spec.Schedule); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("schedule"), rule.Spec.Schedule, err.Error()))
+		}
+	}
+	if len(errs) == 0 && v.requireExistingRoles && strings.HasPrefix(line.PType, "g") {
+		exists, err := v.roleExists(ctx, rule.Namespace, line.V1)
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		if !exists {
+			errs = append(errs, field.NotFound(specPath.Child("v1"), line.V1))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(v1alpha1.GroupVersion.WithKind("Rule").GroupKind(), rule.Name, errs)
+}
+
+func (v *RuleValidator) roleExists(ctx context.Context, namespace string, role string) (bool, error) {
+	l := &v1alpha1.RuleList{}
+	err := v.reader.List(ctx, l, client.InNamespace(namespace), client.MatchingFields{"spec.v0": role}, client.Limit(1))
+	if err != nil {
+		return false, fmt.Errorf("list rules err: %w", err)
+	}
+	return len(l.Items) > 0, nil
+}
@@ -0,0 +1,111 @@
+package casbinkube
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/grepplabs/loggo/zlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// startEnvtest starts a local control plane with the CRDs and webhooks installed, see make test-envtest.
+func startEnvtest(t *testing.T) *envtest.Environment {
+	t.Helper()
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+	ctrl.SetLogger(zlog.Logger)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("config", "crds")},
+		ErrorIfCRDPathMissing: true,
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("config", "webhook")},
+		},
+	}
+	_, err := env.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, env.Stop())
+	})
+	return env
+}
+
+func startWebhookManager(t *testing.T, env *envtest.Environment, setup func(mgr ctrl.Manager) error) {
+	t.Helper()
+	opts := env.WebhookInstallOptions
+	mgr, err := ctrl.NewManager(env.Config, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    opts.LocalServingHost,
+			Port:    opts.LocalServingPort,
+			CertDir: opts.LocalServingCertDir,
+		}),
+	})
+	require.NoError(t, err)
+	require.NoError(t, setup(mgr))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		assert.NoError(t, mgr.Start(ctx))
+	}()
+	addr := net.JoinHostPort(opts.LocalServingHost, fmt.Sprint(opts.LocalServingPort))
+	require.Eventually(t, func() bool {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, 10*time.Second, 100*time.Millisecond, "webhook server started")
+}
+
+func TestEnvtestRuleValidator(t *testing.T) {
+	env := startEnvtest(t)
+
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	require.NoError(t, err)
+	startWebhookManager(t, env, func(mgr ctrl.Manager) error {
+		v, err := NewRuleValidator(nil, &RuleValidatorConfig{Model: m, RequireExistingRoles: true})
+		if err != nil {
+			return err
+		}
+		return v.SetupWebhookWithManager(mgr)
+	})
+
+	c, err := client.New(env.Config, client.Options{Scheme: scheme})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	newRule := func(name string, line CasbinRule) *v1alpha1.Rule {
+		r := toRule(DefaultNamespace, line)
+		r.Name = name
+		return &r
+	}
+	require.NoError(t, c.Create(ctx, newRule("valid", CasbinRule{PType: "p", V0: "admin", V1: "data1", V2: "read"})))
+	require.NoError(t, c.Create(ctx, newRule("member", CasbinRule{PType: "g", V0: "alice", V1: "admin"})))
+
+	err = c.Create(ctx, newRule("unknown-ptype", CasbinRule{PType: "p2", V0: "alice", V1: "data1", V2: "read"}))
+	require.True(t, apierrors.IsInvalid(err), err)
+	err = c.Create(ctx, newRule("wrong-arity", CasbinRule{PType: "p", V0: "alice", V1: "data1"}))
+	require.True(t, apierrors.IsInvalid(err), err)
+	err = c.Create(ctx, newRule("unknown-role", CasbinRule{PType: "g", V0: "alice", V1: "nobody"}))
+	require.True(t, apierrors.IsInvalid(err), err)
+}
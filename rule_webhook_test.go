@@ -0,0 +1,75 @@
+package casbinkube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_RuleValidator(t *testing.T) {
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	require.NoError(t, err)
+
+	admin := namedRule("admin", "p", "admin", "data1", "read")
+	reader := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(admin).
+		WithIndex(&v1alpha1.Rule{}, "spec.v0", func(obj client.Object) []string {
+			return []string{obj.(*v1alpha1.Rule).Spec.V0} //nolint:forcetypeassert
+		}).
+		Build()
+
+	v, err := NewRuleValidator(reader, &RuleValidatorConfig{
+		Model:                m,
+		FieldPatterns:        map[string]map[int]string{"p": {2: "^(read|write)$"}},
+		RequireExistingRoles: true,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		rule    *v1alpha1.Rule
+		wantErr string
+	}{
+		{name: "valid p", rule: namedRule("r", "p", "alice", "data1", "read")},
+		{name: "valid g", rule: namedRule("r", "g", "alice", "admin")},
+		{name: "unknown ptype", rule: namedRule("r", "p2", "alice", "data1", "read"), wantErr: "missing required definition p2"},
+		{name: "wrong arity", rule: namedRule("r", "p", "alice", "data1"), wantErr: "invalid policy rule size"},
+		{name: "disallowed value", rule: namedRule("r", "p", "alice", "data1", "delete"), wantErr: "spec.v2"},
+		{name: "unknown role", rule: namedRule("r", "g", "alice", "nobody"), wantErr: "spec.v1: Not found"},
+		{name: "invalid schedule", rule: scheduledRule(&v1alpha1.RuleSchedule{TimeZone: "Mars/Olympus", Cron: "0 9 * * *"}), wantErr: "spec.schedule"},
+	}
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := v.ValidateCreate(ctx, tc.rule)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.wantErr)
+			require.True(t, apierrors.IsInvalid(err))
+
+			_, err = v.ValidateUpdate(ctx, tc.rule, tc.rule)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+	_, err = v.ValidateDelete(ctx, namedRule("r", "p2"))
+	require.NoError(t, err)
+
+	_, err = NewRuleValidator(nil, &RuleValidatorConfig{Model: m, FieldPatterns: map[string]map[int]string{"p": {6: ".*"}}})
+	require.Error(t, err)
+	_, err = NewRuleValidator(nil, &RuleValidatorConfig{Model: m, FieldPatterns: map[string]map[int]string{"p": {0: "("}}})
+	require.Error(t, err)
+}
+
+func scheduledRule(schedule *v1alpha1.RuleSchedule) *v1alpha1.Rule {
+	r := namedRule("r", "p", "alice", "data1", "read")
+	r.Spec.Schedule = schedule
+	return r
+}
@@ -0,0 +1,116 @@
+package casbinkube
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	KindRule    = "Rule"
+	KindRuleSet = "RuleSet"
+
+	// DefaultRuleSetShardSize keeps a RuleSet well below the etcd object size limit for typical policy lines.
+	DefaultRuleSetShardSize = 1000
+)
+
+func objectKind(obj client.Object) string {
+	switch obj.(type) {
+	case *v1alpha1.RuleSet:
+		return KindRuleSet
+	case *v1alpha1.ClusterRule:
+		return KindClusterRule
+	case *v1alpha1.Role:
+		return KindRole
+	case *rbacv1.RoleBinding:
+		return KindRoleBinding
+	case *rbacv1.ClusterRoleBinding:
+		return KindClusterRoleBinding
+	default:
+		return KindRule
+	}
+}
+
+// multiLine reports whether the objects of the kind provide several policy lines.
+func multiLine(kind string) bool {
+	return kind == KindRuleSet || kind == KindRole || kind == KindRoleBinding || kind == KindClusterRoleBinding
+}
+
+func fromPolicyLine(l v1alpha1.PolicyLine) CasbinRule {
+	return CasbinRule{
+		PType: l.PType,
+		V0:    l.V0,
+		V1:    l.V1,
+		V2:    l.V2,
+		V3:    l.V3,
+		V4:    l.V4,
+		V5:    l.V5,
+	}
+}
+
+func toPolicyLine(cr CasbinRule) v1alpha1.PolicyLine {
+	return v1alpha1.PolicyLine{
+		PType: cr.PType,
+		V0:    cr.V0,
+		V1:    cr.V1,
+		V2:    cr.V2,
+		V3:    cr.V3,
+		V4:    cr.V4,
+		V5:    cr.V5,
+	}
+}
+
+// lineString formats the line like a line of a policy CSV file.
+func lineString(line CasbinRule) string {
+	_, ptype, rule := lineToPolicyParams(line)
+	return strings.Join(append([]string{ptype}, rule...), ", ")
+}
+
+// ruleSetLines returns the policy lines of the RuleSet in order without duplicates.
+func ruleSetLines(rs *v1alpha1.RuleSet) []CasbinRule {
+	seen := make(map[string]struct{}, len(rs.Spec.Rules))
+	lines := make([]CasbinRule, 0, len(rs.Spec.Rules))
+	for _, l := range rs.Spec.Rules {
+		line := fromPolicyLine(l)
+		key := keyFor(line)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ShardRuleSets splits the policy lines into RuleSets named <name>-<index> with at most shardSize lines each.
+// The shards are labeled with v1alpha1.RuleSetLabel, so the shards left over after the policy shrank can be found and deleted.
+func ShardRuleSets(name string, namespace string, lines []CasbinRule, shardSize int) []v1alpha1.RuleSet {
+	if shardSize <= 0 {
+		shardSize = DefaultRuleSetShardSize
+	}
+	ruleSets := make([]v1alpha1.RuleSet, 0, (len(lines)+shardSize-1)/shardSize)
+	for start := 0; start < len(lines); start += shardSize {
+		end := min(start+shardSize, len(lines))
+		rules := make([]v1alpha1.PolicyLine, 0, end-start)
+		for _, line := range lines[start:end] {
+			rules = append(rules, toPolicyLine(line))
+		}
+		ruleSets = append(ruleSets, v1alpha1.RuleSet{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       KindRuleSet,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d", name, len(ruleSets)),
+				Namespace: namespace,
+				Labels:    map[string]string{v1alpha1.RuleSetLabel: name},
+			},
+			Spec: v1alpha1.RuleSetSpec{Rules: rules},
+		})
+	}
+	return ruleSets
+}
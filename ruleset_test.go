@@ -0,0 +1,265 @@
+package casbinkube
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/grepplabs/casbin-kube/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_ShardRuleSets(t *testing.T) {
+	lines := []CasbinRule{
+		{PType: "p", V0: "alice", V1: "data1", V2: "read"},
+		{PType: "p", V0: "bob", V1: "data2", V2: "write"},
+		{PType: "g", V0: "alice", V1: "admin"},
+	}
+	ruleSets := ShardRuleSets("policy", "ns1", lines, 2)
+	require.Len(t, ruleSets, 2)
+	require.Equal(t, "policy-0", ruleSets[0].Name)
+	require.Equal(t, "policy-1", ruleSets[1].Name)
+	require.Equal(t, "ns1", ruleSets[1].Namespace)
+	require.Equal(t, map[string]string{v1alpha1.RuleSetLabel: "policy"}, ruleSets[1].Labels)
+	require.Equal(t, lines[:2], ruleSetLines(&ruleSets[0]))
+	require.Equal(t, lines[2:], ruleSetLines(&ruleSets[1]))
+
+	require.Empty(t, ShardRuleSets("policy", "ns1", nil, 0))
+	require.Len(t, ShardRuleSets("policy", "ns1", lines, 0), 1)
+}
+
+func Test_InformerRuleSet(t *testing.T) {
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	ch := make(chan PolicyEvent, 10)
+	w, err := NewInformer(&InformerConfig{
+		KubeConfig:          KubeConfig{RuleSets: true},
+		PolicyEventHandlers: []PolicyEventHandler{PolicyEventChannel(ch)},
+	}, e)
+	require.NoError(t, err)
+	h := w.ruleSetEventHandler()
+
+	rs := namedRuleSet("set", "p, alice, data1, read", "p, bob, data2, write", "p, alice, data1, read", "p2, carol, data1, read")
+	h.OnAdd(rs, true)
+	requireEvents(t, ch, 2)
+	event := <-ch
+	require.Equal(t, KindRuleSet, event.Kind)
+	require.Equal(t, "set", event.Name)
+	require.True(t, event.InitialList)
+	<-ch
+
+	ok, err := e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("bob", "data2", "write")
+	requireTrue(t, ok, err)
+	quarantined := w.QuarantinedRules()
+	require.Len(t, quarantined, 1)
+	require.Equal(t, KindRuleSet, quarantined[0].Kind)
+	require.Equal(t, "carol", quarantined[0].Rule.V0)
+
+	// bob is provided by a Rule as well
+	w.ruleEventHandler().OnAdd(namedRule("bob", "p", "bob", "data2", "write"), false)
+	require.Empty(t, ch)
+
+	updated := namedRuleSet("set", "p, alice, data1, read", "p, dave, data3, read")
+	h.OnUpdate(rs, updated)
+	event = <-ch
+	require.Equal(t, PolicyAdded, event.Type)
+	require.Equal(t, "dave", event.Rule.V0)
+	require.Empty(t, ch)
+	require.Empty(t, w.QuarantinedRules())
+
+	ok, err = e.Enforce("bob", "data2", "write")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("dave", "data3", "read")
+	requireTrue(t, ok, err)
+
+	h.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/set", Obj: updated})
+	requireEvents(t, ch, 2)
+	policies, err := e.GetPolicy()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"bob", "data2", "write"}}, policies)
+}
+
+func Test_InformerRuleSetRetriesQuarantinedLines(t *testing.T) {
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	w, err := NewInformer(&InformerConfig{KubeConfig: KubeConfig{RuleSets: true}}, e)
+	require.NoError(t, err)
+	h := w.ruleSetEventHandler()
+
+	rs := namedRuleSet("set", "p, alice, data1, read")
+	h.OnAdd(rs, false)
+	ok, err := e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+
+	// e.g. the enforcer failed to add the line
+	h.OnDelete(rs)
+	w.reject(rs, fromRule(rule("p", "alice", "data1", "read")), errors.New("add policy err"))
+	require.Len(t, w.QuarantinedRules(), 1)
+
+	// the unchanged line is retried on the next update
+	updated := namedRuleSet("set", "p, alice, data1, read", "p, bob, data2, write")
+	h.OnUpdate(rs, updated)
+	require.Empty(t, w.QuarantinedRules())
+	ok, err = e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("bob", "data2", "write")
+	requireTrue(t, ok, err)
+}
+
+func Test_AdapterLoadRuleSets(t *testing.T) {
+	a := newTestAdapter(t, KubeConfig{RuleSets: true},
+		namedRule("bob", "p", "bob", "data2", "write"),
+		namedRuleSet("set", "p, alice, data1, read", "p, bob, data2, write", "p, carol, data1"),
+	)
+	a.tolerant = true
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+
+	policies, err := e.GetPolicy()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"bob", "data2", "write"}, {"alice", "data1", "read"}}, policies)
+	quarantined := a.QuarantinedRules()
+	require.Len(t, quarantined, 1)
+	require.Equal(t, "set", quarantined[0].Name)
+	require.Equal(t, "carol", quarantined[0].Rule.V0)
+
+	// RuleSets are not read unless enabled
+	a.store.ruleSetClient = nil
+	require.NoError(t, a.LoadPolicyCtx(context.Background(), e.GetModel()))
+	require.Empty(t, a.QuarantinedRules())
+}
+
+func newTestAdapter(t *testing.T, kubeConfig KubeConfig, objs ...client.Object) *Adapter {
+	t.Helper()
+
+	for _, obj := range objs {
+		obj.SetLabels(mergeLabels(obj.GetLabels(), kubeConfig.Labels))
+	}
+	if kubeConfig.Namespace == "" {
+		kubeConfig.Namespace = DefaultNamespace
+	}
+	builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...)
+	// the filtered removals select the Rules by their spec fields
+	for _, field := range []string{"spec.ptype", "spec.v0", "spec.v1", "spec.v2", "spec.v3", "spec.v4", "spec.v5"} {
+		builder = builder.WithIndex(&v1alpha1.Rule{}, field, func(obj client.Object) []string {
+			return []string{patternFields(fromRule(obj.(*v1alpha1.Rule)))[field]} //nolint:forcetypeassert
+		})
+	}
+	c := builder.Build()
+	a := &Adapter{
+		clusterRulePrecedence: kubeConfig.ClusterRulePrecedence,
+		namespaceAsDomain:     kubeConfig.NamespaceAsDomain,
+		store: &k8sAdapter{
+			clock:      clock.RealClock{},
+			namespaces: kubeConfig.ruleNamespaces(),
+			k8sClient: &k8sClient[*v1alpha1.Rule, *v1alpha1.RuleList]{
+				New:       func() *v1alpha1.Rule { return &v1alpha1.Rule{} },
+				NewList:   func() *v1alpha1.RuleList { return &v1alpha1.RuleList{} },
+				Client:    c,
+				Namespace: kubeConfig.Namespace,
+				Labels:    kubeConfig.Labels,
+			},
+		},
+	}
+	if kubeConfig.RuleSets {
+		a.store.ruleSetClient = &k8sClient[*v1alpha1.RuleSet, *v1alpha1.RuleSetList]{
+			New:       func() *v1alpha1.RuleSet { return &v1alpha1.RuleSet{} },
+			NewList:   func() *v1alpha1.RuleSetList { return &v1alpha1.RuleSetList{} },
+			Client:    c,
+			Namespace: kubeConfig.Namespace,
+			Labels:    kubeConfig.Labels,
+		}
+	}
+	if kubeConfig.Roles {
+		a.store.roleClient = &k8sClient[*v1alpha1.Role, *v1alpha1.RoleList]{
+			New:       func() *v1alpha1.Role { return &v1alpha1.Role{} },
+			NewList:   func() *v1alpha1.RoleList { return &v1alpha1.RoleList{} },
+			Client:    c,
+			Namespace: kubeConfig.Namespace,
+			Labels:    kubeConfig.Labels,
+		}
+	}
+	if kubeConfig.ClusterRules {
+		a.store.clusterRuleClient = &k8sClient[*v1alpha1.ClusterRule, *v1alpha1.ClusterRuleList]{
+			New:     func() *v1alpha1.ClusterRule { return &v1alpha1.ClusterRule{} },
+			NewList: func() *v1alpha1.ClusterRuleList { return &v1alpha1.ClusterRuleList{} },
+			Client:  c,
+			Labels:  kubeConfig.Labels,
+		}
+	}
+	if kubeConfig.RBAC.Enabled {
+		a.store.rbac = kubeConfig.RBAC
+		a.store.roleBindingClient = &k8sClient[*rbacv1.RoleBinding, *rbacv1.RoleBindingList]{
+			New:     func() *rbacv1.RoleBinding { return &rbacv1.RoleBinding{} },
+			NewList: func() *rbacv1.RoleBindingList { return &rbacv1.RoleBindingList{} },
+			Client:  c,
+			Labels:  kubeConfig.RBAC.Labels,
+		}
+		a.store.clusterRoleBindingClient = &k8sClient[*rbacv1.ClusterRoleBinding, *rbacv1.ClusterRoleBindingList]{
+			New:     func() *rbacv1.ClusterRoleBinding { return &rbacv1.ClusterRoleBinding{} },
+			NewList: func() *rbacv1.ClusterRoleBindingList { return &rbacv1.ClusterRoleBindingList{} },
+			Client:  c,
+			Labels:  kubeConfig.RBAC.Labels,
+		}
+	}
+	return a
+}
+
+func namedRuleSet(name string, lines ...string) *v1alpha1.RuleSet {
+	rs := &v1alpha1.RuleSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: DefaultNamespace,
+			UID:       types.UID("uid-" + name),
+		},
+	}
+	for _, line := range lines {
+		fields := strings.Split(line, ", ")
+		r := rule(fields[0], fields[1:]...)
+		rs.Spec.Rules = append(rs.Spec.Rules, toPolicyLine(fromRule(r)))
+	}
+	return rs
+}
+
+func Test_AdapterSavePolicySkipsRuleSetLines(t *testing.T) {
+	a := newTestAdapter(t, KubeConfig{RuleSets: true, Roles: true, ClusterRules: true},
+		namedRule("bob", "p", "bob", "data2", "write"),
+		namedRuleSet("set", "p, alice, data1, read"),
+		&v1alpha1.ClusterRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "admin"},
+			Spec:       v1alpha1.RuleSpec{PType: "p", V0: "admin", V1: "data1", V2: "write"},
+		},
+	)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+	policies, err := e.GetPolicy()
+	require.NoError(t, err)
+	require.Len(t, policies, 3)
+
+	e.EnableAutoSave(false)
+	_, err = e.AddPolicy("carol", "data1", "read")
+	require.NoError(t, err)
+	require.NoError(t, e.SavePolicy())
+
+	rules, err := a.store.GetStoredRules(context.Background())
+	require.NoError(t, err)
+	var lines []CasbinRule
+	for i := range rules {
+		lines = append(lines, fromRule(&rules[i]))
+	}
+	require.ElementsMatch(t, []CasbinRule{
+		{PType: "p", V0: "bob", V1: "data2", V2: "write"},
+		{PType: "p", V0: "carol", V1: "data1", V2: "read"},
+	}, lines, "the RuleSet and ClusterRule lines are not copied into Rules")
+}
@@ -0,0 +1,164 @@
+package casbinkube
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+)
+
+const (
+	DefaultSnapshotSyncTimeout = 30 * time.Second
+	DefaultSnapshotInterval    = time.Minute
+)
+
+// SnapshotConfig configures an optional local copy of the policy used when the API server cannot be reached.
+type SnapshotConfig struct {
+	// Path of the snapshot file. Snapshots are disabled if empty.
+	Path string
+	// SyncTimeout is the time the informer waits for the initial sync before it seeds the enforcer from the snapshot.
+	// Ignored by the adapter. Defaults to DefaultSnapshotSyncTimeout.
+	SyncTimeout time.Duration
+	// Interval is the minimal period between snapshot writes of the informer. Ignored by the adapter.
+	// Defaults to DefaultSnapshotInterval.
+	Interval time.Duration
+}
+
+func (c SnapshotConfig) enabled() bool {
+	return c.Path != ""
+}
+
+func (c SnapshotConfig) syncTimeout() time.Duration {
+	if c.SyncTimeout <= 0 {
+		return DefaultSnapshotSyncTimeout
+	}
+	return c.SyncTimeout
+}
+
+func (c SnapshotConfig) interval() time.Duration {
+	if c.Interval <= 0 {
+		return DefaultSnapshotInterval
+	}
+	return c.Interval
+}
+
+type snapshot struct {
+	Time  time.Time    `json:"time"`
+	Rules []CasbinRule `json:"rules"`
+}
+
+func readSnapshot(path string) (*snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot %s: %w", path, err)
+	}
+	s := &snapshot{}
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parse snapshot %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// writeSnapshot replaces the snapshot atomically using a temporary file in the same directory.
+func writeSnapshot(path string, s *snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("create snapshot dir %s: %w", dir, err)
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create snapshot temp file: %w", err)
+	}
+	tmp := f.Name()
+	if err = writeAndClose(f, data); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+	return nil
+}
+
+func writeAndClose(f *os.File, data []byte) error {
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+	return nil
+}
+
+// staleState reports whether the enforcer serves policies loaded from a snapshot.
+type staleState struct {
+	mu    sync.RWMutex
+	stale bool
+	since time.Time
+}
+
+func (s *staleState) set(since time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stale = true
+	s.since = since
+}
+
+func (s *staleState) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stale = false
+	s.since = time.Time{}
+}
+
+func (s *staleState) get() (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.since, s.stale
+}
+
+// enforcerPolicyLines returns the policy lines of the enforcer sorted by ptype.
+func enforcerPolicyLines(e casbin.IEnforcer) ([]CasbinRule, error) {
+	m := e.GetModel()
+	var lines []CasbinRule
+	for _, sec := range []string{"p", "g"} {
+		ptypes := make([]string, 0, len(m[sec]))
+		for ptype := range m[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+		for _, ptype := range ptypes {
+			var (
+				rules [][]string
+				err   error
+			)
+			if sec == "p" {
+				rules, err = e.GetNamedPolicy(ptype)
+			} else {
+				rules, err = e.GetNamedGroupingPolicy(ptype)
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, rule := range rules {
+				lines = append(lines, toCasbinRule(ptype, rule))
+			}
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,133 @@
+package casbinkube
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type testRegistration struct {
+	synced atomic.Bool
+}
+
+func (r *testRegistration) HasSynced() bool {
+	return r.synced.Load()
+}
+
+func Test_writeReadSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "policy.json")
+	now := time.Now().UTC().Truncate(time.Second)
+	rules := []CasbinRule{{PType: "p", V0: "alice", V1: "data1", V2: "read"}, {PType: "g", V0: "alice", V1: "admin"}}
+
+	require.NoError(t, writeSnapshot(path, &snapshot{Time: now, Rules: rules}))
+	s, err := readSnapshot(path)
+	require.NoError(t, err)
+	require.True(t, now.Equal(s.Time))
+	require.Equal(t, rules, s.Rules)
+
+	// overwrite
+	require.NoError(t, writeSnapshot(path, &snapshot{Time: now, Rules: rules[:1]}))
+	s, err = readSnapshot(path)
+	require.NoError(t, err)
+	require.Equal(t, rules[:1], s.Rules)
+
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 1, "no temporary files left")
+
+	_, err = readSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func Test_enforcerPolicyLines(t *testing.T) {
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	require.NoError(t, err)
+
+	lines, err := enforcerPolicyLines(e)
+	require.NoError(t, err)
+	require.Equal(t, []CasbinRule{
+		{PType: "p", V0: "alice", V1: "data1", V2: "read"},
+		{PType: "p", V0: "bob", V1: "data2", V2: "write"},
+		{PType: "p", V0: "data2_admin", V1: "data2", V2: "read"},
+		{PType: "p", V0: "data2_admin", V1: "data2", V2: "write"},
+		{PType: "g", V0: "alice", V1: "data2_admin"},
+	}, lines)
+}
+
+func Test_InformerSnapshotTakeover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, writeSnapshot(path, &snapshot{Time: time.Now(), Rules: []CasbinRule{
+		{PType: "p", V0: "alice", V1: "data1", V2: "read"},
+		{PType: "p", V0: "bob", V1: "data2", V2: "write"},
+	}}))
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf")
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	w, err := NewInformer(&InformerConfig{
+		Snapshot: SnapshotConfig{Path: path, SyncTimeout: 100 * time.Millisecond},
+		Clock:    clocktesting.NewFakeClock(now),
+	}, e)
+	require.NoError(t, err)
+
+	// only alice is present in the cluster
+	alice := rule("p", "alice", "data1", "read")
+	alice.Name = keyFor(fromRule(alice))
+	alice.Namespace = DefaultNamespace
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reg := &testRegistration{}
+	require.NoError(t, w.waitForSync(ctx, reg))
+
+	_, stale := w.StaleSince()
+	require.True(t, stale)
+	ok, err := e.Enforce("bob", "data2", "write")
+	requireTrue(t, ok, err)
+
+	// informer takes over
+	w.ruleEventHandler().OnAdd(alice, true)
+	reg.synced.Store(true)
+	require.Eventually(t, func() bool {
+		_, stale := w.StaleSince()
+		return !stale
+	}, 3*time.Second, 50*time.Millisecond)
+
+	ok, err = e.Enforce("alice", "data1", "read")
+	requireTrue(t, ok, err)
+	ok, err = e.Enforce("bob", "data2", "write")
+	requireFalse(t, ok, err)
+
+	s, err := readSnapshot(path)
+	require.NoError(t, err)
+	require.Equal(t, []CasbinRule{{PType: "p", V0: "alice", V1: "data1", V2: "read"}}, s.Rules)
+	require.True(t, now.Equal(s.Time), "the time of the informer clock")
+
+	// the snapshot references are released, the line of alice is held by its Rule only
+	w.refsMu.Lock()
+	defer w.refsMu.Unlock()
+	require.Len(t, w.refs, 1)
+	require.Equal(t, map[string]client.Object{ruleKey(alice): alice}, w.refs[keyFor(fromRule(alice))].objects)
+	require.Empty(t, w.seeded)
+}
+
+func Test_AdapterSnapshotTime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	a := newTestAdapter(t, KubeConfig{}, namedRule("alice", "p", "alice", "data1", "read"))
+	a.snapshot = SnapshotConfig{Path: filepath.Join(t.TempDir(), "policy.json")}
+	a.store.clock = clocktesting.NewFakePassiveClock(now)
+	_, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	require.NoError(t, err)
+
+	s, err := readSnapshot(a.snapshot.Path)
+	require.NoError(t, err)
+	require.True(t, now.Equal(s.Time), "the time of the adapter clock")
+	require.Equal(t, []CasbinRule{{PType: "p", V0: "alice", V1: "data1", V2: "read"}}, s.Rules)
+}
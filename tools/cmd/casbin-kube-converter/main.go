@@ -38,6 +38,17 @@ type RuleYAML struct {
 	Spec       RuleSpec         `yaml:"spec" json:"spec"`
 }
 
+type RuleSetSpec struct {
+	Rules []RuleSpec `yaml:"rules" json:"rules"`
+}
+
+type RuleSetYAML struct {
+	APIVersion string           `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string           `yaml:"kind" json:"kind"`
+	Metadata   RuleMetadataYAML `yaml:"metadata" json:"metadata"`
+	Spec       RuleSetSpec      `yaml:"spec" json:"spec"`
+}
+
 func readPolicyContent(src string) (string, error) {
 	u, err := url.Parse(src)
 	if err == nil && (u.Scheme == "http" || u.Scheme == "https") {
@@ -146,6 +157,31 @@ func buildRuleYAML(spec RuleSpec, ns string, labels map[string]string) RuleYAML
 	}
 }
 
+func buildRuleSetYAMLs(specs []RuleSpec, name string, shardSize int, ns string, labels map[string]string) []RuleSetYAML {
+	shardLabels := map[string]string{"casbin.grepplabs.com/ruleset": name}
+	for k, v := range labels {
+		shardLabels[k] = v
+	}
+	var objs []RuleSetYAML
+	for start := 0; start < len(specs); start += shardSize {
+		end := start + shardSize
+		if end > len(specs) {
+			end = len(specs)
+		}
+		objs = append(objs, RuleSetYAML{
+			APIVersion: "casbin.grepplabs.com/v1alpha1",
+			Kind:       "RuleSet",
+			Metadata: RuleMetadataYAML{
+				Name:      fmt.Sprintf("%s-%d", name, len(objs)),
+				Namespace: ns,
+				Labels:    shardLabels,
+			},
+			Spec: RuleSetSpec{Rules: specs[start:end]},
+		})
+	}
+	return objs
+}
+
 func exitErr(msg string) {
 	fmt.Fprintf(os.Stderr, "error: %s\n", msg)
 	os.Exit(1)
@@ -157,6 +193,8 @@ func main() {
 		outputPath string
 		namespace  string
 		labels     map[string]string
+		ruleSet    string
+		shardSize  int
 	)
 
 	pflag.Usage = func() {
@@ -164,7 +202,7 @@ func main() {
 casbin-kube-converter
 ----------------------
 Convert Casbin policy CSV files (local or remote) into Kubernetes CRD YAML 
-objects of kind 'Rule' for the casbin-kube adapter.
+objects of kind 'Rule' or 'RuleSet' for the casbin-kube adapter.
 
 Examples:
   casbin-kube-converter -i policy.csv
@@ -174,6 +212,8 @@ Examples:
   casbin-kube-converter -i keymatch_policy.csv --label=casbin.grepplabs.com/model=keymatch
   casbin-kube-converter -i ./keymatch_policy.csv -o ./keymatch_policy.yaml --label=casbin.grepplabs.com/model=keymatch
 
+  casbin-kube-converter -i policy.csv --ruleset=policy --shard-size=1000
+
 Options:
 `)
 		pflag.PrintDefaults()
@@ -182,6 +222,8 @@ Options:
 	pflag.StringVarP(&outputPath, "output", "o", "-", "Output file for generated YAML. Use '-' for stdout.")
 	pflag.StringVarP(&namespace, "namespace", "n", "", "Target namespace for generated Rules (optional)")
 	pflag.StringToStringVar(&labels, "label", nil, "Label to add to metadata.labels (repeatable: --label key=value)")
+	pflag.StringVar(&ruleSet, "ruleset", "", "Generate RuleSets named <ruleset>-<index> instead of Rules")
+	pflag.IntVar(&shardSize, "shard-size", 1000, "Maximum number of policy lines in a RuleSet")
 	pflag.Parse()
 
 	if inputPath == "" {
@@ -191,6 +233,9 @@ Options:
 	if inputPath == "-" {
 		exitErr("stdin input is not supported, please provide a file path or HTTP(S) URL")
 	}
+	if shardSize <= 0 {
+		exitErr("--shard-size must be positive")
+	}
 
 	policyContent, err := readPolicyContent(inputPath)
 	if err != nil {
@@ -218,9 +263,18 @@ Options:
 		output = f
 	}
 
-	for _, spec := range specs {
-		obj := buildRuleYAML(spec, namespace, labels)
-		data, err := yaml.Marshal(&obj)
+	var objs []any
+	if ruleSet != "" {
+		for _, obj := range buildRuleSetYAMLs(specs, ruleSet, shardSize, namespace, labels) {
+			objs = append(objs, obj)
+		}
+	} else {
+		for _, spec := range specs {
+			objs = append(objs, buildRuleYAML(spec, namespace, labels))
+		}
+	}
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
 		if err != nil {
 			exitErr(fmt.Sprintf("yaml marshal: %v", err))
 		}